@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// How busy the locked target is, in packets per second, worked out from the change in Kismet's packet
+// counter between readings
+type activityMeter struct {
+	target  *TargetItem // Target the counter belongs to
+	packets int         // Packet counter at the last reading
+	at      time.Time   // When it was read
+	rate    float64     // Packets per second between the last two readings
+	known   bool        // Whether rate has been worked out yet
+}
+
+// Feed the locked target's packet counter, read at the given time
+func (a *activityMeter) observe(target *TargetItem, packets int, at time.Time) {
+	elapsed := at.Sub(a.at).Seconds()
+	switch {
+	case target != a.target || packets <= 0 || packets < a.packets:
+		// A new target, no counter, or Kismet restarted and the count began again
+		a.reset()
+		a.target = target
+	case elapsed <= 0:
+		return
+	default:
+		a.rate, a.known = float64(packets-a.packets)/elapsed, true
+	}
+	a.packets, a.at = packets, at
+}
+
+func (a *activityMeter) reset() {
+	*a = activityMeter{}
+}
+
+// The rate for display, e.g. "32 pkt/s" or "idle", or "" until there have been two readings
+func (a *activityMeter) String() string {
+	switch {
+	case !a.known:
+		return ""
+	case a.rate < 0.5:
+		return "idle"
+	case a.rate < 10:
+		return fmt.Sprintf("%.1f pkt/s", a.rate)
+	}
+	return fmt.Sprintf("%.0f pkt/s", a.rate)
+}
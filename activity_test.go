@@ -0,0 +1,43 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestActivityMeter(t *testing.T) {
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	start := time.Now()
+	var a activityMeter
+
+	a.observe(ap, 1000, start)
+	if a.String() != "" {
+		t.Errorf("activity after one reading = %q, want nothing", a.String())
+	}
+	a.observe(ap, 1064, start.Add(2*time.Second))
+	if a.String() != "32 pkt/s" {
+		t.Errorf("activity = %q, want 32 pkt/s", a.String())
+	}
+	a.observe(ap, 1064, start.Add(3*time.Second))
+	if a.String() != "idle" {
+		t.Errorf("activity = %q, want idle", a.String())
+	}
+	a.observe(ap, 1067, start.Add(4*time.Second))
+	if a.String() != "3.0 pkt/s" {
+		t.Errorf("activity = %q, want 3.0 pkt/s", a.String())
+	}
+
+	// A counter that went backwards, no counter at all, or another target starts over
+	for _, reading := range []struct {
+		target  *TargetItem
+		packets int
+	}{{ap, 10}, {ap, 0}, {&TargetItem{Value: "32:34:00:00:00:01", TType: MAC}, 5000}} {
+		a.reset()
+		a.observe(ap, 1000, start)
+		a.observe(ap, 2000, start.Add(time.Second))
+		a.observe(reading.target, reading.packets, start.Add(2*time.Second))
+		if a.known {
+			t.Errorf("activity after %d packets from %s = %q, want it worked out again", reading.packets, reading.target.Value, a.String())
+		}
+	}
+}
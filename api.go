@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Read-only JSON view of the hunt for dashboards, served on optional.api_addr. The poll loop publishes a
+// snapshot after every poll and the handlers only ever read the latest one, so they never touch the hunt
+// while the TUI or headless loop is changing it. The snapshot is the same one a state dump writes.
+type stateAPI struct {
+	mu       sync.RWMutex
+	snapshot stateSnapshot
+}
+
+func newStateAPI() *stateAPI {
+	return &stateAPI{snapshot: stateSnapshot{Hopping: true, Targets: []targetSnapshot{}, RecentErrors: []recentError{}}}
+}
+
+// Take a snapshot of the hunt after a poll
+func (a *stateAPI) publish(h *hunt, at time.Time) {
+	snapshot := snapshotState(h, at)
+	a.mu.Lock()
+	a.snapshot = snapshot
+	a.mu.Unlock()
+}
+
+func (a *stateAPI) current() stateSnapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.snapshot
+}
+
+// The handlers for GET /state, /targets and /locked. Anything else, including other methods, is refused.
+func (a *stateAPI) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /state", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, a.current())
+	})
+	mux.HandleFunc("GET /targets", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, a.current().Targets)
+	})
+	mux.HandleFunc("GET /locked", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, a.current().Locked)
+	})
+	return mux
+}
+
+func writeAPIJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Debug("Error writing API response", "err", err)
+	}
+}
+
+// The address to serve the API on. One without a host, like ":9206", is bound to localhost; serving it to
+// the network takes an explicit host such as 0.0.0.0.
+func apiListenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// Serve the API on addr until Shutdown is called on the returned server
+func serveAPI(addr string, a *stateAPI) (*http.Server, error) {
+	return serveHTTP("API", apiListenAddr(addr), a.handler())
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+)
+
+// GET path from the API and decode the response into v
+func getAPI(t *testing.T, server *httptest.Server, path string, v any) {
+	t.Helper()
+	resp, err := http.Get(server.URL + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("GET %s: status %d, content type %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: %v", path, err)
+	}
+}
+
+func TestStateAPI(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "CoffeeShop", Channel: "44", RSSI: -52, Type: "Wi-Fi AP",
+		Packets: 1200, HTMode: "VHT80"})
+	cafe := &TargetItem{Value: "CoffeeShop", TType: SSID, Label: "Cafe", Tags: []string{"work"}}
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC, Ignored: true}
+	h, uuid := testHunt(t, kismet, cafe, phone)
+	h.api = newStateAPI()
+	server := httptest.NewServer(h.api.handler())
+	t.Cleanup(server.Close)
+
+	// Before the first poll there's nothing locked
+	var locked *lockedSnapshot
+	getAPI(t, server, "/locked", &locked)
+	if locked != nil {
+		t.Errorf("locked = %+v before the first poll", locked)
+	}
+
+	h.poll(uuid)
+
+	var state stateSnapshot
+	getAPI(t, server, "/state", &state)
+	if state.Hopping || state.Locked == nil || len(state.Targets) != 2 {
+		t.Fatalf("state = %+v, want the channel locked to one of two targets", state)
+	}
+	l := state.Locked
+	if l.Target != "Cafe" || l.Type != "ssid" || l.SSID != "CoffeeShop" || l.MAC != "10:22:33:44:55:66" || !l.ChannelLocked ||
+		l.LockedChannel != "44" || l.LockedRSSI != -52 || l.LockedAt == nil || l.Details != "WiFi 5 · 80MHz · 1.2k pkts" {
+		t.Errorf("locked = %+v", l)
+	}
+
+	var targets []targetSnapshot
+	getAPI(t, server, "/targets", &targets)
+	if len(targets) != 2 || targets[0].RSSI == nil || *targets[0].RSSI != -52 || targets[0].Tags[0] != "work" {
+		t.Fatalf("targets = %+v", targets)
+	}
+	if p := targets[1]; p.Type != "mac" || p.MAC != phone.Value || !p.Ignored || p.RSSI != nil || p.LastSeen != nil {
+		t.Errorf("unseen target = %+v", p)
+	}
+
+	// It's read-only
+	resp, err := http.Post(server.URL+"/state", "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("POST /state: status %d, want 405", resp.StatusCode)
+	}
+}
+
+// A state dump and GET /state come from the same snapshot, so one taken at the same time is the same document
+func TestStateDumpMatchesAPI(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -57, Type: "Wi-Fi Client", Packets: 300})
+	h, uuid := testHunt(t, kismet, &TargetItem{Value: "32:34:00:00:00:01", TType: MAC, Label: "Phone"})
+	h.api = newStateAPI()
+	h.dumpDir = t.TempDir()
+	server := httptest.NewServer(h.api.handler())
+	t.Cleanup(server.Close)
+
+	h.poll(uuid)
+
+	resp, err := http.Get(server.URL + "/state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	served, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path, err := h.dumpState(h.api.current().Time)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dumped, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(dumped, served) {
+		t.Errorf("dump differs from GET /state\ndump:\n%s\nGET /state:\n%s", dumped, served)
+	}
+}
+
+func TestAPIListenAddr(t *testing.T) {
+	for addr, want := range map[string]string{
+		":9206":        "127.0.0.1:9206",
+		"0.0.0.0:9206": "0.0.0.0:9206",
+		"[::1]:9206":   "[::1]:9206",
+		"lab-pi:9206":  "lab-pi:9206",
+	} {
+		if got := apiListenAddr(addr); got != want {
+			t.Errorf("apiListenAddr(%q) = %q, want %q", addr, got, want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var errIwNotFound = errors.New("iw not found in $PATH")
+
+// Check that every interface can do monitor mode before Kismet is launched on them, logging the result
+// for each so a bad adapter can be dropped from a list. Without iw the check is skipped with a warning.
+func checkMonitorCapability(ifaces []string) error {
+	var bad []string
+	for _, iface := range ifaces {
+		// Kismet source definitions can carry options, e.g. wlan0:hop=false
+		name, _, _ := strings.Cut(iface, ":")
+
+		phy, err := monitorCapable(name)
+		switch {
+		case errors.Is(err, errIwNotFound):
+			slog.Warn("Can't check for monitor mode support, iw is not installed")
+			return nil
+		case err != nil:
+			slog.Error("Interface can't be used for capture", "interface", name, "err", err)
+			bad = append(bad, name)
+		default:
+			slog.Info("Interface supports monitor mode", "interface", name, "phy", phy)
+		}
+	}
+
+	if len(bad) > 0 {
+		pronoun := "it"
+		if len(bad) > 1 {
+			pronoun = "them"
+		}
+		return fmt.Errorf("%s can't do monitor mode. Remove %s from required.interface, or pass --skip-capability-check if the driver is known to work",
+			strings.Join(bad, ", "), pronoun)
+	}
+	return nil
+}
+
+// A frequency band a phy can use and its enabled channels
+type wifiBand struct {
+	Band     string `json:"band"` // 2.4GHz, 5GHz, 6GHz or 60GHz
+	Channels []int  `json:"channels"`
+}
+
+// What a wireless phy supports, from iw phy info
+type phyCapabilities struct {
+	modes []string
+	bands []wifiBand
+}
+
+// The wireless phy behind iface, e.g. phy0
+func interfacePhy(iface string) (string, error) {
+	data, err := os.ReadFile(filepath.Join("/sys/class/net", iface, "phy80211", "name"))
+	if err != nil {
+		if _, statErr := os.Stat(filepath.Join("/sys/class/net", iface)); statErr != nil {
+			return "", fmt.Errorf("no such interface")
+		}
+		return "", fmt.Errorf("not a Wi-Fi interface")
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// Run iw phy info for phy and parse its capabilities
+func readPhyCapabilities(phy string) (phyCapabilities, error) {
+	iw, err := exec.LookPath("iw")
+	if err != nil {
+		return phyCapabilities{}, errIwNotFound
+	}
+	out, err := exec.Command(iw, "phy", phy, "info").Output()
+	if err != nil {
+		return phyCapabilities{}, fmt.Errorf("error running iw phy %s info: %v", phy, err)
+	}
+	return parsePhyInfo(out), nil
+}
+
+// Find the wireless phy behind iface and check that it lists monitor among its supported interface modes
+func monitorCapable(iface string) (string, error) {
+	phy, err := interfacePhy(iface)
+	if err != nil {
+		return "", err
+	}
+	caps, err := readPhyCapabilities(phy)
+	if err != nil {
+		return phy, err
+	}
+	if !slices.Contains(caps.modes, "monitor") {
+		return phy, fmt.Errorf("%s does not support monitor mode", phy)
+	}
+	return phy, nil
+}
+
+// Frequency lines look like "* 2412.0 MHz [1] (22.0 dBm)" or "* 5260 MHz [52] (disabled)"
+var frequencyLine = regexp.MustCompile(`^\* ([0-9.]+) MHz \[(\d+)\](.*)$`)
+
+// Parse the supported interface modes and enabled channels from iw phy info output
+func parsePhyInfo(info []byte) phyCapabilities {
+	var caps phyCapabilities
+	channels := make(map[string][]int)
+
+	scanner := bufio.NewScanner(bytes.NewReader(info))
+	inModes := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "Supported interface modes:" {
+			inModes = true
+			continue
+		}
+		if inModes {
+			if mode, ok := strings.CutPrefix(line, "* "); ok {
+				caps.modes = append(caps.modes, mode)
+				continue
+			}
+			// The list ends at the next section heading
+			inModes = false
+		}
+
+		match := frequencyLine.FindStringSubmatch(line)
+		if match == nil || strings.Contains(match[3], "disabled") {
+			continue
+		}
+		mhz, _ := strconv.ParseFloat(match[1], 64)
+		channel, _ := strconv.Atoi(match[2])
+		if band := bandOf(mhz); band != "" {
+			channels[band] = append(channels[band], channel)
+		}
+	}
+
+	for _, band := range []string{"2.4GHz", "5GHz", "6GHz", "60GHz"} {
+		if len(channels[band]) > 0 {
+			caps.bands = append(caps.bands, wifiBand{Band: band, Channels: channels[band]})
+		}
+	}
+	return caps
+}
+
+// The band a frequency in MHz falls in
+func bandOf(mhz float64) string {
+	switch {
+	case mhz >= 2400 && mhz < 2500:
+		return "2.4GHz"
+	case mhz >= 4900 && mhz < 5925:
+		return "5GHz"
+	case mhz >= 5925 && mhz < 7200:
+		return "6GHz"
+	case mhz >= 57000 && mhz < 72000:
+		return "60GHz"
+	}
+	return ""
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// What --check needs to know beyond the config
+type checkOptions struct {
+	skipKismet          bool
+	skipCapabilityCheck bool
+	offline             bool // --demo, --replay or --kismetdb: there's no Kismet to check
+	euid                int
+}
+
+// Prints a line per --check step and remembers whether a required one failed
+type checkReport struct {
+	out    io.Writer
+	failed bool
+}
+
+func (r *checkReport) pass(name, detail string) {
+	fmt.Fprintf(r.out, "✓ %s: %s\n", name, detail)
+}
+
+func (r *checkReport) fail(name string, err error) {
+	r.failed = true
+	fmt.Fprintf(r.out, "✗ %s: %v\n", name, err)
+}
+
+// A problem worth knowing about that doesn't stop a run
+func (r *checkReport) warn(name, detail string) {
+	fmt.Fprintf(r.out, "! %s: %s\n", name, detail)
+}
+
+func (r *checkReport) skip(name, why string) {
+	fmt.Fprintf(r.out, "- %s: skipped, %s\n", name, why)
+}
+
+// An indented line under the last step, e.g. one of the targets it counted
+func (r *checkReport) item(text string) {
+	fmt.Fprintf(r.out, "    %s\n", text)
+}
+
+// Entry point for --check: validate the config, credentials and Kismet (or what launching it needs) in
+// order, printing a line for each, without starting a session. The config has already been read. Returns
+// the process exit code, 1 if any required check failed.
+func runCheck(out io.Writer, targets []*TargetItem, opts checkOptions) int {
+	r := &checkReport{out: out}
+
+	if path := viper.ConfigFileUsed(); path != "" {
+		r.pass("Config", fmt.Sprintf("%s (%s)", path, configFormat))
+	} else {
+		r.pass("Config", "no config file, using flags and environment variables")
+	}
+
+	checkTargets(r, targets)
+
+	ifaces := configList("required.interface")
+	if len(ifaces) == 0 {
+		r.fail("Interfaces", errors.New("none set in required.interface (--interface)"))
+	} else {
+		r.pass("Interfaces", strings.Join(ifaces, ", "))
+	}
+
+	if _, _, err := getCachedCredentials(); err != nil {
+		r.fail("Credentials", err)
+	} else {
+		r.pass("Credentials", "set")
+	}
+
+	switch {
+	case opts.offline:
+		r.skip("Kismet", "a replay or demo doesn't use it")
+	case kismetRunning(viper.GetString("optional.kismet_endpoint")):
+		checkRunningKismet(r, ifaces)
+	case opts.skipKismet:
+		r.fail("Kismet", fmt.Errorf("nothing answers at %s, and --skip-kismet says not to launch it", viper.GetString("optional.kismet_endpoint")))
+	default:
+		r.pass("Kismet", fmt.Sprintf("not running at %s, so it would be launched", viper.GetString("optional.kismet_endpoint")))
+		checkKismetLaunch(r, ifaces, opts)
+	}
+
+	if r.failed {
+		fmt.Fprintln(out, "Some checks failed")
+		return 1
+	}
+	fmt.Fprintln(out, "All checks passed")
+	return 0
+}
+
+// Every configured MAC and SSID is valid and there's at least one target
+func checkTargets(r *checkReport, targets []*TargetItem) {
+	invalid := errors.Join(checkMACs(configList("required.target_mac")), checkSSIDs(configList("optional.target_ssid")))
+	switch {
+	case invalid != nil:
+		r.fail("Targets", invalid)
+	case len(targets) == 0:
+		r.fail("Targets", errors.New("none set in required.target_mac or optional.target_ssid (--mac, --ssid or piped on stdin)"))
+	default:
+		r.pass("Targets", fmt.Sprintf("%d", len(targets)))
+		for _, target := range targets {
+			r.item(describeTarget(target))
+		}
+	}
+}
+
+// A target as it was parsed, e.g. `MAC 32:34:00:00:00:01 "Phone" [work, home]`
+func describeTarget(target *TargetItem) string {
+	text := "MAC " + target.Value
+	if target.TType == SSID {
+		text = "SSID " + strconv.Quote(target.Value)
+	}
+	if target.Label != "" {
+		text += fmt.Sprintf(" %q", target.Label)
+	}
+	if len(target.Tags) > 0 {
+		text += " [" + strings.Join(target.Tags, ", ") + "]"
+	}
+	if target.AlertRSSI != 0 {
+		text += fmt.Sprintf(" alert at %d dBm", target.AlertRSSI)
+	}
+	return text
+}
+
+// Kismet answers: the credentials are accepted and every interface is one of its datasources
+func checkRunningKismet(r *checkReport, ifaces []string) {
+	endpoint := viper.GetString("optional.kismet_endpoint")
+	r.pass("Kismet", "answering at "+endpoint)
+
+	sources, err := FetchDatasources(endpoint)
+	if err != nil {
+		r.fail("Login", err)
+		r.skip("Datasources", "the datasource list couldn't be read")
+		return
+	}
+	r.pass("Login", "accepted")
+
+	uuids := make(map[string]string)
+	for _, source := range sources {
+		if name, ok := source["kismet.datasource.interface"].(string); ok {
+			uuids[name], _ = source["kismet.datasource.uuid"].(string)
+		}
+	}
+	for _, iface := range ifaces {
+		name, _, _ := strings.Cut(iface, ":")
+		if uuid, ok := uuids[name]; ok {
+			r.pass("Datasource "+name, "capturing in Kismet as "+uuid)
+		} else {
+			r.fail("Datasource "+name, errors.New("not one of Kismet's datasources; add it to Kismet (kismet -c "+name+") or pick another interface"))
+		}
+	}
+}
+
+// Kismet would be launched: we're root, kismet is on PATH and every interface can do monitor mode
+func checkKismetLaunch(r *checkReport, ifaces []string, opts checkOptions) {
+	if err := checkCanLaunchKismet(opts.euid, true); err != nil {
+		r.fail("Root", err)
+	} else {
+		r.pass("Root", "running as root")
+	}
+
+	if path, err := exec.LookPath("kismet"); err != nil {
+		r.fail("Kismet binary", errors.New("kismet not found in $PATH"))
+	} else {
+		r.pass("Kismet binary", path)
+	}
+
+	if opts.skipCapabilityCheck {
+		r.skip("Monitor mode", "--skip-capability-check")
+		return
+	}
+	for _, iface := range ifaces {
+		name, _, _ := strings.Cut(iface, ":")
+		phy, err := monitorCapable(name)
+		switch {
+		case errors.Is(err, errIwNotFound):
+			r.warn("Monitor mode", "iw is not installed, so monitor mode support can't be checked")
+			return
+		case err != nil:
+			r.fail("Monitor mode "+name, err)
+		default:
+			r.pass("Monitor mode "+name, phy+" supports it")
+		}
+	}
+}
+
+// Print a failed config read as a --check line, returning the exit code
+func failCheckConfig(err error) int {
+	r := &checkReport{out: os.Stdout}
+	r.fail("Config", err)
+	return 1
+}
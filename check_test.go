@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// Point the config at the fake Kismet with the given interfaces and target MACs for the test
+func useCheckConfig(t *testing.T, endpoint string, ifaces, macs []string) {
+	t.Helper()
+	viper.Set("optional.kismet_endpoint", endpoint)
+	viper.Set("required.interface", ifaces)
+	viper.Set("required.target_mac", macs)
+	t.Cleanup(func() {
+		viper.Set("optional.kismet_endpoint", "")
+		viper.Set("required.interface", nil)
+		viper.Set("required.target_mac", nil)
+	})
+}
+
+func TestCheckAgainstRunningKismet(t *testing.T) {
+	server := fakeKismet(t)
+	useCheckConfig(t, server.Endpoint(), []string{"wlan0"}, []string{"32:34:00:00:00:01"})
+	targets := []*TargetItem{
+		{Value: "32:34:00:00:00:01", TType: MAC, Label: "Phone", Tags: []string{"work", "home"}, AlertRSSI: -45},
+		{Value: "CoffeeShop", TType: SSID},
+	}
+
+	var out bytes.Buffer
+	if code := runCheck(&out, targets, checkOptions{}); code != 0 {
+		t.Fatalf("exit code %d, output:\n%s", code, out.String())
+	}
+	for _, want := range []string{
+		"✓ Targets: 2\n",
+		`    MAC 32:34:00:00:00:01 "Phone" [work, home] alert at -45 dBm` + "\n",
+		`    SSID "CoffeeShop"` + "\n",
+		"✓ Login: accepted\n",
+		"✓ Datasource wlan0: capturing in Kismet as " + testUUID + "\n",
+		"All checks passed\n",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestCheckMissingDatasource(t *testing.T) {
+	server := fakeKismet(t)
+	useCheckConfig(t, server.Endpoint(), []string{"wlan9"}, []string{"32:34:00:00:00:01"})
+
+	var out bytes.Buffer
+	code := runCheck(&out, []*TargetItem{{Value: "32:34:00:00:00:01", TType: MAC}}, checkOptions{})
+	if code != 1 || !strings.Contains(out.String(), "✗ Datasource wlan9: not one of Kismet's datasources") {
+		t.Errorf("exit code %d, output:\n%s", code, out.String())
+	}
+}
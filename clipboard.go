@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/atotto/clipboard"
+	"github.com/aymanbagabas/go-osc52/v2"
+)
+
+// Copy text to the system clipboard. Without a local clipboard (xclip, xsel or wl-copy on Linux, usually
+// missing over SSH) it falls back to an OSC 52 escape written to out, which most terminal emulators turn
+// into a copy on the machine the terminal is running on.
+func copyToClipboard(text string, out io.Writer) error {
+	if !clipboard.Unsupported {
+		if err := clipboard.WriteAll(text); err == nil {
+			return nil
+		}
+	}
+	if _, err := osc52.New(text).WriteTo(out); err != nil {
+		return fmt.Errorf("error copying to clipboard: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// Two targets from optional.colocation_pairs, alerted on when both are in range at once
+type targetPair struct {
+	a, b     *TargetItem
+	together bool // Both were in range at the last check, so the alert has already fired
+}
+
+// Watches the configured pairs of targets and fires once when both of a pair have been heard at or above
+// the threshold within the same window. A pair re-arms when either one drops out of range.
+type colocationWatch struct {
+	threshold int           // dBm both targets have to reach
+	window    time.Duration // How recently both have to have been heard
+	pairs     []*targetPair
+}
+
+// Build the watch from "target=target" entries, each side a MAC, SSID or label. Entries naming targets
+// that aren't configured are skipped with a warning.
+func newColocationWatch(entries []string, targets []*TargetItem, threshold int, window time.Duration) *colocationWatch {
+	w := &colocationWatch{threshold: threshold, window: window}
+	for _, entry := range entries {
+		a, b, ok := strings.Cut(entry, "=")
+		if !ok {
+			slog.Warn("Ignoring malformed entry, expected target=target", "key", "optional.colocation_pairs", "entry", entry)
+			continue
+		}
+		ta, tb := findConfiguredTarget(targets, a), findConfiguredTarget(targets, b)
+		if ta == nil || tb == nil || ta == tb {
+			slog.Warn("Ignoring co-location pair, both sides must be different configured targets", "entry", entry)
+			continue
+		}
+		w.pairs = append(w.pairs, &targetPair{a: ta, b: tb})
+	}
+	return w
+}
+
+// The target written in the config as name, whether by MAC, SSID or label
+func findConfiguredTarget(targets []*TargetItem, name string) *TargetItem {
+	name = strings.TrimSpace(name)
+	if mac, err := formatMAC(name); err == nil {
+		name = mac
+	}
+	for _, target := range targets {
+		switch {
+		case target.TType == MAC && target.Value == name:
+		case target.TType == SSID && (target.OriginalValue == name || (target.OriginalValue == "" && target.Value == name)):
+		case target.Label != "" && target.Label == name:
+		default:
+			continue
+		}
+		return target
+	}
+	return nil
+}
+
+// Whether the target was heard at or above the threshold within the window before now
+func (w *colocationWatch) inRange(target *TargetItem, now time.Time) bool {
+	return !target.Ignored && !target.LastSeen.IsZero() && now.Sub(target.LastSeen) <= w.window && target.LastRSSI >= w.threshold
+}
+
+// The pairs that have just come into range together
+func (w *colocationWatch) check(now time.Time) []*targetPair {
+	var met []*targetPair
+	for _, pair := range w.pairs {
+		together := w.inRange(pair.a, now) && w.inRange(pair.b, now)
+		if together && !pair.together {
+			met = append(met, pair)
+		}
+		pair.together = together
+	}
+	return met
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// A subcommand as listed by the completion scripts
+type subcommand struct {
+	name  string
+	usage string
+	flags func() *pflag.FlagSet // nil if it takes none
+}
+
+var subcommands = []subcommand{
+	{"history", "Summarize every sighting of a MAC from the sightings database", historyFlagSet},
+	{"list-interfaces", "List wireless interfaces and whether they support monitor mode", listInterfacesFlagSet},
+	{"completion", "Print a shell completion script for bash, zsh or fish", nil},
+}
+
+var completionShells = []string{"bash", "zsh", "fish"}
+
+// Values offered for flags that take one of a fixed set
+var flagChoices = map[string][]string{
+	"output":      {"text", "json"},
+	"config-type": {"toml", "yaml", "json"},
+}
+
+// Flags whose value is a path, completed as a file name
+var fileFlags = []string{"config", "log-file", "record", "record-track", "export-wigle", "record-session", "replay", "kismetdb", "db"}
+
+// Entry point for "rizzyscope completion <shell>": write the completion script for the top-level flags in
+// root and every subcommand to stdout. Returns the process exit code.
+func runCompletion(args []string, root *pflag.FlagSet) int {
+	if len(args) != 1 || !slices.Contains(completionShells, args[0]) {
+		fmt.Fprintln(os.Stderr, "Usage: rizzyscope completion bash|zsh|fish")
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "bash":
+		err = writeBashCompletion(os.Stdout, root)
+	case "zsh":
+		err = writeZshCompletion(os.Stdout, root)
+	case "fish":
+		err = writeFishCompletion(os.Stdout, root)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// Every flag in the set, sorted by name
+func completionFlags(flags *pflag.FlagSet) []*pflag.Flag {
+	var all []*pflag.Flag
+	if flags == nil {
+		return all
+	}
+	flags.VisitAll(func(f *pflag.Flag) {
+		if !f.Hidden {
+			all = append(all, f)
+		}
+	})
+	return all
+}
+
+// The flags of a subcommand, or of the top level for an empty name
+func commandFlags(name string, root *pflag.FlagSet) []*pflag.Flag {
+	if name == "" {
+		return completionFlags(root)
+	}
+	for _, cmd := range subcommands {
+		if cmd.name == name && cmd.flags != nil {
+			return completionFlags(cmd.flags())
+		}
+	}
+	return nil
+}
+
+// Whether the flag is a switch rather than taking a value
+func isBoolFlag(f *pflag.Flag) bool {
+	return f.NoOptDefVal != ""
+}
+
+// Whether the flag can be given more than once, e.g. --mac
+func isRepeatableFlag(f *pflag.Flag) bool {
+	return strings.HasSuffix(f.Value.Type(), "Slice")
+}
+
+// The top level followed by every subcommand name
+func completionCommands() []string {
+	commands := []string{""}
+	for _, cmd := range subcommands {
+		commands = append(commands, cmd.name)
+	}
+	return commands
+}
+
+func writeBashCompletion(out io.Writer, root *pflag.FlagSet) error {
+	var b strings.Builder
+	b.WriteString(`# bash completion for rizzyscope
+# Load with: source <(rizzyscope completion bash)
+
+_rizzyscope() {
+    local cur="${COMP_WORDS[COMP_CWORD]}"
+    local prev="${COMP_WORDS[COMP_CWORD-1]}"
+    local cmd=""
+    if [[ ${COMP_CWORD} -gt 1 ]]; then
+        case "${COMP_WORDS[1]}" in
+`)
+	fmt.Fprintf(&b, "            %s) cmd=\"${COMP_WORDS[1]}\" ;;\n", strings.Join(completionCommands()[1:], "|"))
+	b.WriteString(`        esac
+    fi
+
+    # The value of the flag before the cursor
+    case "${cmd}:${prev}" in
+`)
+	for _, cmd := range completionCommands() {
+		for _, f := range commandFlags(cmd, root) {
+			if isBoolFlag(f) {
+				continue
+			}
+			pattern := fmt.Sprintf("%s:--%s", cmd, f.Name)
+			if f.Shorthand != "" {
+				pattern += fmt.Sprintf("|%s:-%s", cmd, f.Shorthand)
+			}
+			switch {
+			case flagChoices[f.Name] != nil:
+				fmt.Fprintf(&b, "        %s)\n            COMPREPLY=($(compgen -W %q -- \"${cur}\"))\n            return ;;\n", pattern, strings.Join(flagChoices[f.Name], " "))
+			case slices.Contains(fileFlags, f.Name):
+				fmt.Fprintf(&b, "        %s)\n            compopt -o filenames\n            COMPREPLY=($(compgen -f -- \"${cur}\"))\n            return ;;\n", pattern)
+			default:
+				fmt.Fprintf(&b, "        %s)\n            return ;;\n", pattern)
+			}
+		}
+	}
+	b.WriteString(`    esac
+
+    local words=""
+    case "${cmd}" in
+`)
+	for _, cmd := range completionCommands() {
+		var words []string
+		for _, f := range commandFlags(cmd, root) {
+			words = append(words, "--"+f.Name)
+			if f.Shorthand != "" {
+				words = append(words, "-"+f.Shorthand)
+			}
+		}
+		switch cmd {
+		case "":
+			words = append(words, completionCommands()[1:]...)
+		case "completion":
+			words = append(words, completionShells...)
+		}
+		fmt.Fprintf(&b, "        %q) words=%q ;;\n", cmd, strings.Join(words, " "))
+	}
+	b.WriteString(`    esac
+    COMPREPLY=($(compgen -W "${words}" -- "${cur}"))
+}
+
+complete -F _rizzyscope rizzyscope
+`)
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// Quote s for a zsh _arguments description in single quotes
+func zshDescription(s string) string {
+	s = strings.NewReplacer("[", `\[`, "]", `\]`).Replace(s)
+	return strings.ReplaceAll(s, "'", `'\''`)
+}
+
+// The _arguments specs for a set of flags
+func zshFlagSpecs(flags []*pflag.Flag) []string {
+	var specs []string
+	for _, f := range flags {
+		var spec string
+		switch {
+		case isRepeatableFlag(f) && f.Shorthand != "":
+			spec = fmt.Sprintf("'*'{-%s,--%s}'", f.Shorthand, f.Name)
+		case isRepeatableFlag(f):
+			spec = fmt.Sprintf("'*--%s", f.Name)
+		case f.Shorthand != "":
+			spec = fmt.Sprintf("'(-%s --%s)'{-%s,--%s}'", f.Shorthand, f.Name, f.Shorthand, f.Name)
+		default:
+			spec = fmt.Sprintf("'--%s", f.Name)
+		}
+		spec += "[" + zshDescription(f.Usage) + "]"
+
+		switch {
+		case isBoolFlag(f):
+		case flagChoices[f.Name] != nil:
+			spec += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(flagChoices[f.Name], " "))
+		case slices.Contains(fileFlags, f.Name):
+			spec += fmt.Sprintf(":%s:_files", f.Name)
+		default:
+			spec += fmt.Sprintf(":%s: ", f.Name)
+		}
+		specs = append(specs, spec+"'")
+	}
+	return specs
+}
+
+func writeZshCompletion(out io.Writer, root *pflag.FlagSet) error {
+	var b strings.Builder
+	b.WriteString(`#compdef rizzyscope
+# zsh completion for rizzyscope
+# Load with: source <(rizzyscope completion zsh), or save as _rizzyscope in a directory on $fpath
+
+_rizzyscope() {
+    if (( CURRENT > 2 )); then
+        case "${words[2]}" in
+`)
+	for _, cmd := range subcommands {
+		// Drop the program name so the subcommand's own arguments start at 1
+		fmt.Fprintf(&b, "            %s)\n                shift words\n                (( CURRENT-- ))\n", cmd.name)
+		specs := zshFlagSpecs(commandFlags(cmd.name, root))
+		if cmd.name == "completion" {
+			specs = append(specs, fmt.Sprintf("'1:shell:(%s)'", strings.Join(completionShells, " ")))
+		}
+		fmt.Fprintf(&b, "                _arguments -s \\\n                    %s\n", strings.Join(specs, " \\\n                    "))
+		b.WriteString("                return ;;\n")
+	}
+	b.WriteString(`        esac
+    fi
+
+    local -a commands
+    commands=(
+`)
+	for _, cmd := range subcommands {
+		fmt.Fprintf(&b, "        '%s:%s'\n", cmd.name, zshDescription(cmd.usage))
+	}
+	b.WriteString(`    )
+    _arguments -s \
+`)
+	for _, spec := range zshFlagSpecs(completionFlags(root)) {
+		fmt.Fprintf(&b, "        %s \\\n", spec)
+	}
+	b.WriteString(`        '1:command:_describe command commands'
+}
+
+if [[ "${funcstack[1]}" == _rizzyscope ]]; then
+    _rizzyscope "$@"
+else
+    compdef _rizzyscope rizzyscope
+fi
+`)
+	_, err := io.WriteString(out, b.String())
+	return err
+}
+
+// Quote s in single quotes for fish
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+func writeFishCompletion(out io.Writer, root *pflag.FlagSet) error {
+	var b strings.Builder
+	b.WriteString(`# fish completion for rizzyscope
+# Load with: rizzyscope completion fish | source, or save as ~/.config/fish/completions/rizzyscope.fish
+
+complete -c rizzyscope -f
+`)
+	for _, cmd := range subcommands {
+		fmt.Fprintf(&b, "complete -c rizzyscope -n __fish_use_subcommand -a %s -d %s\n", cmd.name, fishQuote(cmd.usage))
+	}
+	fmt.Fprintf(&b, "complete -c rizzyscope -n '__fish_seen_subcommand_from completion' -a %s\n", fishQuote(strings.Join(completionShells, " ")))
+
+	for _, cmd := range completionCommands() {
+		condition := "__fish_use_subcommand"
+		if cmd != "" {
+			condition = fishQuote("__fish_seen_subcommand_from " + cmd)
+		}
+		for _, f := range commandFlags(cmd, root) {
+			line := fmt.Sprintf("complete -c rizzyscope -n %s", condition)
+			if f.Shorthand != "" {
+				line += " -s " + f.Shorthand
+			}
+			line += " -l " + f.Name
+
+			switch {
+			case isBoolFlag(f):
+			case flagChoices[f.Name] != nil:
+				line += " -x -a " + fishQuote(strings.Join(flagChoices[f.Name], " "))
+			case slices.Contains(fileFlags, f.Name):
+				line += " -r -F"
+			default:
+				line += " -x"
+			}
+			fmt.Fprintf(&b, "%s -d %s\n", line, fishQuote(f.Usage))
+		}
+	}
+	_, err := io.WriteString(out, b.String())
+	return err
+}
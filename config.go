@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Keys holding a plain list. Set through an environment variable, they're split on commas (see configList).
+var listConfigKeys = []string{"required.target_mac", "required.interface", "optional.target_ssid", "optional.webhook_events", "optional.colocation_pairs"}
+
+// Keys whose values --print-config hides
+var secretConfigKeys = []string{"credentials.user", "credentials.password", "mqtt.password", "optional.webhook_url"}
+
+// Every key in the --init template
+func templateConfigKeys() []string {
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(strings.NewReader(defaultConfig)); err != nil {
+		panic("invalid config template: " + err.Error())
+	}
+	keys := v.AllKeys()
+	slices.Sort(keys)
+	return keys
+}
+
+// Register every known key for RIZZYSCOPE_<SECTION>_<KEY> lookups. AutomaticEnv alone already lets Get find
+// them, but viper only includes keys it knows about in AllSettings, which --print-config relies on.
+func bindConfigEnv() {
+	for _, key := range templateConfigKeys() {
+		viper.BindEnv(key)
+	}
+}
+
+// A list-valued key. From a flag or the config file it's already a list; from an environment variable it's
+// one string, split on commas, or on whitespace if it has none.
+func configList(key string) []string {
+	s, ok := viper.Get(key).(string)
+	if !ok || !strings.Contains(s, ",") {
+		return viper.GetStringSlice(key)
+	}
+
+	var values []string
+	for _, value := range strings.Split(s, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Write the merged configuration (flags, environment, config file and defaults) as JSON, with secrets
+// redacted, for --print-config
+func printEffectiveConfig(out io.Writer) error {
+	settings := viper.AllSettings()
+	// [defaults] and the profiles are already merged into the other sections, and would show secrets unredacted
+	delete(settings, "defaults")
+	delete(settings, "profiles")
+	for _, key := range listConfigKeys {
+		setSetting(settings, key, configList(key))
+	}
+	for _, key := range secretConfigKeys {
+		if viper.GetString(key) != "" {
+			setSetting(settings, key, "REDACTED")
+		}
+	}
+
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(settings)
+}
+
+// Set section.name in the nested map AllSettings returns
+func setSetting(settings map[string]interface{}, key string, value interface{}) {
+	section, name, _ := strings.Cut(key, ".")
+	m, ok := settings[section].(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{}
+		settings[section] = m
+	}
+	m[name] = value
+}
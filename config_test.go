@@ -0,0 +1,203 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Load a config file with the given name and contents the way main does, on a fresh viper that's reset
+// again after the test
+func loadTestConfig(t *testing.T, name, contents string) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return loadConfigFile(t, path)
+}
+
+// Load the config file at path the way main does, on a fresh viper that's reset again after the test
+func loadConfigFile(t *testing.T, path string) error {
+	t.Helper()
+	viper.Reset()
+	t.Cleanup(func() {
+		viper.Reset()
+		configFormat = "toml"
+	})
+	if err := setupConfig(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	return readConfig()
+}
+
+func TestEnvOverridesConfigFile(t *testing.T) {
+	t.Setenv("RIZZYSCOPE_CREDENTIALS_USER", "env-user")
+	t.Setenv("RIZZYSCOPE_OPTIONAL_KISMET_ENDPOINT", "http://10.0.0.5:2501")
+	err := loadTestConfig(t, "config.toml", `
+[credentials]
+user = "file-user"
+password = "file-password"
+
+[optional]
+kismet_endpoint = "http://localhost:2501"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{
+		"credentials.user":         "env-user",
+		"optional.kismet_endpoint": "http://10.0.0.5:2501",
+		"credentials.password":     "file-password", // Not in the environment, so the file's value stands
+	} {
+		if got := viper.GetString(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestValidateSettings(t *testing.T) {
+	phone := []*TargetItem{{Value: "32:34:00:00:00:01", TType: MAC}}
+	wlan0 := []string{"wlan0"}
+	noCredentials := errors.New("no credentials")
+
+	for _, tt := range []struct {
+		name           string
+		targets        []*TargetItem
+		ifaces         []string
+		credentialsErr error
+		wantMissing    []string // A phrase naming each missing setting, nil if none are
+	}{
+		{"all set", phone, wlan0, nil, nil},
+		{"no targets", nil, wlan0, nil, []string{"at least one target"}},
+		{"no interfaces", phone, nil, nil, []string{"required.interface"}},
+		{"no credentials", phone, wlan0, noCredentials, []string{"credentials.user and credentials.password"}},
+		{"nothing", nil, nil, noCredentials, []string{"at least one target", "required.interface", "credentials.user"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSettings(tt.targets, tt.ifaces, tt.credentialsErr)
+			if tt.wantMissing == nil {
+				if err != nil {
+					t.Errorf("validateSettings = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("validateSettings = nil, want %v missing", tt.wantMissing)
+			}
+			// One line per missing setting after the heading
+			lines := strings.Split(err.Error(), "\n")
+			if lines[0] != "missing required settings:" || len(lines) != len(tt.wantMissing)+1 {
+				t.Fatalf("validateSettings = %q, want %d missing", err, len(tt.wantMissing))
+			}
+			for i, want := range tt.wantMissing {
+				if !strings.Contains(lines[i+1], want) {
+					t.Errorf("line %d = %q, want it to mention %s", i+1, lines[i+1], want)
+				}
+			}
+		})
+	}
+}
+
+// Every format loads its valid fixture, and a broken one is reported with the file and the format's name
+func TestConfigFormatFixtures(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		name   string // As describeParseError names it
+	}{
+		{"toml", "TOML"},
+		{"yaml", "YAML"},
+		{"json", "JSON"},
+	} {
+		t.Run(tt.format, func(t *testing.T) {
+			valid := filepath.Join("testdata", "config", "valid."+tt.format)
+			if err := loadConfigFile(t, valid); err != nil {
+				t.Fatalf("%s: %v", valid, err)
+			}
+			if macs := configList("required.target_mac"); len(macs) != 1 || macs[0] != "32:34:00:00:00:01" {
+				t.Errorf("%s: required.target_mac = %v", valid, macs)
+			}
+			if user := viper.GetString("credentials.user"); user != "kismet" {
+				t.Errorf("%s: credentials.user = %q", valid, user)
+			}
+
+			broken := filepath.Join("testdata", "config", "broken."+tt.format)
+			err := loadConfigFile(t, broken)
+			if err == nil {
+				t.Fatalf("%s loaded without an error", broken)
+			}
+			if want := broken + " is not valid " + tt.name + ": "; !strings.HasPrefix(err.Error(), want) {
+				t.Errorf("%s: error = %q, want it to start with %q", broken, err, want)
+			}
+		})
+	}
+
+	// Other errors aren't parse errors, so they pass through untouched
+	notFound := errors.New("open config.toml: no such file or directory")
+	if err := describeParseError(notFound, "config.toml", "toml"); err != notFound {
+		t.Errorf("describeParseError(%v) = %v, want it unchanged", notFound, err)
+	}
+}
+
+// The list keys are the tricky ones for precedence: a flag holds a list, an environment variable a string
+// that's split, and the file a list again. Each source must replace the lower ones whole, not merge.
+func TestListKeyPrecedence(t *testing.T) {
+	const file = `
+[required]
+target_mac = ["32:34:00:00:00:01"]
+
+[optional]
+target_tags = ["32:34:00:00:00:01=file"]
+`
+	for _, tt := range []struct {
+		name     string
+		env      map[string]string
+		args     []string
+		wantMACs []string
+		wantTags []string
+	}{
+		{"file", nil, nil, []string{"32:34:00:00:00:01"}, []string{"32:34:00:00:00:01=file"}},
+		{"env over file", map[string]string{
+			"RIZZYSCOPE_REQUIRED_TARGET_MAC":  "32:34:00:00:00:02,32:34:00:00:00:03",
+			"RIZZYSCOPE_OPTIONAL_TARGET_TAGS": "32:34:00:00:00:02=env,home 32:34:00:00:00:03=env",
+		}, nil, []string{"32:34:00:00:00:02", "32:34:00:00:00:03"}, []string{"32:34:00:00:00:02=env,home", "32:34:00:00:00:03=env"}},
+		{"flag over env and file", map[string]string{
+			"RIZZYSCOPE_REQUIRED_TARGET_MAC":  "32:34:00:00:00:02",
+			"RIZZYSCOPE_OPTIONAL_TARGET_TAGS": "32:34:00:00:00:02=env",
+		}, []string{"--mac", "32:34:00:00:00:04,32:34:00:00:00:05", "--target-tag", "32:34:00:00:00:04=flag,vip"},
+			[]string{"32:34:00:00:00:04", "32:34:00:00:00:05"}, []string{"32:34:00:00:00:04=flag,vip"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			if err := loadTestConfig(t, "config.toml", file); err != nil {
+				t.Fatal(err)
+			}
+
+			// Bound the way main binds them
+			flags := pflag.NewFlagSet("rizzyscope", pflag.ContinueOnError)
+			flags.StringSliceP("mac", "m", []string{}, "")
+			flags.StringArray("target-tag", []string{}, "")
+			if err := flags.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+			viper.BindPFlag("required.target_mac", flags.Lookup("mac"))
+			viper.BindPFlag("optional.target_tags", flags.Lookup("target-tag"))
+
+			if macs := configList("required.target_mac"); !slices.Equal(macs, tt.wantMACs) {
+				t.Errorf("required.target_mac = %q, want %q", macs, tt.wantMACs)
+			}
+			if tags := viper.GetStringSlice("optional.target_tags"); !slices.Equal(tags, tt.wantTags) {
+				t.Errorf("optional.target_tags = %q, want %q", tags, tt.wantTags)
+			}
+		})
+	}
+}
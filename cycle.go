@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
+)
+
+// In cycle mode, a target that hasn't been heard this long after it was picked is skipped rather than
+// waited on for the whole interval. It's about one pass of Kismet's channel hopping.
+const cycleSkipAfter = 10 * time.Second
+
+// One move of the cycle on to the next target
+type cycleStep struct {
+	from  *TargetItem        // Target the cycle moved off, nil when it starts
+	heard bool               // from was heard and locked during its turn; if not it was skipped
+	peak  tracker.SignalPeak // from's strongest reading during its turn
+	to    *TargetItem
+}
+
+// The targets the cycle goes through, in target list order
+func (h *hunt) cycleTargets() []*TargetItem {
+	var targets []*TargetItem
+	for _, target := range h.ActiveTargets() {
+		if !target.IsIgnored() && !target.Stale {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Start locking each target in turn. The target already being searched for or locked gets the first turn.
+func (h *hunt) startCycle(uuid string) error {
+	h.cycling = true
+	h.cycleHeard = false
+	h.cycleTarget = h.LockedTarget
+	h.cycleUntil = time.Now().Add(cycleSkipAfter)
+	if !h.Multi {
+		return nil
+	}
+	return h.stopRotation(uuid)
+}
+
+func (h *hunt) stopCycle() {
+	h.cycling = false
+	h.cycleTarget, h.cycleHeard, h.cycleUntil = nil, false, time.Time{}
+}
+
+// Move the cycle on once the current target's turn is up: cycleInterval from when it was locked, or
+// cycleSkipAfter from when it was picked if it hasn't been heard. A target released in the meantime (gone
+// for lock_dwell_seconds, or ignored) ends its turn straight away.
+func (h *hunt) advanceCycle(uuid string, now time.Time) (*cycleStep, error) {
+	if !h.cycling {
+		return nil, nil
+	}
+	current := h.cycleTarget != nil && h.LockedTarget == h.cycleTarget
+	if current && h.ChannelLocked && !h.cycleHeard {
+		h.cycleHeard, h.cycleUntil = true, now.Add(h.cycleInterval)
+	}
+	if current && now.Before(h.cycleUntil) {
+		return nil, nil
+	}
+
+	targets := h.cycleTargets()
+	if len(targets) == 0 {
+		return nil, nil
+	}
+	next := targets[0]
+	if i := slices.Index(targets, h.cycleTarget); i >= 0 {
+		next = targets[(i+1)%len(targets)]
+	}
+
+	step := &cycleStep{from: h.cycleTarget, heard: h.cycleHeard, to: next}
+	if step.from != nil {
+		step.peak = step.from.Peak // Reset by the search below
+	}
+	h.cycleTarget, h.cycleHeard, h.cycleUntil = next, false, now.Add(cycleSkipAfter)
+	if _, err := h.search(next, uuid); err != nil {
+		return step, fmt.Errorf("error hopping channel: %v", err)
+	}
+	return step, nil
+}
+
+// Describe a move of the cycle, e.g. "Cycle: Office AP done (peak -48 dBm), searching for Lobby AP"
+func describeCycleStep(step *cycleStep, formatRSSI func(int) string) string {
+	switch {
+	case step.from == nil || step.from == step.to:
+		return fmt.Sprintf("Cycle: searching for %s", step.to.DisplayValue())
+	case !step.heard:
+		return fmt.Sprintf("Cycle: %s not heard, skipped; searching for %s", step.from.DisplayValue(), step.to.DisplayValue())
+	case step.peak.At.IsZero():
+		return fmt.Sprintf("Cycle: %s done, searching for %s", step.from.DisplayValue(), step.to.DisplayValue())
+	}
+	return fmt.Sprintf("Cycle: %s done (peak %s), searching for %s", step.from.DisplayValue(), formatRSSI(step.peak.RSSI), step.to.DisplayValue())
+}
+
+// Switch cycle mode on or off. It's paused by picking a target by hand, and c starts it again.
+func (m *Model) toggleCycle(uuid string) {
+	if m.cycling {
+		m.stopCycle()
+		m.addTempMessage("Cycle off, staying on the current target")
+		return
+	}
+	if len(m.cycleTargets()) == 0 {
+		m.addTempMessage("No targets to cycle through")
+		return
+	}
+	if err := m.startCycle(uuid); err != nil {
+		m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+	}
+	m.addTempMessage(fmt.Sprintf("Cycling through the targets, %s each", m.cycleInterval))
+}
+
+// Pause cycle mode when a target is picked by hand
+func (m *Model) pauseCycle() {
+	if m.cycling {
+		m.stopCycle()
+		m.addTempMessage("Cycle paused, press c to resume")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Kismet alert types raised by deauthentication and disassociation attacks
+var deauthAlertTypes = []string{"DEAUTHFLOOD", "BCASTDISCON", "DISASSOCTRAFFIC", "DEAUTHCODEINVALID", "DISCONCODEINVALID"}
+
+const (
+	deauthPollInterval = 5 * time.Second // Kismet's alert feed is checked this often, not every poll
+	deauthAlertWindow  = 30              // Seconds of alerts fetched each time, so a slow poll doesn't miss any
+)
+
+// An entry in Kismet's alert feed, simplified to the fields used here
+type kismetAlert struct {
+	Header         string  `json:"kismet.alert.header"`
+	Text           string  `json:"kismet.alert.text"`
+	Timestamp      float64 `json:"kismet.alert.timestamp"`
+	Channel        string  `json:"kismet.alert.channel"`
+	SourceMAC      string  `json:"kismet.alert.source_mac"`
+	DestMAC        string  `json:"kismet.alert.dest_mac"`
+	TransmitterMAC string  `json:"kismet.alert.transmitter_mac"`
+	OtherMAC       string  `json:"kismet.alert.other_mac"`
+}
+
+// Whether the alert names mac as any of its addresses
+func (a kismetAlert) involves(mac string) bool {
+	for _, addr := range []string{a.SourceMAC, a.DestMAC, a.TransmitterMAC, a.OtherMAC} {
+		if strings.EqualFold(addr, mac) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetches the alerts Kismet raised in the last deauthAlertWindow seconds. The window is relative rather
+// than "since the last fetch" so the request path stays the same, which --record-session and --replay need.
+func FetchAlerts(kismetEndpoint string) ([]kismetAlert, error) {
+	kismetEndpoint = fmt.Sprintf("http://%s/alerts/last-time/-%d/alerts.json", kismetEndpoint, deauthAlertWindow)
+
+	req, err := CreateRequest("GET", kismetEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kismet API returned status code %d", resp.StatusCode)
+	}
+
+	var feed struct {
+		Alerts []kismetAlert `json:"kismet.alert.list"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, err
+	}
+	return feed.Alerts, nil
+}
+
+// Watches Kismet's alert feed for deauthentication attacks on the locked target, with optional.deauth_watch
+type deauthWatch struct {
+	lastPoll time.Time
+	since    float64 // Timestamp of the newest alert already looked at
+	failing  bool    // Only the first failure after a success is logged
+}
+
+func newDeauthWatch() *deauthWatch {
+	// Alerts from before startup aren't news
+	return &deauthWatch{since: float64(time.Now().UnixNano()) / 1e9}
+}
+
+// The deauth alerts naming target raised since the last check, if it's time to look again
+func (w *deauthWatch) check(kismetEndpoint string, target *TargetItem, now time.Time) []kismetAlert {
+	if now.Sub(w.lastPoll) < deauthPollInterval {
+		return nil
+	}
+	w.lastPoll = now
+
+	alerts, err := FetchAlerts(kismetEndpoint)
+	switch {
+	case err != nil && !w.failing:
+		w.failing = true
+		slog.Warn("Error fetching Kismet alerts, deauth attacks won't be noticed until it recovers", "err", err)
+	case err == nil && w.failing:
+		w.failing = false
+		slog.Info("Kismet alerts are being fetched again")
+	}
+
+	var matched []kismetAlert
+	newest := w.since
+	for _, a := range alerts {
+		if a.Timestamp <= w.since {
+			continue
+		}
+		newest = max(newest, a.Timestamp)
+		if slices.Contains(deauthAlertTypes, a.Header) && a.involves(target.Value) {
+			matched = append(matched, a)
+		}
+	}
+	w.since = newest
+	return matched
+}
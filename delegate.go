@@ -0,0 +1,45 @@
+package main
+
+import (
+	"io"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// List delegate that marks the locked target with a ► prefix and the theme's Good color
+type targetDelegate struct {
+	list.DefaultDelegate
+	locked       func() *TargetItem
+	lockedStyles list.DefaultItemStyles
+}
+
+func newTargetDelegate(styles Styles, locked func() *TargetItem) targetDelegate {
+	lockedStyles := list.NewDefaultItemStyles()
+	lockedStyles.NormalTitle = lockedStyles.NormalTitle.Foreground(styles.Theme.Good).Bold(true)
+	lockedStyles.SelectedTitle = lockedStyles.SelectedTitle.Foreground(styles.Theme.Good).BorderForeground(styles.Theme.Good).Bold(true)
+
+	return targetDelegate{
+		DefaultDelegate: list.NewDefaultDelegate(),
+		locked:          locked,
+		lockedStyles:    lockedStyles,
+	}
+}
+
+func (d targetDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	target, ok := item.(*TargetItem)
+	if !ok || target != d.locked() {
+		d.DefaultDelegate.Render(w, m, index, item)
+		return
+	}
+
+	lockedDelegate := d.DefaultDelegate
+	lockedDelegate.Styles = d.lockedStyles
+	lockedDelegate.Render(w, m, index, lockedItem{target})
+}
+
+// Wraps the locked target so its title carries the marker
+type lockedItem struct {
+	*TargetItem
+}
+
+func (i lockedItem) Title() string { return "► " + i.TargetItem.Title() }
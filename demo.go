@@ -0,0 +1,255 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	demoInterface = "demo0"
+	demoUUID      = "00000000-0000-0000-0000-00000000d3e0"
+
+	demoMinRSSI    = -95
+	demoMaxRSSI    = -30
+	demoRSSIStep   = 3.0  // Standard deviation of each step of an RSSI random walk
+	demoVanishOdds = 0.01 // Chance per poll that a device goes quiet
+	demoReturnOdds = 0.05 // Chance per poll that a quiet device comes back
+	demoClientOdds = 0.04 // Chance per poll that an AP gains or loses a client
+
+	demoLat = 51.5007 // Where the simulated GPS starts
+	demoLon = -0.1246
+)
+
+// A simulated device
+type demoDevice struct {
+	mac       string
+	ssid      string
+	manuf     string
+	crypt     string
+	kind      string
+	channel   string
+	rssi      float64
+	present   bool
+	clients   map[string]string
+	firstSeen time.Time
+}
+
+// Stands in for Kismet with a handful of simulated devices. Each device listing advances the simulation
+// by one step: RSSI follows a random walk, devices occasionally go quiet and come back, and APs gain and
+// lose clients. Only devices on the locked channel are heard while it is locked. The same seed always
+// plays out the same way.
+type demoTransport struct {
+	mu      sync.Mutex
+	rng     *rand.Rand
+	devices []*demoDevice
+	locked  string // Channel the interface is locked to, empty while hopping
+	step    int
+}
+
+func newDemoTransport(seed int64) *demoTransport {
+	d := &demoTransport{rng: rand.New(rand.NewSource(seed))}
+
+	now := time.Now()
+	add := func(mac, ssid, manuf, crypt, kind, channel string, rssi float64) *demoDevice {
+		dev := &demoDevice{
+			mac: mac, ssid: ssid, manuf: manuf, crypt: crypt, kind: kind, channel: channel,
+			rssi: rssi, present: true, clients: map[string]string{}, firstSeen: now,
+		}
+		d.devices = append(d.devices, dev)
+		return dev
+	}
+
+	add("02:DE:00:00:00:01", "", "Apple", "None", "Wi-Fi Client", "6", -58)
+	add("02:DE:00:00:00:02", "", "Samsung", "None", "Wi-Fi Client", "11", -72)
+	ap := add("02:DE:00:00:00:03", "CoffeeShop", "TP-Link", "WPA2 WPA2-PSK AES-CCMP", "Wi-Fi AP", "1", -64)
+	add("02:DE:00:00:00:04", "Rogue-AP", "Espressif", "None", "Wi-Fi AP", "36", -80)
+	add("02:DE:00:00:00:05", "", "Intel", "None", "Wi-Fi Client", "6", -88).present = false
+
+	// Background devices that aren't targets
+	add("02:DE:00:00:01:01", "Neighbors", "Netgear", "WPA2 WPA2-PSK AES-CCMP", "Wi-Fi AP", "6", -75)
+	add("02:DE:00:00:01:02", "", "Google", "None", "Wi-Fi Client", "1", -82)
+
+	ap.clients["02:DE:00:00:02:01"] = "associated"
+
+	return d
+}
+
+// The targets shown in demo mode, matching the simulated devices
+func demoTargets() []*TargetItem {
+	return []*TargetItem{
+		{Value: "02:DE:00:00:00:01", TType: MAC, Label: "Demo phone", Tags: []string{"phones"}},
+		{Value: "02:DE:00:00:00:02", TType: MAC, Label: "Demo tablet", Tags: []string{"phones"}},
+		{Value: "CoffeeShop", TType: SSID, Tags: []string{"aps"}},
+		{Value: "Rogue-AP", TType: SSID, Label: "Rogue AP", Tags: []string{"aps"}},
+		{Value: "02:DE:00:00:00:05", TType: MAC, Label: "Demo laptop"},
+	}
+}
+
+func (d *demoTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	path := req.URL.Path
+	switch {
+	case path == "/datasource/all_sources.json":
+		return jsonResponse(req, []map[string]any{{
+			"kismet.datasource.interface": demoInterface,
+			"kismet.datasource.uuid":      demoUUID,
+		}})
+
+	case strings.HasSuffix(path, "/set_channel.cmd"):
+		var payload map[string]string
+		json.Unmarshal(body, &payload)
+		d.locked = payload["channel"]
+		slog.Debug("Demo: locked channel", "channel", d.locked)
+		return jsonResponse(req, map[string]any{})
+
+	case strings.HasSuffix(path, "/set_hop.cmd"):
+		d.locked = ""
+		return jsonResponse(req, map[string]any{})
+
+	case path == "/gps/location.json":
+		return jsonResponse(req, d.location())
+
+	case strings.HasPrefix(path, "/alerts/last-time/"):
+		return jsonResponse(req, map[string]any{"kismet.alert.list": []any{}})
+
+	case path == "/devices/last-time/-5/devices.json" && req.Method == http.MethodGet:
+		d.advance()
+		return jsonResponse(req, d.heard(nil))
+
+	case path == "/devices/last-time/-5/devices.json":
+		var payload KismetPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return syntheticResponse(req, http.StatusBadRequest, err.Error()), nil
+		}
+		d.advance()
+		return jsonResponse(req, d.heard(payload.Fields))
+
+	default:
+		return syntheticResponse(req, http.StatusNotFound, "not simulated: "+path), nil
+	}
+}
+
+// Advance the simulation by one poll
+func (d *demoTransport) advance() {
+	d.step++
+	for _, dev := range d.devices {
+		if !dev.present {
+			dev.present = d.rng.Float64() < demoReturnOdds
+			continue
+		}
+		if d.rng.Float64() < demoVanishOdds {
+			dev.present = false
+			continue
+		}
+
+		dev.rssi = math.Max(demoMinRSSI, math.Min(demoMaxRSSI, dev.rssi+d.rng.NormFloat64()*demoRSSIStep))
+
+		if dev.kind == "Wi-Fi AP" && d.rng.Float64() < demoClientOdds {
+			if len(dev.clients) > 0 && d.rng.Intn(2) == 0 {
+				for client := range dev.clients {
+					delete(dev.clients, client)
+					break
+				}
+			} else {
+				dev.clients[fmt.Sprintf("02:DE:00:00:02:%02X", d.rng.Intn(256))] = "associated"
+			}
+		}
+	}
+}
+
+// Every device currently heard as a full Kismet device record, or simplified to fields if given
+func (d *demoTransport) heard(fields [][]string) []map[string]any {
+	var devices []map[string]any
+	for _, dev := range d.devices {
+		if !dev.present || (d.locked != "" && dev.channel != d.locked) {
+			continue
+		}
+
+		record := map[string]any{
+			"kismet.device.base.macaddr":    dev.mac,
+			"kismet.device.base.channel":    dev.channel,
+			"kismet.device.base.manuf":      dev.manuf,
+			"kismet.device.base.crypt":      dev.crypt,
+			"kismet.device.base.type":       dev.kind,
+			"kismet.device.base.first_time": float64(dev.firstSeen.Unix()),
+			"kismet.device.base.signal": map[string]any{
+				"kismet.common.signal.last_signal": math.Round(dev.rssi),
+			},
+			"dot11.device": map[string]any{
+				"dot11.device.last_beaconed_ssid_record": map[string]any{"dot11.advertisedssid.ssid": dev.ssid},
+				"dot11.device.associated_client_map":     dev.clients,
+			},
+		}
+		if fields == nil {
+			devices = append(devices, record)
+			continue
+		}
+
+		devices = append(devices, simplifyDevice(record, fields))
+	}
+	return devices
+}
+
+// Like Kismet, answer a field list with just those fields of a device record, renamed to their aliases
+func simplifyDevice(record map[string]any, fields [][]string) map[string]any {
+	simplified := map[string]any{}
+	for _, field := range fields {
+		if len(field) != 2 {
+			continue
+		}
+		if value, ok := deviceField(record, field[0]); ok {
+			simplified[field[1]] = value
+		}
+	}
+	return simplified
+}
+
+// Look up a slash-separated field path in a device record
+func deviceField(record map[string]any, path string) (any, bool) {
+	var value any = record
+	for _, key := range strings.Split(path, "/") {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[key]; !ok {
+			return nil, false
+		}
+	}
+	return value, true
+}
+
+// A GPS fix slowly circling the starting point
+func (d *demoTransport) location() map[string]any {
+	angle := float64(d.step) / 200
+	return map[string]any{
+		"kismet.common.location.fix":      3,
+		"kismet.common.location.geopoint": []float64{demoLon + 0.002*math.Cos(angle), demoLat + 0.001*math.Sin(angle)},
+		"kismet.common.location.alt":      12.0,
+	}
+}
+
+// A 200 response carrying v as JSON, for the demo and kismetdb transports
+func jsonResponse(req *http.Request, v any) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return syntheticResponse(req, http.StatusOK, string(body)), nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Poll errors kept for the state snapshot
+const recentErrorCount = 20
+
+// Sent into the TUI when SIGUSR1 asks for a state dump
+type dumpStateMsg struct{}
+
+// The state of the hunt, as written by a state dump and served by the JSON API, for dashboards and for working
+// out why a running instance stopped tracking
+type stateSnapshot struct {
+	Time           time.Time        `json:"time"`
+	StartedAt      time.Time        `json:"started_at"`
+	Uptime         string           `json:"uptime"`
+	KismetEndpoint string           `json:"kismet_endpoint"`
+	KismetVersion  string           `json:"kismet_version,omitempty"` // Absent if it couldn't be read at startup
+	Interfaces     []string         `json:"interfaces"`
+	Hopping        bool             `json:"hopping"`              // The interface is hopping channels
+	Multi          bool             `json:"multi,omitempty"`      // Watching every visible target at once
+	ActiveTag      string           `json:"active_tag,omitempty"` // Only targets with this tag are searched for
+	Locked         *lockedSnapshot  `json:"locked"`               // Target being searched for or locked onto, null if none
+	Targets        []targetSnapshot `json:"targets"`
+	RecentErrors   []recentError    `json:"recent_errors"` // Oldest first
+}
+
+// A configured target
+type targetSnapshot struct {
+	Target   string     `json:"target"`         // Label, MAC, or SSID for SSID targets
+	Type     string     `json:"type"`           // "mac" or "ssid"
+	MAC      string     `json:"mac,omitempty"`  // Empty for an SSID target until it's been heard
+	SSID     string     `json:"ssid,omitempty"` // Set for SSID targets
+	Label    string     `json:"label,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	Ignored  bool       `json:"ignored"`
+	Stale    bool       `json:"stale"`
+	RSSI     *int       `json:"rssi,omitempty"`      // dBm from the last poll that saw it, absent until it's been seen
+	LastSeen *time.Time `json:"last_seen,omitempty"` // Absent until it's been seen
+	Channel  string     `json:"channel,omitempty"`   // Channel it was last heard on
+	Channels []string   `json:"channels,omitempty"`  // Every channel it has been heard on this session
+}
+
+// The target being searched for or locked onto
+type lockedSnapshot struct {
+	targetSnapshot
+	ChannelLocked bool       `json:"channel_locked"`           // Heard, and the channel locked to it
+	LockedChannel string     `json:"locked_channel,omitempty"` // Channel the interface is locked to
+	LockedAt      *time.Time `json:"locked_at,omitempty"`
+	LastReceived  time.Time  `json:"last_received"`
+	LockedRSSI    int        `json:"locked_rssi"` // dBm of the latest reading
+	Quiet         bool       `json:"quiet"`       // Not heard for a while
+	PacketRate    *float64   `json:"packets_per_second,omitempty"`
+	Details       string     `json:"details,omitempty"` // e.g. "WiFi 5 · 80MHz · WPS off · 14.2k pkts"
+}
+
+// An error a poll ran into
+type recentError struct {
+	Time  time.Time `json:"time"`
+	Error string    `json:"error"`
+}
+
+// Keep the errors a poll ran into, for the snapshot
+func (h *hunt) noteErrors(result pollResult, at time.Time) {
+	for _, err := range result.errs {
+		h.recentErrors = append(h.recentErrors, recentError{Time: at, Error: err.Error()})
+	}
+	if result.lockErr != nil {
+		h.recentErrors = append(h.recentErrors, recentError{Time: at, Error: fmt.Sprintf("failed to lock channel: %v", result.lockErr)})
+	}
+	if excess := len(h.recentErrors) - recentErrorCount; excess > 0 {
+		h.recentErrors = slices.Delete(h.recentErrors, 0, excess)
+	}
+}
+
+// Take a snapshot of the hunt as it stands
+func snapshotState(h *hunt, at time.Time) stateSnapshot {
+	snapshot := stateSnapshot{
+		Time:           at,
+		StartedAt:      h.startedAt,
+		Uptime:         at.Sub(h.startedAt).Round(time.Second).String(),
+		KismetEndpoint: h.kismetEndpoint,
+		Interfaces:     slices.Clone(h.iface),
+		Hopping:        !h.ChannelLocked && h.rotateChannel == "",
+		Multi:          h.Multi,
+		ActiveTag:      h.ActiveTag,
+		Targets:        make([]targetSnapshot, 0, len(h.Targets)),
+		RecentErrors:   append([]recentError{}, h.recentErrors...),
+	}
+	if h.kismetStatus != nil {
+		snapshot.KismetVersion = h.kismetStatus.Version
+	}
+	for _, target := range h.Targets {
+		snapshot.Targets = append(snapshot.Targets, snapshotTarget(target))
+	}
+	if locked := h.LockedTarget; locked != nil {
+		s := &lockedSnapshot{
+			targetSnapshot: snapshotTarget(locked),
+			ChannelLocked:  h.ChannelLocked,
+			LastReceived:   h.LastReceived,
+			LockedRSSI:     h.RSSI,
+			Quiet:          h.Quiet,
+		}
+		if h.ChannelLocked {
+			lockedAt := h.LockedAt
+			s.LockedChannel, s.LockedAt = h.Channel, &lockedAt
+		}
+		if h.activity.known {
+			rate := h.activity.rate
+			s.PacketRate = &rate
+		}
+		if h.readingTarget == locked {
+			s.Details = h.reading.Details()
+		}
+		snapshot.Locked = s
+	}
+	return snapshot
+}
+
+func snapshotTarget(target *TargetItem) targetSnapshot {
+	s := targetSnapshot{
+		Target:   target.DisplayValue(),
+		Type:     "mac",
+		MAC:      target.Value,
+		Label:    target.Label,
+		Tags:     slices.Clone(target.Tags),
+		Ignored:  target.IsIgnored(),
+		Stale:    target.Stale,
+		Channel:  target.Channel,
+		Channels: slices.Clone(target.Channels),
+	}
+	if target.TType == SSID {
+		s.Type, s.SSID = "ssid", target.OriginalValue
+		if target.OriginalValue == "" {
+			s.SSID, s.MAC = target.Value, "" // Value is still the SSID until the AP is found
+		}
+	}
+	if !target.LastSeen.IsZero() {
+		rssi, lastSeen := target.LastRSSI, target.LastSeen
+		s.RSSI, s.LastSeen = &rssi, &lastSeen
+	}
+	return s
+}
+
+// Write a snapshot of the hunt to a timestamped JSON file in dumpDir, returning the file's path
+func (h *hunt) dumpState(now time.Time) (string, error) {
+	data, err := json.MarshalIndent(snapshotState(h, now), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("error encoding the state: %v", err)
+	}
+	path := filepath.Join(h.dumpDir, "rizzyscope-state-"+now.Format("20060102-150405")+".json")
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return "", fmt.Errorf("error writing the state dump: %v", err)
+	}
+	return path, nil
+}
+
+// Relay the signals that ask for a state dump to c. There are none on some platforms, and then nothing is
+// relayed, since signal.Notify without any signals would relay them all.
+func notifyDumpSignals(c chan<- os.Signal) {
+	if len(dumpSignals) > 0 {
+		signal.Notify(c, dumpSignals...)
+	}
+}
+
+// Send a dumpStateMsg into the program on every SIGUSR1
+func forwardDumpSignals(p *tea.Program) {
+	usr1 := make(chan os.Signal, 1)
+	notifyDumpSignals(usr1)
+	go func() {
+		for range usr1 {
+			p.Send(dumpStateMsg{})
+		}
+	}()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+)
+
+func TestDumpState(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "44", RSSI: -52, Type: "Wi-Fi AP"})
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC, Label: "Office AP"}
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC, Ignored: true}
+	h, uuid := testHunt(t, kismet, ap, phone)
+	h.dumpDir = t.TempDir()
+	h.logKismetStatus()
+	h.poll(uuid)
+	h.noteErrors(pollResult{errs: []error{errors.New("kismet API returned status code 500")}, lockErr: errNoChannel}, time.Now())
+
+	now := time.Date(2026, 10, 15, 14, 25, 1, 0, time.Local)
+	path, err := h.dumpState(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(h.dumpDir, "rizzyscope-state-20261015-142501.json"); path != want {
+		t.Errorf("dumped to %s, want %s", path, want)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var dump stateSnapshot
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatal(err)
+	}
+	if dump.KismetEndpoint != kismet.Endpoint() || dump.KismetVersion != "testkismet" || len(dump.Interfaces) != 1 ||
+		dump.Uptime == "" || dump.Hopping {
+		t.Errorf("dump = %+v", dump)
+	}
+	if l := dump.Locked; l == nil || l.Target != "Office AP" || !l.ChannelLocked || l.LockedChannel != "44" || l.LockedRSSI != -52 {
+		t.Errorf("locked = %+v, want the AP locked at -52 dBm", l)
+	}
+	if len(dump.Targets) != 2 || !dump.Targets[1].Ignored || dump.Targets[1].RSSI != nil {
+		t.Errorf("targets = %+v, want both, with the phone ignored and unseen", dump.Targets)
+	}
+	if len(dump.RecentErrors) != 2 || dump.RecentErrors[0].Error != "kismet API returned status code 500" ||
+		dump.RecentErrors[1].Error != "failed to lock channel: no channel to lock to" {
+		t.Errorf("recent errors = %+v", dump.RecentErrors)
+	}
+
+	// Only the latest errors are kept
+	for range recentErrorCount {
+		h.noteErrors(pollResult{errs: []error{errors.New("timeout")}}, time.Now())
+	}
+	if len(h.recentErrors) != recentErrorCount || h.recentErrors[0].Error != "timeout" {
+		t.Errorf("%d errors kept, oldest %q", len(h.recentErrors), h.recentErrors[0].Error)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build windows || plan9
+
+package main
+
+import "os"
+
+// There is no SIGUSR1 here, so the state can't be dumped on demand
+var dumpSignals []os.Signal
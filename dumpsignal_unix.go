@@ -0,0 +1,11 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals that ask for a state dump
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
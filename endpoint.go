@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const defaultKismetPort = "2501"
+
+// Parse the Kismet endpoint from the config or flags into the host:port every request is built from. It
+// takes "host:port", "[::1]:2501", a bare host or IPv6 address (on Kismet's default port), and the same
+// with an http:// prefix or a trailing slash. IPv6 hosts come back in brackets so they can go in a URL.
+func parseKismetEndpoint(raw string) (string, error) {
+	endpoint := strings.TrimSpace(raw)
+	if scheme, rest, ok := strings.Cut(endpoint, "://"); ok {
+		if !strings.EqualFold(scheme, "http") {
+			return "", fmt.Errorf("invalid Kismet endpoint %q: only http is supported, not %s", raw, scheme)
+		}
+		endpoint = rest
+	}
+	endpoint = strings.TrimRight(endpoint, "/")
+	if endpoint == "" {
+		return "", fmt.Errorf("invalid Kismet endpoint %q: expected host:port, e.g. 127.0.0.1:2501", raw)
+	}
+	if strings.ContainsAny(endpoint, "/?#@ ") {
+		return "", fmt.Errorf("invalid Kismet endpoint %q: expected host:port without a path", raw)
+	}
+
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		// No port: a bare host, or an IPv6 address with or without brackets
+		host, port = strings.TrimSuffix(strings.TrimPrefix(endpoint, "["), "]"), defaultKismetPort
+		if strings.Contains(host, ":") && net.ParseIP(host) == nil {
+			return "", fmt.Errorf("invalid Kismet endpoint %q: IPv6 addresses with a port go in brackets, e.g. [::1]:2501", raw)
+		}
+	}
+	if host == "" {
+		return "", fmt.Errorf("invalid Kismet endpoint %q: no host", raw)
+	}
+	// A link-local address's zone, e.g. fe80::1%eth0, is escaped as it has to be in a URL
+	if addr, zone, ok := strings.Cut(host, "%"); ok && !strings.HasPrefix(zone, "25") {
+		host = addr + "%25" + zone
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return "", fmt.Errorf("invalid Kismet endpoint %q: port %q is not a number from 1 to 65535", raw, port)
+	}
+	if _, err := url.Parse("http://" + net.JoinHostPort(host, port)); err != nil {
+		return "", fmt.Errorf("invalid Kismet endpoint %q: %v", raw, err)
+	}
+
+	return net.JoinHostPort(host, port), nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestParseKismetEndpoint(t *testing.T) {
+	valid := map[string]string{
+		"127.0.0.1:2501":         "127.0.0.1:2501",
+		" 127.0.0.1:2501 ":       "127.0.0.1:2501",
+		"127.0.0.1:2501/":        "127.0.0.1:2501",
+		"http://127.0.0.1:2501/": "127.0.0.1:2501",
+		"HTTP://kismet.lan:8080": "kismet.lan:8080",
+		"kismet.lan":             "kismet.lan:2501",
+		"[::1]:2501":             "[::1]:2501",
+		"http://[::1]:2501/":     "[::1]:2501",
+		"[fe80::1%eth0]:2501":    "[fe80::1%25eth0]:2501",
+		"[fe80::1%25eth0]:2501":  "[fe80::1%25eth0]:2501",
+		"::1":                    "[::1]:2501",
+		"[::1]":                  "[::1]:2501",
+	}
+	for raw, want := range valid {
+		if got, err := parseKismetEndpoint(raw); err != nil || got != want {
+			t.Errorf("parseKismetEndpoint(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{
+		"",
+		"/",
+		":2501",
+		"127.0.0.1:",
+		"127.0.0.1:0",
+		"127.0.0.1:70000",
+		"127.0.0.1:kismet",
+		"https://127.0.0.1:2501",
+		"127.0.0.1:2501/api",
+		"user@127.0.0.1:2501",
+		"kismet lan:2501",
+		"fe80::zz:2501",
+	} {
+		if got, err := parseKismetEndpoint(raw); err == nil {
+			t.Errorf("parseKismetEndpoint(%q) = %q, want an error", raw, got)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Event types emitted by headless mode. These names and the event field names are a stable interface for
+// anything consuming --output json; don't rename them.
+const (
+	eventTargetFound   = "target_found"
+	eventRSSISample    = "rssi_sample"
+	eventChannelLocked = "channel_locked"
+	eventTargetLost    = "target_lost"
+	eventTargetDropped = "target_dropped"
+	eventTargetMoved   = "target_moved"
+	eventMACConflict   = "mac_conflict"
+	eventProximity     = "proximity"
+	eventDeauthAlert   = "deauth_alert"
+	eventColocation    = "colocation"
+	eventKismetError   = "kismet_error"
+)
+
+// A single state transition or reading from the tracker
+type event struct {
+	Time         time.Time `json:"time"`
+	Type         string    `json:"type"`
+	Target       string    `json:"target,omitempty"` // MAC, or SSID for SSID targets
+	MAC          string    `json:"mac,omitempty"`    // MAC of the device being tracked
+	Channel      string    `json:"channel,omitempty"`
+	RSSI         *int      `json:"rssi,omitempty"`   // dBm; always set on rssi_sample, proximity and colocation events
+	Locked       *bool     `json:"locked,omitempty"` // Channel is locked to the target; always set on rssi_sample and channel_locked events
+	Manufacturer string    `json:"manufacturer,omitempty"`
+	SSID         string    `json:"ssid,omitempty"`
+	Encryption   string    `json:"encryption,omitempty"`
+	DeviceType   string    `json:"device_type,omitempty"`
+	Details      string    `json:"details,omitempty"` // e.g. "WiFi 5 · 80MHz · WPS off · 14.2k pkts"
+	Error        string    `json:"error,omitempty"`
+	Alert        string    `json:"alert,omitempty"`        // Kismet alert type, e.g. DEAUTHFLOOD
+	Message      string    `json:"message,omitempty"`      // Kismet's description of the alert
+	Other        string    `json:"other,omitempty"`        // Second target of a colocation event
+	OtherMAC     string    `json:"other_mac,omitempty"`    // Its MAC
+	FromChannel  string    `json:"from_channel,omitempty"` // Channel a target_moved target moved off
+	Channels     []string  `json:"channels,omitempty"`     // Channels a mac_conflict MAC was seen on at once
+}
+
+// Turn the outcome of a poll into events, in the order they happened
+func pollEvents(t *hunt, result pollResult, at time.Time) []event {
+	var events []event
+
+	for _, err := range result.errs {
+		events = append(events, event{Time: at, Type: eventKismetError, Error: err.Error()})
+	}
+	if result.lockErr != nil {
+		events = append(events, event{Time: at, Type: eventKismetError, Error: fmt.Sprintf("failed to lock channel: %v", result.lockErr)})
+	}
+
+	if result.dropped != nil {
+		events = append(events, event{Time: at, Type: eventTargetDropped, Target: result.dropped.DisplayValue(), MAC: result.dropped.Value})
+	}
+	for _, pair := range result.colocated {
+		rssi := pair.a.LastRSSI
+		events = append(events, event{Time: at, Type: eventColocation, Target: pair.a.DisplayValue(), MAC: pair.a.Value, RSSI: &rssi,
+			Other: pair.b.DisplayValue(), OtherMAC: pair.b.Value})
+	}
+
+	if t.LockedTarget == nil {
+		return events
+	}
+	target := event{Time: at, Target: t.LockedTarget.DisplayValue(), MAC: t.LockedTarget.Value, Channel: t.Channel}
+
+	if result.found {
+		e := target
+		e.Type = eventTargetFound
+		events = append(events, e)
+	}
+	if result.locked {
+		e := target
+		e.Type = eventChannelLocked
+		locked := true
+		e.Locked = &locked
+		e.Manufacturer = result.reading.Manufacturer
+		e.SSID = result.reading.SSID
+		e.Encryption = result.reading.Crypt
+		e.DeviceType = result.reading.Type
+		e.Details = result.reading.Details()
+		events = append(events, e)
+	}
+	if result.movedFrom != "" {
+		e := target
+		e.Type = eventTargetMoved
+		e.FromChannel = result.movedFrom
+		events = append(events, e)
+	}
+	if result.conflict != nil {
+		e := target
+		e.Type = eventMACConflict
+		e.Message = describeConflict(t.LockedTarget, result.conflict)
+		for _, reading := range result.conflict {
+			e.Channels = append(e.Channels, reading.Channel)
+		}
+		events = append(events, e)
+	}
+	if result.reading != nil {
+		e := target
+		e.Type = eventRSSISample
+		rssi, locked := t.RSSI, t.ChannelLocked
+		e.RSSI, e.Locked = &rssi, &locked
+		events = append(events, e)
+	}
+	if result.near != nil {
+		e := target
+		e.Type = eventProximity
+		rssi := result.near.rssi
+		e.RSSI = &rssi
+		events = append(events, e)
+	}
+	for _, a := range result.deauth {
+		e := target
+		e.Type = eventDeauthAlert
+		e.Alert = a.Header
+		e.Message = a.Text
+		events = append(events, e)
+	}
+	if result.lost {
+		e := target
+		e.Type = eventTargetLost
+		events = append(events, e)
+	}
+
+	return events
+}
+
+// Where headless mode sends its events
+type emitter interface {
+	emit(e event)
+}
+
+// Writes newline-delimited JSON events
+type jsonEmitter struct {
+	enc *json.Encoder
+}
+
+func newJSONEmitter(w io.Writer) *jsonEmitter {
+	return &jsonEmitter{enc: json.NewEncoder(w)}
+}
+
+func (j *jsonEmitter) emit(e event) {
+	if err := j.enc.Encode(e); err != nil {
+		slog.Error("Error writing event", "err", err)
+	}
+}
+
+// Prints one line per reading and logs every other event
+type textEmitter struct {
+	w io.Writer
+}
+
+func (t *textEmitter) emit(e event) {
+	switch e.Type {
+	case eventRSSISample:
+		state := "searching"
+		if *e.Locked {
+			state = "locked"
+		}
+		// e.g. "2024-05-03T10:12:01Z AA:BB:CC:DD:EE:FF ch6 -61dBm locked"
+		fmt.Fprintf(t.w, "%s %s ch%s %ddBm %s\n", e.Time.UTC().Format(time.RFC3339), e.MAC, e.Channel, *e.RSSI, state)
+	case eventTargetFound:
+		slog.Info("Found target", "target", e.Target, "channel", e.Channel)
+	case eventChannelLocked:
+		slog.Info("Locked to target", "target", e.Target, "channel", e.Channel, "make", e.Manufacturer,
+			"ssid", e.SSID, "encryption", e.Encryption, "type", e.DeviceType)
+	case eventTargetLost:
+		slog.Warn("Lost target", "target", e.Target)
+	case eventTargetDropped:
+		slog.Warn("Lost target, resuming scan", "target", e.Target)
+	case eventProximity:
+		slog.Warn("Target within reach", "target", e.Target, "rssi", *e.RSSI)
+	case eventMACConflict:
+		slog.Warn(e.Message, "channels", e.Channels)
+	case eventDeauthAlert:
+		slog.Warn("Deauthentication attack on target", "target", e.Target, "alert", e.Alert, "message", e.Message)
+	case eventKismetError:
+		// Kismet API errors are already logged where they happen
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata with the current output")
+
+// Compare got to the golden file testdata/name, or rewrite it with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs from the golden file (rerun with -update if the change is intended)\ngot:\n%s\nwant:\n%s", path, got, want)
+	}
+}
+
+// The JSON of each event type is a stable interface, so every one is pinned by a golden file
+func TestJSONEventsGolden(t *testing.T) {
+	at := time.Date(2026, 10, 15, 14, 25, 1, 0, time.UTC)
+	target := event{Time: at, Target: "Phone", MAC: "32:34:00:00:00:01", Channel: "6"}
+	with := func(e event, change func(*event)) event {
+		change(&e)
+		return e
+	}
+	rssi := func(v int) *int { return &v }
+	locked := func(v bool) *bool { return &v }
+
+	tests := []struct {
+		name  string // Of the golden file, event_<name>.golden
+		event event
+	}{
+		{eventTargetFound, with(target, func(e *event) { e.Type = eventTargetFound })},
+		{eventRSSISample, with(target, func(e *event) { e.Type, e.RSSI, e.Locked = eventRSSISample, rssi(-57), locked(true) })},
+		// A sample from before the channel is locked still says so, rather than leaving locked out
+		{eventRSSISample + "_unlocked", with(target, func(e *event) { e.Type, e.RSSI, e.Locked = eventRSSISample, rssi(-57), locked(false) })},
+		{eventChannelLocked, with(target, func(e *event) {
+			e.Type, e.Locked, e.Manufacturer, e.SSID = eventChannelLocked, locked(true), "Apple, Inc.", "CoffeeShop"
+			e.Encryption, e.DeviceType, e.Details = "WPA2-PSK", "Wi-Fi Client", "WiFi 5 · 80MHz · 1.2k pkts"
+		})},
+		{eventTargetLost, with(target, func(e *event) { e.Type = eventTargetLost })},
+		{eventTargetDropped, event{Time: at, Type: eventTargetDropped, Target: "Phone", MAC: "32:34:00:00:00:01"}},
+		{eventTargetMoved, with(target, func(e *event) { e.Type, e.Channel, e.FromChannel = eventTargetMoved, "11", "6" })},
+		{eventMACConflict, with(target, func(e *event) {
+			e.Type, e.Message, e.Channels = eventMACConflict, "32:34:00:00:00:01 is on channels 6 and 11 at once", []string{"6", "11"}
+		})},
+		{eventProximity, with(target, func(e *event) { e.Type, e.RSSI = eventProximity, rssi(-42) })},
+		{eventDeauthAlert, with(target, func(e *event) {
+			e.Type, e.Alert, e.Message = eventDeauthAlert, "DEAUTHFLOOD", "Deauthenticate/Disassociate flood"
+		})},
+		{eventColocation, event{Time: at, Type: eventColocation, Target: "Phone", MAC: "32:34:00:00:00:01", RSSI: rssi(-57), Other: "Laptop",
+			OtherMAC: "32:34:00:00:00:02"}},
+		{eventKismetError, event{Time: at, Type: eventKismetError, Error: "kismet API returned status code 500"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			newJSONEmitter(&buf).emit(tt.event)
+			checkGolden(t, "event_"+tt.name+".golden", buf.Bytes())
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// What fills the screen. The grid is the normal multi-pane layout; the others give a single pane the
+// whole terminal for small screens, cycled with F.
+type screenView int
+
+const (
+	viewGrid screenView = iota
+	viewChart
+	viewTargets
+	viewClients
+	viewCount
+)
+
+func (v screenView) String() string {
+	switch v {
+	case viewChart:
+		return "chart"
+	case viewTargets:
+		return "targets"
+	case viewClients:
+		return "clients"
+	default:
+		return "grid"
+	}
+}
+
+// Switch to the next view
+func (m *Model) cycleScreenView() {
+	m.screenView = (m.screenView + 1) % viewCount
+	m.applyLayout()
+}
+
+// Pane budgets when a single pane fills the screen, leaving the last row for the footer
+func focusLayout(width, height int) layout {
+	return layout{
+		stacked:     true,
+		width:       width,
+		height:      height,
+		leftWidth:   width,
+		rightWidth:  width,
+		listHeight:  max(height-listChromeRows-stackedHelpLines-paneBorderRows-focusFooterRows, minListHeight),
+		chartLevels: max(height-rssiBarRows-paneBorderRows-chartExtraRows-focusFooterRows, minChartLevels), // Under the RSSI bar pane
+		kismetRows:  max(height-kismetHeaderRows-paneBorderRows-focusFooterRows, minKismetRows),
+	}
+}
+
+// Client counts kept for the sparkline in the clients pane title, one per poll
+const clientHistoryLen = 16
+
+// Keep the locked target's associated clients from its latest reading, with their manufacturers from the
+// device listing it came with
+func (m *Model) updateClients(target *TargetItem, reading *DeviceInfo, devices []map[string]interface{}) {
+	if target != m.clientsOf {
+		m.clientCounts = nil
+	}
+	m.clientsOf = target
+	m.clients = reading.ClientMACs()
+	makes := map[string]string{}
+	for _, device := range devices {
+		if mac, _ := device["base.macaddr"].(string); reading.AssociatedClients[mac] != "" {
+			makes[mac], _ = device["Make"].(string)
+		}
+	}
+	m.clientMakes = map[string]string{}
+	for _, client := range m.clients {
+		m.clientMakes[client] = tracker.ResolveManufacturer(client, makes[client])
+	}
+	m.clientCounts = append(m.clientCounts, len(m.clients))
+	if len(m.clientCounts) > clientHistoryLen {
+		m.clientCounts = m.clientCounts[1:]
+	}
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// A one-line chart of the values, scaled from zero to the largest so an empty AP stays at the bottom
+func sparkline(values []int) string {
+	top := slices.Max(append([]int{0}, values...))
+	spark := make([]rune, len(values))
+	for i, v := range values {
+		level := 0
+		if top > 0 {
+			level = v * (len(sparkBlocks) - 1) / top
+		}
+		spark[i] = sparkBlocks[level]
+	}
+	return string(spark)
+}
+
+// Render the single pane for the current view, followed by the footer and status bar
+func (m *Model) viewFocused() string {
+	var pane string
+	switch m.screenView {
+	case viewChart:
+		pane = lipgloss.JoinVertical(lipgloss.Left,
+			m.renderRSSIProgressBar(m.layout.width),
+			m.renderSignalPane(m.layout.width, m.layout.chartLevels),
+		)
+	case viewTargets:
+		pane = m.renderTargetListWithHelp(m.layout.width)
+	case viewClients:
+		pane = m.renderClientsPane(m.layout.width)
+	}
+
+	return lipgloss.JoinVertical(lipgloss.Left, pane, m.renderFocusFooter(), m.renderStatusBar(m.layout.width))
+}
+
+func (m *Model) renderClientsPane(width int) string {
+	if m.LockedTarget == nil || m.clientsOf != m.LockedTarget {
+		return m.renderKismetPane("Clients", []string{"No target locked"}, width)
+	}
+
+	title := fmt.Sprintf("Clients of %s (%d) %s", m.LockedTarget.DisplayValue(), len(m.clients), sparkline(m.clientCounts))
+	if len(m.clients) == 0 {
+		return m.renderKismetPane(title, []string{"No associated clients seen"}, width)
+	}
+	var lines []string
+	for _, client := range m.clients[:min(len(m.clients), m.layout.kismetRows)] {
+		lines = append(lines, fmt.Sprintf("%s  %s", client, m.clientMakes[client]))
+	}
+	return m.renderKismetPane(title, lines, width)
+}
+
+// One-line footer naming the current target and the next view
+func (m *Model) renderFocusFooter() string {
+	status := "Searching..."
+	if m.LockedTarget != nil && m.ChannelLocked {
+		status = fmt.Sprintf("%s • %s • ch %s", m.LockedTarget.DisplayValue(), m.formatRSSI(m.RSSI), tracker.FormatChannel(m.Channel))
+	}
+	next := (m.screenView + 1) % viewCount
+	return m.styles.Help.Render(fmt.Sprintf("%s • [F] %s • [?] help • [q] quit", status, next))
+}
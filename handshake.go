@@ -0,0 +1,29 @@
+package main
+
+// Whether Kismet has captured a WPA handshake for the locked target, so the moment one turns up can be
+// told apart from one that was already there when the target was locked
+type handshakeWatch struct {
+	target   *TargetItem // Target the readings belong to
+	captured bool        // A handshake has been seen in its readings
+}
+
+// Feed a reading of the locked target, returning whether it brought the first handshake since the target
+// was locked. One already in the first reading doesn't count, as it was there before the lock.
+func (w *handshakeWatch) observe(target *TargetItem, captured bool) bool {
+	if target != w.target {
+		w.target, w.captured = target, captured
+		return false
+	}
+	flipped := captured && !w.captured
+	w.captured = w.captured || captured
+	return flipped
+}
+
+// Whether a handshake has been captured for the target
+func (w *handshakeWatch) capturedFor(target *TargetItem) bool {
+	return target != nil && w.target == target && w.captured
+}
+
+func (w *handshakeWatch) reset() {
+	*w = handshakeWatch{}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// Run the tracker without the TUI, sending every event to out until interrupted or limit ends the run.
+// Returns the process exit code: 0 when stopped by the user, 1 if Kismet fails, or whatever limit says.
+func runHeadless(t *hunt, kismet *exec.Cmd, out emitter, limit runLimit) int {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Notice if the Kismet we launched dies underneath us
+	kismetExited := make(chan error, 1)
+	if kismet != nil {
+		go func() { kismetExited <- kismet.Wait() }()
+	}
+
+	uuid, err := t.awaitUUID(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		out.emit(event{Time: time.Now(), Type: eventKismetError, Error: err.Error()})
+		stopKismet(kismet)
+		return 1
+	}
+	t.logKismetStatus()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	usr1 := make(chan os.Signal, 1)
+	notifyDumpSignals(usr1)
+	defer signal.Stop(usr1)
+
+	// SIGHUP and edits to the config file both ask for a reload here, so it happens between polls
+	reload := make(chan struct{}, 1)
+	requestReload := func() {
+		select {
+		case reload <- struct{}{}:
+		default: // A reload is already pending
+		}
+	}
+	stopWatching, err := watchConfigFile(requestReload)
+	if err != nil {
+		slog.Warn("Config changes won't be picked up until SIGHUP", "err", err)
+	} else {
+		defer stopWatching()
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			stopKismet(kismet)
+			return 0
+		case err := <-kismetExited:
+			slog.Error("Kismet exited unexpectedly", "err", err)
+			out.emit(event{Time: time.Now(), Type: eventKismetError, Error: fmt.Sprintf("kismet exited: %v", err)})
+			return 1
+		case <-hup:
+			requestReload()
+		case <-reload:
+			summary, err := t.reloadConfig(nil)
+			if summary != "" {
+				slog.Info(summary)
+			}
+			if err != nil {
+				slog.Error("Error reloading config", "err", err)
+			}
+		case <-usr1:
+			if path, err := t.dumpState(time.Now()); err != nil {
+				slog.Error(err.Error())
+			} else {
+				slog.Info("State dumped", "path", path)
+			}
+		case now := <-ticker.C:
+			result := t.poll(uuid)
+			for _, e := range pollEvents(t, result, now) {
+				out.emit(e)
+			}
+			if done, code, reason := limit.check(result, t.LockedTarget, now); done {
+				slog.Info(reason)
+				stopKismet(kismet)
+				return code
+			}
+		}
+	}
+}
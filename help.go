@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+type helpBinding struct {
+	keys string
+	desc string
+}
+
+type helpGroup struct {
+	title    string
+	bindings []helpBinding
+}
+
+// Every key binding, grouped by context. Add new bindings here so they show up in the help overlay.
+var helpGroups = []helpGroup{
+	{
+		title: "Navigation",
+		bindings: []helpBinding{
+			{"↑/k ↓/j", "Move the selection (or scroll the log when it has focus)"},
+			{"1-9, 0", "Jump to that target (0 is the 10th)"},
+			{"PgUp/PgDn", "Page the target list (or the log when it has focus)"},
+			{"Home/End", "Jump to the first or last target (or the oldest or newest log line)"},
+			{"Tab", "Switch focus between the target list and the log"},
+			{"F", "Cycle full-screen views: chart, targets, clients, grid"},
+			{"d", "Show the RSSI in dBm, percent or signal bars"},
+			{"%", "Show or hide the percentage after the signal bar"},
+			{"T", "Cycle the color theme"},
+			{"o", "Sort targets by signal, name or last seen"},
+			{"Mouse wheel", "Scroll the pane under the pointer"},
+			{"Click / double-click", "Select a target / search for it"},
+		},
+	},
+	{
+		title: "Target control",
+		bindings: []helpBinding{
+			{"Enter", "Search for the selected target"},
+			{"i", "Ignore the current target and resume searching"},
+			{"I", "Ignore every target except the selected one"},
+			{"U", "Remove every target from the ignore list"},
+			{"u", "Undo the last change to the ignore list"},
+			{"t", "Cycle the target list through each tag group"},
+			{"m", "Watch every visible target's RSSI at once, without locking (m again to lock)"},
+			{"c", "Cycle mode: lock each target in turn for cycle_interval_seconds (Enter pauses it)"},
+			{"x", "Export the GPS track, and the WiGLE CSV with --export-wigle"},
+			{"y", "Copy the locked target's MAC (or SSID) to the clipboard"},
+		},
+	},
+	{
+		title: "Message log",
+		bindings: []helpBinding{
+			{"L", "Open the full-screen message log"},
+			{"/", "Search the log (Enter to apply)"},
+			{"n/N", "Next/previous search match"},
+			{"w", "Show only warnings and errors (also in the main view)"},
+			{"g/G", "Jump to the oldest/newest entry"},
+			{"Esc/L", "Close the message log"},
+		},
+	},
+	{
+		title: "General",
+		bindings: []helpBinding{
+			{"?", "Toggle this help"},
+			{"Esc", "Close this help"},
+			{"q/Ctrl+C", "Quit"},
+		},
+	},
+}
+
+// Render the full-screen help overlay listing every key binding
+func (m *Model) renderHelpOverlay() string {
+	keyWidth := 0
+	for _, group := range helpGroups {
+		for _, b := range group.bindings {
+			keyWidth = max(keyWidth, lipgloss.Width(b.keys))
+		}
+	}
+
+	keyStyle := m.styles.Header.Width(keyWidth + 2)
+
+	var sections []string
+	for _, group := range helpGroups {
+		var rows []string
+		rows = append(rows, m.styles.Header.Underline(true).Render(group.title))
+		for _, b := range group.bindings {
+			rows = append(rows, keyStyle.Render(b.keys)+b.desc)
+		}
+		sections = append(sections, strings.Join(rows, "\n"))
+	}
+
+	body := m.styles.Header.Render("Key bindings") + "\n\n" +
+		strings.Join(sections, "\n\n") + "\n\n" +
+		m.styles.Help.Render("Press ? or Esc to close")
+
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center, m.styles.Pane.Render(body))
+}
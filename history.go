@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Entry point for "rizzyscope history": print a summary of every sighting of a MAC from the sightings
+// database, without launching the TUI or Kismet. Returns the process exit code.
+func runHistory(args []string) int {
+	flags := historyFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	target, _ := flags.GetString("target")
+	configPath, _ := flags.GetString("config")
+	configType, _ := flags.GetString("config-type")
+	profile, _ := flags.GetString("profile")
+	dbPath, _ := flags.GetString("db")
+
+	if target == "" {
+		fmt.Fprintln(os.Stderr, "Usage: rizzyscope history --target <mac> [--db path | --config path]")
+		return 2
+	}
+	mac, err := formatMAC(target)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if dbPath == "" {
+		if err := setupConfig(configPath, configType); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		activeProfile = profile
+		if err := readConfig(); err != nil {
+			fmt.Fprintln(os.Stderr, "Error reading config file:", err)
+			return 1
+		}
+		dbPath = viper.GetString("optional.db_path")
+	}
+	if dbPath == "" {
+		fmt.Fprintln(os.Stderr, "No database configured; set optional.db_path or pass --db")
+		return 1
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		fmt.Fprintln(os.Stderr, "Error opening database:", err)
+		return 1
+	}
+
+	store, err := openSQLiteStore(dbPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer store.Close()
+
+	summary, err := store.summary(mac)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error querying sightings:", err)
+		return 1
+	}
+
+	fmt.Println(formatSightingSummary(mac, summary))
+	return 0
+}
+
+// The flags "rizzyscope history" takes, also listed by the completion scripts
+func historyFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("history", pflag.ContinueOnError)
+	flags.StringP("target", "t", "", "MAC address of the target")
+	flags.StringP("config", "c", "", "Path to config file (.toml, .yaml, .yml or .json)")
+	flags.String("config-type", "", "Format of the config file if its extension doesn't say: toml, yaml or json")
+	flags.String("profile", "", "Use the [profiles.<name>] section of the config (default: default_profile)")
+	flags.String("db", "", "Path to the sightings database (default: optional.db_path from the config)")
+	return flags
+}
+
+func formatSightingSummary(mac string, s sightingSummary) string {
+	if s.count == 0 {
+		return fmt.Sprintf("%s: never seen", mac)
+	}
+	return fmt.Sprintf("%s\n  first seen: %s\n  last seen:  %s\n  peak RSSI:  %d dBm\n  sightings:  %d",
+		mac, s.firstSeen.Format(time.RFC3339), s.lastSeen.Format(time.RFC3339), s.peakRSSI, s.count)
+}
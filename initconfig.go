@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+const defaultConfigPath = "config.toml"
+
+// Template written by --init. Keep it in step with every key the program reads.
+const defaultConfig = `# rizzyscope configuration
+# Any key can also be set with a RIZZYSCOPE_<SECTION>_<KEY> environment variable, e.g. RIZZYSCOPE_CREDENTIALS_PASSWORD.
+
+# Profile used when --profile isn't given (see the end of the file)
+# default_profile = "home"
+
+[required]
+# MAC addresses to track, in any common format (AA:BB:CC:DD:EE:FF, aabbccddeeff, ...)
+target_mac = []
+# Wireless interfaces Kismet captures on; the first one is used for channel locking
+interface = ["wlan0"]
+
+[optional]
+# SSIDs to track
+target_ssid = []
+# Names shown in place of a MAC or SSID, as "target=label"
+target_labels = []
+# Groups for filtering the target list with t, as "target=tag1,tag2"
+target_tags = []
+# Per-target RSSI for webhook rssi_above alerts, as "target=-40"
+target_alert_rssi = []
+# Kismet server address
+kismet_endpoint = "127.0.0.1:2501"
+# Append every real-time message (timestamped) to this file; empty to disable
+event_log = ""
+# Directory SIGUSR1 state dumps are written to; empty for the working directory
+dump_dir = ""
+# Number of real-time output lines shown
+realtime_lines = 7
+# Number of real-time messages kept for scrollback and the message log
+realtime_history = 1000
+# Number of temporary messages shown
+temp_message_count = 3
+# How long temporary messages stay on screen, in seconds
+temp_message_seconds = 3
+# RSSI readout: dbm, percent or bars (cycle with d)
+rssi_display = "dbm"
+# Show the percentage after the signal quality bar (toggle with %)
+show_percentage = false
+# Require pressing q/Ctrl+C twice to quit
+confirm_quit = false
+# Ring the terminal bell when a target is found and its channel locked, and when it comes within reach
+bell_on_found = false
+# Also send a desktop notification with notify-send
+desktop_notify = false
+# Watch Kismet's alerts for deauthentication attacks on the locked target and flag them prominently
+deauth_watch = false
+# Alert once when the locked target's smoothed RSSI rises to this many dBm (e.g. -50, within arm's reach); 0 disables
+proximity_threshold_dbm = 0
+# Pairs of targets (MAC, SSID or label) to alert on when both are in range at once, e.g. ["Phone=Laptop"]
+colocation_pairs = []
+# RSSI both targets of a pair have to reach
+colocation_rssi_dbm = -70
+# Seconds within which both have to have been heard
+colocation_window_seconds = 10
+# Seconds before the same target can notify again
+notify_cooldown_seconds = 60
+# Seconds a locked target can go unheard before the channel is unlocked and the search resumes; 0 stays locked
+lock_dwell_seconds = 0
+# Minutes a target can go unseen before it's marked [STALE] and skipped by discovery until it shows up again; 0 never
+stale_after_minutes = 0
+# Most targets shown at once in the multi-target view (m)
+multi_target_count = 5
+# Seconds the multi-target view dwells on each target's channel in turn; 0 just hops channels
+rotate_dwell_seconds = 5
+# Seconds cycle mode (c) keeps each target locked before moving on to the next
+cycle_interval_seconds = 30
+# Mouse wheel scrolling and click/double-click target selection
+mouse = true
+# Where the last view, theme, sort order and ignored targets are remembered between sessions;
+# defaults to ~/.config/rizzyscope/state.json, set to "" to disable
+# state_file = ""
+# Rotate the --record file once it reaches this many MB; 0 never rotates
+record_max_mb = 0
+# SQLite database that keeps every sighting across sessions; empty to disable
+db_path = ""
+# Serve Prometheus metrics on this address (e.g. ":9205"); empty to disable
+metrics_addr = ""
+# Serve a read-only JSON API of the current state on this address (e.g. ":9206", bound to localhost
+# unless a host is given); empty to disable
+api_addr = ""
+# Write every device seen to this file as a WiGLE CSV on exit, like --export-wigle; empty to disable
+wigle_csv = ""
+# Webhook that receives alerts (Slack-compatible JSON); empty to disable
+webhook_url = ""
+# Alerts to send: target_found, target_lost, rssi_above, proximity, colocation
+webhook_events = ["target_found", "target_lost", "rssi_above", "proximity", "colocation"]
+# RSSI at which rssi_above fires for targets without their own threshold
+webhook_rssi_threshold = -50
+# Minimum seconds between two alerts of the same kind for one target
+webhook_min_interval_seconds = 60
+# Write channel lock/unlock and alert events to the local syslog daemon
+syslog = false
+# Send those events as RFC 5424 to a remote syslog server instead (udp://host:514 or tcp://host:514); empty to disable
+syslog_addr = ""
+
+[credentials]
+# Kismet login; consider RIZZYSCOPE_CREDENTIALS_USER/PASSWORD instead of storing them here
+user = ""
+password = ""
+# Secrets file holding user/password as JSON or key=value lines; fills in whichever is not set above
+file = ""
+
+[mqtt]
+# Broker for live tracking state (tcp://host:1883, or ssl://host:8883 for TLS); empty to disable
+broker = ""
+# Topics are <prefix>/state, <prefix>/target/<mac>/rssi and <prefix>/status
+topic_prefix = "rizzyscope"
+# Defaults to rizzyscope-<hostname>
+client_id = ""
+username = ""
+password = ""
+# CA certificate to verify the broker, and a client certificate and key for mutual TLS
+tls_ca = ""
+tls_cert = ""
+tls_key = ""
+# Skip verifying the broker's certificate
+tls_insecure = false
+
+[theme]
+# Built-in preset: "dark", "light", "dracula" or "solarized"
+name = "dark"
+# Override any role with an ANSI color number ("63") or a hex color ("#bd93f9"); empty keeps the preset
+border = ""
+accent = ""
+good = ""
+warn = ""
+bad = ""
+muted = ""
+# RSSI bar gradient from weak to strong; empty uses bad and good
+gradient_from = ""
+gradient_to = ""
+
+# Profiles for different engagements, picked with --profile <name> (--profile ? lists them). Settings in
+# [defaults] apply to every profile, and a profile's own settings replace them; lists are replaced, not
+# combined. Both are laid over the sections above.
+# [defaults.credentials]
+# user = "kismet"
+#
+# [profiles.home.required]
+# target_mac = ["AA:BB:CC:DD:EE:FF"]
+#
+# [profiles.client_a.required]
+# target_mac = ["10:22:33:44:55:66"]
+# interface = ["wlan1"]
+`
+
+// Write the config template to path, refusing to replace an existing file unless force is set
+func writeDefaultConfig(path string, force bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+
+	file, err := os.OpenFile(path, flags, 0600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return fmt.Errorf("error creating %s: %v", path, err)
+	}
+
+	if _, err := file.WriteString(defaultConfig); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing %s: %v", path, err)
+	}
+	return file.Close()
+}
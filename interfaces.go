@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+// A wireless network interface as shown by list-interfaces. The JSON field names are part of the --json
+// output; keep them stable.
+type wirelessInterface struct {
+	Name    string     `json:"name"`
+	Phy     string     `json:"phy"`
+	Driver  string     `json:"driver"`
+	Monitor bool       `json:"monitor"`
+	Bands   []wifiBand `json:"bands"`
+	Error   string     `json:"error,omitempty"` // Why the capabilities couldn't be read, e.g. iw is missing
+}
+
+// Entry point for "rizzyscope list-interfaces": print every wireless interface with its phy, driver, bands
+// and monitor mode support. Needs neither root nor Kismet. Returns the process exit code.
+func runListInterfaces(args []string) int {
+	flags := listInterfacesFlagSet()
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	asJSON, _ := flags.GetBool("json")
+
+	ifaces, err := listWirelessInterfaces()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	if asJSON {
+		err = writeInterfacesJSON(os.Stdout, ifaces)
+	} else {
+		err = writeInterfacesTable(os.Stdout, ifaces)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// The flags "rizzyscope list-interfaces" takes, also listed by the completion scripts
+func listInterfacesFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("list-interfaces", pflag.ContinueOnError)
+	flags.Bool("json", false, "Print JSON instead of a table")
+	return flags
+}
+
+// Every interface with a wireless phy, sorted by name
+func listWirelessInterfaces() ([]wirelessInterface, error) {
+	entries, err := os.ReadDir("/sys/class/net")
+	if err != nil {
+		return nil, fmt.Errorf("error listing network interfaces: %v", err)
+	}
+
+	ifaces := []wirelessInterface{}
+	for _, entry := range entries { // ReadDir sorts by name
+		phy, err := interfacePhy(entry.Name())
+		if err != nil {
+			continue
+		}
+
+		iface := wirelessInterface{Name: entry.Name(), Phy: phy, Bands: []wifiBand{}}
+		if driver, err := os.Readlink(filepath.Join("/sys/class/net", entry.Name(), "device", "driver")); err == nil {
+			iface.Driver = filepath.Base(driver)
+		}
+
+		caps, err := readPhyCapabilities(phy)
+		if err != nil {
+			iface.Error = err.Error()
+		} else {
+			iface.Monitor = slices.Contains(caps.modes, "monitor")
+			if caps.bands != nil {
+				iface.Bands = caps.bands
+			}
+		}
+		ifaces = append(ifaces, iface)
+	}
+	return ifaces, nil
+}
+
+func writeInterfacesJSON(out io.Writer, ifaces []wirelessInterface) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ifaces)
+}
+
+func writeInterfacesTable(out io.Writer, ifaces []wirelessInterface) error {
+	if len(ifaces) == 0 {
+		_, err := fmt.Fprintln(out, "No wireless interfaces found")
+		return err
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "INTERFACE\tPHY\tDRIVER\tMONITOR\tBANDS (CHANNELS)")
+	for _, iface := range ifaces {
+		monitor := "no"
+		if iface.Monitor {
+			monitor = "yes"
+		}
+		if iface.Error != "" {
+			monitor = "?"
+		}
+
+		var bands []string
+		for _, band := range iface.Bands {
+			bands = append(bands, fmt.Sprintf("%s (%d)", band.Band, len(band.Channels)))
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", iface.Name, iface.Phy, valueOr(iface.Driver, "-"), monitor, valueOr(strings.Join(bands, ", "), "-"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, iface := range ifaces {
+		if iface.Error != "" {
+			if iface.Error == errIwNotFound.Error() {
+				_, err := fmt.Fprintln(out, "\nInstall iw to see monitor mode and band support.")
+				return err
+			}
+			fmt.Fprintf(out, "%s: %s\n", iface.Name, iface.Error)
+		}
+	}
+	return nil
+}
+
+func valueOr(s, fallback string) string {
+	if s == "" {
+		return fallback
+	}
+	return s
+}
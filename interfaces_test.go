@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// list-interfaces --json is read by scripts, so its shape is checked key by key: every field is always
+// there with the right JSON type, apart from error, which only appears when it's set
+func TestInterfacesJSONSchema(t *testing.T) {
+	ifaces := []wirelessInterface{
+		{Name: "wlan0", Phy: "phy0", Driver: "mt7921u", Monitor: true, Bands: []wifiBand{
+			{Band: "2.4GHz", Channels: []int{1, 6, 11}},
+			{Band: "5GHz", Channels: []int{36, 40}},
+		}},
+		{Name: "wlan1", Phy: "phy1", Bands: []wifiBand{}, Error: "iw not found"},
+	}
+	var buf bytes.Buffer
+	if err := writeInterfacesJSON(&buf, ifaces); err != nil {
+		t.Fatal(err)
+	}
+
+	var listing []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &listing); err != nil {
+		t.Fatalf("output isn't a JSON array of objects: %v\n%s", err, buf.String())
+	}
+	if len(listing) != len(ifaces) {
+		t.Fatalf("got %d interfaces, want %d", len(listing), len(ifaces))
+	}
+	for i, iface := range listing {
+		want := []string{"bands", "driver", "monitor", "name", "phy"}
+		if ifaces[i].Error != "" {
+			want = append(want, "error")
+			slices.Sort(want)
+		}
+		var keys []string
+		for key := range iface {
+			keys = append(keys, key)
+		}
+		slices.Sort(keys)
+		if !slices.Equal(keys, want) {
+			t.Errorf("interface %d has keys %v, want %v", i, keys, want)
+		}
+		for _, key := range []string{"name", "phy", "driver", "error"} {
+			if v, ok := iface[key]; ok {
+				if _, isString := v.(string); !isString {
+					t.Errorf("interface %d: %s = %#v, want a string", i, key, v)
+				}
+			}
+		}
+		if _, ok := iface["monitor"].(bool); !ok {
+			t.Errorf("interface %d: monitor = %#v, want a bool", i, iface["monitor"])
+		}
+		bands, ok := iface["bands"].([]any)
+		if !ok {
+			t.Fatalf("interface %d: bands = %#v, want an array", i, iface["bands"])
+		}
+		for _, b := range bands {
+			band, ok := b.(map[string]any)
+			if !ok || len(band) != 2 {
+				t.Fatalf("interface %d: band = %#v, want an object with band and channels", i, b)
+			}
+			if _, ok := band["band"].(string); !ok {
+				t.Errorf("interface %d: band name = %#v, want a string", i, band["band"])
+			}
+			channels, ok := band["channels"].([]any)
+			if !ok || len(channels) == 0 {
+				t.Fatalf("interface %d: channels = %#v, want a non-empty array", i, band["channels"])
+			}
+			for _, c := range channels {
+				if n, ok := c.(float64); !ok || n != float64(int(n)) {
+					t.Errorf("interface %d: channel = %#v, want an integer", i, c)
+				}
+			}
+		}
+	}
+
+	// No interfaces is an empty array, not null
+	buf.Reset()
+	if err := writeInterfacesJSON(&buf, []wirelessInterface{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != "[]\n" {
+		t.Errorf("empty listing = %q, want []", got)
+	}
+}
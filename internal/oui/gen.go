@@ -0,0 +1,112 @@
+//go:build ignore
+
+// Regenerate oui.txt.gz from the IEEE registry: go generate ./internal/oui. The CSVs are downloaded from
+// the IEEE unless local copies are passed as arguments.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// The MA-L, MA-M and MA-S registries
+var registries = []string{
+	"https://standards-oui.ieee.org/oui/oui.csv",
+	"https://standards-oui.ieee.org/oui28/mam.csv",
+	"https://standards-oui.ieee.org/oui36/oui36.csv",
+}
+
+func main() {
+	sources := registries
+	if len(os.Args) > 1 {
+		sources = os.Args[1:]
+	}
+
+	var lines []string
+	counts := make(map[string]int) // Entries from each registry, for the header
+	for _, source := range sources {
+		entries, err := readRegistry(source, counts)
+		if err != nil {
+			log.Fatalf("%s: %v", source, err)
+		}
+		lines = append(lines, entries...)
+	}
+	slices.Sort(lines)
+	lines = slices.Compact(lines)
+
+	out, err := os.Create("oui.txt.gz")
+	if err != nil {
+		log.Fatal(err)
+	}
+	zw, _ := gzip.NewWriterLevel(out, gzip.BestCompression)
+	// The counts show at a glance whether a table holds the whole registry or only part of it
+	fmt.Fprintf(zw, "# Generated by gen.go from the IEEE registries (%d MA-L, %d MA-M and %d MA-S entries); do not edit\n",
+		counts["MA-L"], counts["MA-M"], counts["MA-S"])
+	for _, line := range lines {
+		fmt.Fprintln(zw, line)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Wrote %d entries to oui.txt.gz", len(lines))
+}
+
+// The "PREFIX\tVendor" lines for a registry CSV at a URL or path, adding the number kept from each
+// registry to counts
+func readRegistry(source string, counts map[string]int) ([]string, error) {
+	var r io.Reader
+	if strings.HasPrefix(source, "https://") {
+		// The IEEE turns away requests without a browser-like user agent
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", "Mozilla/5.0 (rizzyscope oui generator)")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("status %d", resp.StatusCode)
+		}
+		r = resp.Body
+	} else {
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	// Registry,Assignment,Organization Name,Organization Address
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, record := range records[1:] {
+		if len(record) < 3 {
+			continue
+		}
+		prefix := strings.ToUpper(strings.TrimSpace(record[1]))
+		vendor := strings.Join(strings.Fields(record[2]), " ")
+		if vendor == "" || vendor == "Private" || vendor == "IEEE Registration Authority" {
+			continue // Withheld, or a block split into MA-M and MA-S assignments listed on their own
+		}
+		lines = append(lines, prefix+"\t"+vendor)
+		counts[strings.TrimSpace(record[0])]++
+	}
+	return lines, nil
+}
@@ -0,0 +1,174 @@
+// Package oui looks up the vendor a MAC address was assigned to in a copy of the IEEE registry bundled
+// with the binary, for devices Kismet couldn't name.
+package oui
+
+//go:generate go run gen.go
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// The registry as gzipped lines of "PREFIX\tVendor", sorted by prefix. The prefix is 6, 7 or 9 hex digits
+// for the IEEE's MA-L, MA-M and MA-S blocks (24, 28 and 36 bits).
+//
+//go:embed oui.txt.gz
+var registry []byte
+
+// A block of MAC addresses assigned to a vendor
+type entry struct {
+	prefix uint64 // The first bits of the address, left-aligned in 48 bits
+	bits   int
+	vendor string
+}
+
+// Entries sorted by prefix and then by length, searched with a binary search per block size
+type Table struct {
+	entries []entry
+	sizes   []int // Block sizes in the table, longest first, so the most specific block wins
+}
+
+var (
+	loadOnce sync.Once
+	bundled  *Table
+)
+
+// The bundled registry, decompressed the first time it's needed
+func Bundled() *Table {
+	loadOnce.Do(func() {
+		r, err := gzip.NewReader(bytes.NewReader(registry))
+		if err == nil {
+			bundled, err = Parse(r)
+		}
+		if err != nil {
+			slog.Error("Error loading the bundled OUI table", "err", err)
+			bundled = &Table{}
+		}
+	})
+	return bundled
+}
+
+// Read a table of "PREFIX\tVendor" lines. Blank lines and lines starting with # are skipped.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{}
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		hex, vendor, ok := strings.Cut(text, "\t")
+		if !ok || strings.TrimSpace(vendor) == "" {
+			return nil, fmt.Errorf("line %d: expected a prefix and a vendor separated by a tab", line)
+		}
+		e, err := parsePrefix(hex)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		e.vendor = strings.TrimSpace(vendor)
+		t.entries = append(t.entries, e)
+		if !slices.Contains(t.sizes, e.bits) {
+			t.sizes = append(t.sizes, e.bits)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(t.entries, compareEntries)
+	slices.Sort(t.sizes)
+	slices.Reverse(t.sizes)
+	return t, nil
+}
+
+func parsePrefix(hex string) (entry, error) {
+	hex = strings.TrimSpace(hex)
+	if len(hex) != 6 && len(hex) != 7 && len(hex) != 9 {
+		return entry{}, fmt.Errorf("prefix %q is not 6, 7 or 9 hex digits", hex)
+	}
+	value, err := strconv.ParseUint(hex, 16, 64)
+	if err != nil {
+		return entry{}, fmt.Errorf("prefix %q is not hex", hex)
+	}
+	bits := len(hex) * 4
+	return entry{prefix: value << (48 - bits), bits: bits}, nil
+}
+
+func compareEntries(a, b entry) int {
+	if a.prefix != b.prefix {
+		if a.prefix < b.prefix {
+			return -1
+		}
+		return 1
+	}
+	return a.bits - b.bits
+}
+
+// The number of vendor blocks in the table
+func (t *Table) Len() int {
+	return len(t.entries)
+}
+
+// The vendor of the block the MAC falls in, trying the smallest blocks first. The MAC may be written with
+// colons, dashes or no separators.
+func (t *Table) Lookup(mac string) (string, bool) {
+	addr, ok := parseMAC(mac)
+	if !ok {
+		return "", false
+	}
+	for _, bits := range t.sizes {
+		want := entry{prefix: addr &^ (1<<(48-bits) - 1), bits: bits}
+		if i, found := slices.BinarySearchFunc(t.entries, want, compareEntries); found {
+			return t.entries[i].vendor, true
+		}
+	}
+	return "", false
+}
+
+// Look the MAC up in the bundled registry
+func Lookup(mac string) (string, bool) {
+	return Bundled().Lookup(mac)
+}
+
+// Whether the MAC is locally administered rather than assigned by the IEEE, as the randomized addresses
+// phones use while scanning are. Such an address has no vendor to look up.
+func Randomized(mac string) bool {
+	addr, ok := parseMAC(mac)
+	return ok && addr&(0x02<<40) != 0
+}
+
+// The 48-bit value of a MAC written with colons, dashes, dots or no separators
+func parseMAC(mac string) (uint64, bool) {
+	var value uint64
+	digits := 0
+	for i := 0; i < len(mac); i++ {
+		c := mac[i]
+		var nibble byte
+		switch {
+		case c >= '0' && c <= '9':
+			nibble = c - '0'
+		case c >= 'a' && c <= 'f':
+			nibble = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			nibble = c - 'A' + 10
+		case c == ':' || c == '-' || c == '.':
+			continue
+		default:
+			return 0, false
+		}
+		value = value<<4 | uint64(nibble)
+		digits++
+	}
+	return value, digits == 12
+}
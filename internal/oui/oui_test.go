@@ -0,0 +1,124 @@
+package oui
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const testTable = `# A comment
+001122	Big Vendor
+0011223	Medium Vendor
+00112233A	Small Vendor
+
+B827EB	Raspberry Pi Foundation
+`
+
+func TestLookup(t *testing.T) {
+	table, err := Parse(strings.NewReader(testTable))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", table.Len())
+	}
+
+	// The smallest block the address falls in wins
+	for mac, want := range map[string]string{
+		"00:11:22:00:00:01": "Big Vendor",
+		"00:11:22:40:00:01": "Big Vendor",
+		"00:11:22:30:00:01": "Medium Vendor",
+		"00:11:22:33:A0:01": "Small Vendor",
+		"00-11-22-33-AF-FF": "Small Vendor",
+		"0011223B0001":      "Medium Vendor",
+		"b8:27:eb:12:34:56": "Raspberry Pi Foundation",
+	} {
+		if vendor, ok := table.Lookup(mac); !ok || vendor != want {
+			t.Errorf("Lookup(%q) = %q, %v; want %q", mac, vendor, ok, want)
+		}
+	}
+	for _, mac := range []string{"00:11:23:00:00:01", "FF:FF:FF:FF:FF:FF", "not a mac", "00:11:22"} {
+		if vendor, ok := table.Lookup(mac); ok {
+			t.Errorf("Lookup(%q) = %q, want no vendor", mac, vendor)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, table := range []string{"001122", "001122\t", "0011\tShort", "00112G\tNot hex", "0011223344\tToo long"} {
+		if _, err := Parse(strings.NewReader(table)); err == nil {
+			t.Errorf("Parse(%q) succeeded", table)
+		}
+	}
+}
+
+func TestBundled(t *testing.T) {
+	if Bundled().Len() == 0 {
+		t.Fatal("the bundled table is empty")
+	}
+	if vendor, ok := Lookup("B8:27:EB:00:00:01"); !ok || vendor != "Raspberry Pi Foundation" {
+		t.Errorf("Lookup = %q, %v; want Raspberry Pi Foundation", vendor, ok)
+	}
+}
+
+func TestRandomized(t *testing.T) {
+	for mac, want := range map[string]bool{
+		"32:34:00:00:00:01": true,
+		"DA:A1:19:00:00:01": true,
+		"B8:27:EB:00:00:01": false,
+		"00:11:22:33:44:55": false,
+		"bogus":             false,
+	} {
+		if got := Randomized(mac); got != want {
+			t.Errorf("Randomized(%q) = %v, want %v", mac, got, want)
+		}
+	}
+}
+
+// A table the size of the full IEEE registry, with MA-M and MA-S blocks in it
+func largeTable(b *testing.B) *Table {
+	var sb strings.Builder
+	for i := range 30000 {
+		fmt.Fprintf(&sb, "%06X\tVendor %d\n", i*0x1F3, i)
+	}
+	for i := range 5000 {
+		fmt.Fprintf(&sb, "%07X\tMedium %d\n", i*0x2F3F, i)
+		fmt.Fprintf(&sb, "%09X\tSmall %d\n", i*0x2F3F3F, i)
+	}
+	table, err := Parse(strings.NewReader(sb.String()))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return table
+}
+
+func BenchmarkLookup(b *testing.B) {
+	table := largeTable(b)
+	macs := []string{"00:01:F3:00:00:01", "12:34:56:78:9A:BC", "DC:A6:32:11:22:33", "FF:FF:FF:FF:FF:FF"}
+	b.ResetTimer()
+	for i := range b.N {
+		table.Lookup(macs[i%len(macs)])
+	}
+}
+
+func BenchmarkLookupBundled(b *testing.B) {
+	Bundled()
+	b.ResetTimer()
+	for range b.N {
+		Lookup("B8:27:EB:00:00:01")
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	var sb strings.Builder
+	for i := range 30000 {
+		fmt.Fprintf(&sb, "%06X\tVendor %d\n", i*0x1F3, i)
+	}
+	text := sb.String()
+	b.ResetTimer()
+	for range b.N {
+		if _, err := Parse(strings.NewReader(text)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+// Package testkismet is a fake Kismet REST API for tests. It serves the endpoints rizzyscope uses from a
+// device inventory the test sets up and can change between polls, checks credentials the way Kismet
+// does, and records every request so tests can check what was sent.
+package testkismet
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A device in the fake Kismet's inventory
+type Device struct {
+	MAC     string
+	SSID    string // Advertised SSID, empty for clients
+	Channel string
+	RSSI    int
+	Manuf   string
+	Crypt   string
+	Type    string   // e.g. "Wi-Fi AP" or "Wi-Fi Client"
+	Clients []string // MACs of associated clients
+
+	// Left out of the record when zero, as Kismet does for devices without them
+	Packets     int
+	DataPackets int
+	WPSState    int    // Bits of dot11.advertisedssid.wps_state
+	BeaconRate  int    // Beacons per second
+	HTMode      string // e.g. "VHT80"
+	Handshake   int    // Bits of dot11.device.wpa_present_handshake
+}
+
+// A datasource the fake Kismet reports
+type Source struct {
+	Interface string
+	UUID      string
+	Channels  []string // Channels it can tune to, as Kismet lists them
+}
+
+// A request the fake Kismet received, with the credentials taken out of the query
+type Request struct {
+	Method   string
+	Path     string
+	User     string
+	Password string
+	Body     []byte
+}
+
+// A fake Kismet server. Only devices on a source's locked channel are listed while it is locked, as
+// with a real radio.
+type Server struct {
+	*httptest.Server
+
+	user     string
+	password string
+
+	mu       sync.Mutex
+	devices  []Device
+	sources  []Source
+	locked   map[string]string // Channel each source UUID is locked to, absent while hopping
+	requests []Request
+}
+
+// Start a fake Kismet accepting the given credentials. Close it when done.
+func New(user, password string) *Server {
+	s := &Server{user: user, password: password, locked: map[string]string{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /system/timestamp.json", s.timestamp)
+	mux.HandleFunc("GET /system/status.json", s.status)
+	mux.HandleFunc("/devices/last-time/{time}/devices.json", s.lastTime)
+	mux.HandleFunc("/devices/by-mac/{mac}/devices.json", s.byMAC)
+	mux.HandleFunc("GET /datasource/all_sources.json", s.allSources)
+	mux.HandleFunc("POST /datasource/by-uuid/{uuid}/set_channel.cmd", s.setChannel)
+	mux.HandleFunc("POST /datasource/by-uuid/{uuid}/set_hop.cmd", s.setHop)
+
+	s.Server = httptest.NewServer(s.record(mux))
+	return s
+}
+
+// The host:port to pass as rizzyscope's Kismet endpoint
+func (s *Server) Endpoint() string {
+	return s.Listener.Addr().String()
+}
+
+// Replace the device inventory
+func (s *Server) SetDevices(devices ...Device) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.devices = slices.Clone(devices)
+}
+
+// Add a datasource that can tune to channels, hopping until a channel is set
+func (s *Server) AddSource(iface, uuid string, channels ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources = append(s.sources, Source{Interface: iface, UUID: uuid, Channels: channels})
+}
+
+// The channel the source is locked to, or "" while it is hopping
+func (s *Server) Channel(uuid string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locked[uuid]
+}
+
+// Every request received so far, oldest first
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.requests)
+}
+
+// The requests received so far for paths ending in suffix
+func (s *Server) RequestsTo(suffix string) []Request {
+	var matched []Request
+	for _, req := range s.Requests() {
+		if strings.HasSuffix(req.Path, suffix) {
+			matched = append(matched, req)
+		}
+	}
+	return matched
+}
+
+// Record the request and reject it with a 401 unless it carries the right credentials, in the query
+// or as basic auth. Like Kismet, the timestamp needs no login.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(strings.NewReader(string(body)))
+
+		user, password := r.URL.Query().Get("user"), r.URL.Query().Get("password")
+		if basicUser, basicPassword, ok := r.BasicAuth(); ok {
+			user, password = basicUser, basicPassword
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Method: r.Method, Path: r.URL.Path, User: user, Password: password, Body: body})
+		s.mu.Unlock()
+
+		if r.URL.Path != "/system/timestamp.json" && (user != s.user || password != s.password) {
+			http.Error(w, "Login required", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) timestamp(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	writeJSON(w, map[string]any{"kismet.system.timestamp.sec": now.Unix(), "kismet.system.timestamp.usec": now.Nanosecond() / 1000})
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, map[string]any{
+		"kismet.system.version":       "testkismet",
+		"kismet.system.git":           "0123456789abcdef",
+		"kismet.system.devices.count": len(s.devices),
+		"kismet.system.timestamp.sec": time.Now().Unix(),
+	})
+}
+
+// Devices heard recently, simplified to the requested fields if the body (or the json form value) has any
+func (s *Server) lastTime(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	fields, err := requestedFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	devices := []map[string]any{}
+	for _, device := range s.devices {
+		if s.heard(device) {
+			devices = append(devices, simplify(record(device), fields))
+		}
+	}
+	writeJSON(w, devices)
+}
+
+func (s *Server) byMAC(w http.ResponseWriter, r *http.Request) {
+	fields, err := requestedFields(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	devices := []map[string]any{}
+	for _, device := range s.devices {
+		if strings.EqualFold(device.MAC, r.PathValue("mac")) {
+			devices = append(devices, simplify(record(device), fields))
+		}
+	}
+	writeJSON(w, devices)
+}
+
+func (s *Server) allSources(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sources := []map[string]any{}
+	for _, source := range s.sources {
+		channel, locked := s.locked[source.UUID]
+		sources = append(sources, map[string]any{
+			"kismet.datasource.interface": source.Interface,
+			"kismet.datasource.uuid":      source.UUID,
+			"kismet.datasource.running":   true,
+			"kismet.datasource.hopping":   !locked,
+			"kismet.datasource.channel":   channel,
+			"kismet.datasource.channels":  append([]string{}, source.Channels...),
+		})
+	}
+	writeJSON(w, sources)
+}
+
+func (s *Server) setChannel(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Channel string `json:"channel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Channel == "" {
+		http.Error(w, "expected {\"channel\": ...}", http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasSource(r.PathValue("uuid")) {
+		http.Error(w, "no such datasource", http.StatusNotFound)
+		return
+	}
+	s.locked[r.PathValue("uuid")] = payload.Channel
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) setHop(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.hasSource(r.PathValue("uuid")) {
+		http.Error(w, "no such datasource", http.StatusNotFound)
+		return
+	}
+	delete(s.locked, r.PathValue("uuid"))
+	writeJSON(w, map[string]any{})
+}
+
+func (s *Server) hasSource(uuid string) bool {
+	return slices.ContainsFunc(s.sources, func(source Source) bool { return source.UUID == uuid })
+}
+
+// Whether a source is hopping or locked to the device's channel
+func (s *Server) heard(device Device) bool {
+	if len(s.locked) < len(s.sources) || len(s.sources) == 0 {
+		return true
+	}
+	for _, channel := range s.locked {
+		if channel == device.Channel {
+			return true
+		}
+	}
+	return false
+}
+
+// The field list from a JSON body or a json= form value, nil for full records
+func requestedFields(r *http.Request) ([][]string, error) {
+	body, _ := io.ReadAll(r.Body)
+	if values, err := url.ParseQuery(string(body)); err == nil && values.Has("json") {
+		body = []byte(values.Get("json"))
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, nil
+	}
+
+	var payload struct {
+		Fields []json.RawMessage `json:"fields"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("invalid request body: %v", err)
+	}
+
+	// Each field is a path, or a [path, alias] pair
+	var fields [][]string
+	for _, raw := range payload.Fields {
+		var path string
+		if json.Unmarshal(raw, &path) == nil {
+			fields = append(fields, []string{path, path})
+			continue
+		}
+		var pair []string
+		if err := json.Unmarshal(raw, &pair); err != nil || len(pair) != 2 {
+			return nil, fmt.Errorf("invalid field %s", raw)
+		}
+		fields = append(fields, pair)
+	}
+	return fields, nil
+}
+
+// The device as a full Kismet device record
+func record(device Device) map[string]any {
+	clients := map[string]any{}
+	for _, client := range device.Clients {
+		clients[client] = client
+	}
+	ssid := map[string]any{"dot11.advertisedssid.ssid": device.SSID}
+	if device.WPSState != 0 {
+		ssid["dot11.advertisedssid.wps_state"] = float64(device.WPSState)
+	}
+	if device.BeaconRate != 0 {
+		ssid["dot11.advertisedssid.beaconrate"] = float64(device.BeaconRate)
+	}
+	if device.HTMode != "" {
+		ssid["dot11.advertisedssid.ht_mode"] = device.HTMode
+	}
+	r := map[string]any{
+		"kismet.device.base.macaddr":    device.MAC,
+		"kismet.device.base.channel":    device.Channel,
+		"kismet.device.base.manuf":      device.Manuf,
+		"kismet.device.base.crypt":      device.Crypt,
+		"kismet.device.base.type":       device.Type,
+		"kismet.device.base.first_time": float64(time.Now().Unix()),
+		"kismet.device.base.signal": map[string]any{
+			"kismet.common.signal.last_signal": float64(device.RSSI),
+		},
+		"dot11.device": map[string]any{
+			"dot11.device.last_beaconed_ssid_record": ssid,
+			"dot11.device.associated_client_map":     clients,
+		},
+	}
+	if device.Handshake != 0 {
+		r["dot11.device"].(map[string]any)["dot11.device.wpa_present_handshake"] = float64(device.Handshake)
+	}
+	if device.Packets != 0 {
+		r["kismet.device.base.packets.total"] = float64(device.Packets)
+		r["kismet.device.base.packets.data"] = float64(device.DataPackets)
+	}
+	return r
+}
+
+// Like Kismet, keep just the requested fields of a record, renamed to their aliases
+func simplify(record map[string]any, fields [][]string) map[string]any {
+	if fields == nil {
+		return record
+	}
+	simplified := map[string]any{}
+	for _, field := range fields {
+		var value any = record
+		for _, key := range strings.Split(field[0], "/") {
+			m, ok := value.(map[string]any)
+			if !ok {
+				value = nil
+				break
+			}
+			value = m[key]
+		}
+		if value != nil {
+			simplified[field[1]] = value
+		}
+	}
+	return simplified
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
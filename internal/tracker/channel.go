@@ -0,0 +1,155 @@
+package tracker
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kismet names 6 GHz channels with this suffix, e.g. 37W6e, since their numbers overlap 2.4 and 5 GHz
+const sixGHzSuffix = "W6e"
+
+// A Wi-Fi channel and the centre frequency of its 20 MHz channel
+type WifiChannel struct {
+	Band   string // 2.4GHz, 5GHz or 6GHz
+	Number int
+	MHz    int
+	PSC    bool // A 6 GHz preferred scanning channel, where 6 GHz access points are found
+	DFS    bool // A 5 GHz channel shared with radar, which an AP must leave when it detects one
+}
+
+// The channel as Kismet names it
+func (c WifiChannel) String() string {
+	if c.Band == "6GHz" {
+		return strconv.Itoa(c.Number) + sixGHzSuffix
+	}
+	return strconv.Itoa(c.Number)
+}
+
+// Every 20 MHz channel on 2.4, 5 and 6 GHz
+var wifiChannels = buildChannelTable()
+
+func buildChannelTable() []WifiChannel {
+	var table []WifiChannel
+	for n := 1; n <= 13; n++ {
+		table = append(table, WifiChannel{Band: "2.4GHz", Number: n, MHz: 2407 + 5*n})
+	}
+	table = append(table, WifiChannel{Band: "2.4GHz", Number: 14, MHz: 2484})
+
+	for _, span := range [][2]int{{32, 68}, {96, 144}, {149, 177}} {
+		for n := span[0]; n <= span[1]; n += 4 {
+			table = append(table, WifiChannel{Band: "5GHz", Number: n, MHz: 5000 + 5*n, DFS: n >= 52 && n <= 144})
+		}
+	}
+
+	table = append(table, WifiChannel{Band: "6GHz", Number: 2, MHz: 5935})
+	for n := 1; n <= 233; n += 4 {
+		table = append(table, WifiChannel{Band: "6GHz", Number: n, MHz: 5950 + 5*n, PSC: (n-5)%16 == 0})
+	}
+	return table
+}
+
+// The channel centred on a frequency in MHz
+func ChannelForFrequency(mhz int) (WifiChannel, bool) {
+	for _, c := range wifiChannels {
+		if c.MHz == mhz {
+			return c, true
+		}
+	}
+	return WifiChannel{}, false
+}
+
+// Look up a channel as Kismet names it: a number, optionally with a width suffix like HT40+ or VHT80,
+// a 6 GHz channel like 37W6e, or a frequency in MHz
+func LookupChannel(channel string) (WifiChannel, bool) {
+	channel = strings.TrimSpace(channel)
+	digits := len(channel) - len(strings.TrimLeft(channel, "0123456789"))
+	number, err := strconv.Atoi(channel[:digits])
+	if err != nil {
+		return WifiChannel{}, false
+	}
+
+	band := "5GHz"
+	switch {
+	case strings.HasPrefix(channel[digits:], sixGHzSuffix):
+		band = "6GHz"
+	case digits == len(channel) && number >= 2400:
+		return ChannelForFrequency(number)
+	case number <= 14:
+		band = "2.4GHz"
+	}
+	for _, c := range wifiChannels {
+		if c.Band == band && c.Number == number {
+			return c, true
+		}
+	}
+	return WifiChannel{}, false
+}
+
+// The centre frequency in MHz of a channel as Kismet names it
+func ChannelFrequency(channel string) (int, bool) {
+	c, ok := LookupChannel(channel)
+	return c.MHz, ok
+}
+
+// Whether two channels as Kismet names them are the same 20 MHz channel, e.g. 6 and 6HT40+ or 44 and 5220
+func SameChannel(a, b string) bool {
+	ca, okA := LookupChannel(a)
+	cb, okB := LookupChannel(b)
+	if okA && okB {
+		return ca.MHz == cb.MHz
+	}
+	return strings.TrimSpace(a) == strings.TrimSpace(b)
+}
+
+// Whether the channel is a DFS channel, which an AP moves off when it detects radar
+func IsDFS(channel string) bool {
+	c, ok := LookupChannel(channel)
+	return ok && c.DFS
+}
+
+// A channel for display with its frequency, e.g. "44 (5220 MHz)", or as it is if it isn't a known channel
+func FormatChannel(channel string) string {
+	if mhz, ok := ChannelFrequency(channel); ok {
+		return fmt.Sprintf("%s (%d MHz)", channel, mhz)
+	}
+	return channel
+}
+
+// The channel of a device from a Kismet device listing, or "" if it has none. Depending on the phy and
+// Kismet version the channel comes back as a string or a number, and sometimes as the frequency in MHz
+// (or kHz), which is translated to the Wi-Fi channel. Channel strings with a width suffix, like
+// "6HT40+", are kept as they are, since Kismet takes them back that way.
+func DeviceChannel(device map[string]interface{}) string {
+	switch channel := device["base.channel"].(type) {
+	case string:
+		return NormalizeChannel(channel)
+	case float64:
+		return NormalizeChannel(strconv.FormatFloat(channel, 'f', -1, 64))
+	case json.Number:
+		return NormalizeChannel(channel.String())
+	case int:
+		return NormalizeChannel(strconv.Itoa(channel))
+	}
+	return ""
+}
+
+// Tidy a channel Kismet reported: trim it, drop a trailing ".0" and turn a frequency into a channel. No
+// channel number is anywhere near 2400, so anything below that is taken to already be a channel.
+func NormalizeChannel(channel string) string {
+	channel = strings.TrimSpace(channel)
+	value, err := strconv.ParseFloat(channel, 64)
+	if err != nil {
+		return channel
+	}
+
+	mhz := value
+	if mhz >= 1_000_000 {
+		mhz /= 1000
+	}
+	if c, ok := ChannelForFrequency(int(mhz)); ok && mhz == float64(int(mhz)) {
+		return c.String()
+	}
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,172 @@
+package tracker
+
+import (
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDeviceChannel(t *testing.T) {
+	tests := []struct {
+		channel interface{}
+		want    string
+	}{
+		{"6", "6"},
+		{" 11 ", "11"},
+		{float64(6), "6"},
+		{float64(149), "149"},
+		{json.Number("36"), "36"},
+		{6, "6"},
+		{"6HT40+", "6HT40+"},
+		{"36VHT80", "36VHT80"},
+
+		// Frequencies are shown as channel numbers
+		{"2412", "1"},
+		{float64(2437), "6"},
+		{"2484", "14"},
+		{"5180", "36"},
+		{float64(5825), "165"},
+		{"5955", "1W6e"},
+		{"5935", "2W6e"},
+		{float64(6115), "33W6e"},
+		{float64(5180000), "36"}, // kHz
+		{"5181", "5181"},         // Not on a channel
+		{"", ""},
+		{nil, ""},
+		{true, ""},
+	}
+	for _, test := range tests {
+		if got := DeviceChannel(map[string]interface{}{"base.channel": test.channel}); got != test.want {
+			t.Errorf("DeviceChannel(%#v) = %q, want %q", test.channel, got, test.want)
+		}
+	}
+	if got := DeviceChannel(map[string]interface{}{}); got != "" {
+		t.Errorf("DeviceChannel without a channel = %q", got)
+	}
+}
+
+func TestChannelFrequency(t *testing.T) {
+	tests := []struct {
+		channel string
+		mhz     int
+	}{
+		{"1", 2412},
+		{"6", 2437},
+		{"6HT40+", 2437},
+		{"13", 2472},
+		{"14", 2484},
+		{"36", 5180},
+		{"44", 5220},
+		{"36VHT80", 5180},
+		{"64", 5320},
+		{"100", 5500},
+		{"144", 5720},
+		{"149", 5745},
+		{"165", 5825},
+		{"177", 5885},
+		{"1W6e", 5955},
+		{"2W6e", 5935},
+		{"37W6e", 6135},
+		{"233W6e", 7115},
+		{"5220", 5220},
+		{" 11 ", 2462},
+	}
+	for _, test := range tests {
+		if mhz, ok := ChannelFrequency(test.channel); !ok || mhz != test.mhz {
+			t.Errorf("ChannelFrequency(%q) = %d, %v; want %d", test.channel, mhz, ok, test.mhz)
+		}
+	}
+
+	for _, channel := range []string{"", "15", "35", "80", "3W6e", "237W6e", "5181", "HT40"} {
+		if mhz, ok := ChannelFrequency(channel); ok {
+			t.Errorf("ChannelFrequency(%q) = %d, want no channel", channel, mhz)
+		}
+	}
+}
+
+func TestChannelForFrequencyRoundTrip(t *testing.T) {
+	for _, c := range wifiChannels {
+		found, ok := ChannelForFrequency(c.MHz)
+		if !ok || found != c {
+			t.Errorf("ChannelForFrequency(%d) = %+v, want %+v", c.MHz, found, c)
+		}
+		if mhz, ok := ChannelFrequency(c.String()); !ok || mhz != c.MHz {
+			t.Errorf("ChannelFrequency(%q) = %d, want %d", c.String(), mhz, c.MHz)
+		}
+	}
+}
+
+func TestPSCChannels(t *testing.T) {
+	var psc []int
+	for _, c := range wifiChannels {
+		if c.PSC {
+			if c.Band != "6GHz" {
+				t.Errorf("%s channel %d marked PSC", c.Band, c.Number)
+			}
+			psc = append(psc, c.Number)
+		}
+	}
+	want := []int{5, 21, 37, 53, 69, 85, 101, 117, 133, 149, 165, 181, 197, 213, 229}
+	if !slices.Equal(psc, want) {
+		t.Errorf("PSC channels = %v, want %v", psc, want)
+	}
+	if c, ok := ChannelForFrequency(6135); !ok || !c.PSC || c.String() != "37W6e" {
+		t.Errorf("6135 MHz = %+v, want PSC channel 37W6e", c)
+	}
+}
+
+func TestSameChannelAndDFS(t *testing.T) {
+	same := [][2]string{{"6", "6"}, {"6", "6HT40+"}, {"44", "5220"}, {"36VHT80", "36"}, {"1W6e", "5955"}, {"x", "x"}}
+	for _, pair := range same {
+		if !SameChannel(pair[0], pair[1]) {
+			t.Errorf("SameChannel(%q, %q) = false", pair[0], pair[1])
+		}
+	}
+	different := [][2]string{{"6", "11"}, {"1", "1W6e"}, {"36", "40"}, {"6", ""}, {"x", "y"}}
+	for _, pair := range different {
+		if SameChannel(pair[0], pair[1]) {
+			t.Errorf("SameChannel(%q, %q) = true", pair[0], pair[1])
+		}
+	}
+
+	for channel, want := range map[string]bool{"36": false, "52": true, "100HT40+": true, "144": true, "149": false, "6": false, "53W6e": false} {
+		if IsDFS(channel) != want {
+			t.Errorf("IsDFS(%q) = %v, want %v", channel, !want, want)
+		}
+	}
+}
+
+func TestFormatChannel(t *testing.T) {
+	tests := map[string]string{
+		"44":     "44 (5220 MHz)",
+		"6HT40+": "6HT40+ (2437 MHz)",
+		"5W6e":   "5W6e (5975 MHz)",
+		"":       "",
+		"200":    "200",
+	}
+	for channel, want := range tests {
+		if got := FormatChannel(channel); got != want {
+			t.Errorf("FormatChannel(%q) = %q, want %q", channel, got, want)
+		}
+	}
+}
+
+func TestNumericChannelListing(t *testing.T) {
+	phone := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	listing := []map[string]interface{}{device(phone.Value, "", "", -60)}
+	listing[0]["base.channel"] = float64(5180)
+	s := New([]*Target{phone}, start)
+
+	s.Observe(listing, start)
+	if s.LockedTarget != phone || s.Channel != "36" || !slices.Equal(phone.Channels, []string{"36"}) {
+		t.Fatalf("channel = %q, target channels = %v; want 36", s.Channel, phone.Channels)
+	}
+
+	// A reading that doesn't say which channel keeps the one already known
+	delete(listing[0], "base.channel")
+	s.Observe(listing, start.Add(time.Second))
+	if s.Channel != "36" {
+		t.Errorf("channel = %q after a reading without one, want 36", s.Channel)
+	}
+}
@@ -0,0 +1,260 @@
+package tracker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/oui"
+)
+
+var errDeviceNotFound = errors.New("device not found") // Error to match on
+
+type DeviceInfo struct {
+	RSSI              int               // Signal strength
+	Channel           string            // Operating channel
+	Manufacturer      string            // Manufacturer of the device
+	SSID              string            // SSID of the device (if applicable)
+	Crypt             string            // Encryption type
+	Type              string            // Device type (AP, Client, etc.)
+	AssociatedClients map[string]string // Map of associated client MAC addresses
+	Packets           int               // Packets Kismet has seen from the device, 0 if unknown
+	DataPackets       int               // Of those, data packets
+	WPS               string            // "on", "off" or "locked" for an AP's advertised WPS, "" if unknown
+	BeaconRate        int               // Beacons per second the AP advertises, 0 if unknown
+	HTMode            string            // The AP's HT/VHT/HE mode and width, e.g. "VHT80", "" if unknown
+	Handshake         bool              // Kismet has captured enough of a WPA handshake to crack
+}
+
+// Bits of Kismet's dot11.advertisedssid.wps_state
+const (
+	wpsConfigured    = 1 << 1
+	wpsNotConfigured = 1 << 2
+	wpsLocked        = 1 << 3
+)
+
+// Bits of Kismet's dot11.device.wpa_present_handshake, one per message of the 4-way handshake seen. Messages
+// 1 and 2, or 2 and 3, are enough to crack it.
+const (
+	handshakeM1 = 1 << 1
+	handshakeM2 = 1 << 2
+	handshakeM3 = 1 << 3
+)
+
+// The Wi-Fi generation the AP's HT mode implies, e.g. "WiFi 5", or "" if unknown
+func (d *DeviceInfo) Generation() string {
+	mode := strings.ToUpper(d.HTMode)
+	switch {
+	case strings.HasPrefix(mode, "EHT"):
+		return "WiFi 7"
+	case strings.HasPrefix(mode, "HE"):
+		if c, ok := LookupChannel(d.Channel); ok && c.Band == "6GHz" {
+			return "WiFi 6E"
+		}
+		return "WiFi 6"
+	case strings.HasPrefix(mode, "VHT"):
+		return "WiFi 5"
+	case strings.HasPrefix(mode, "HT"):
+		return "WiFi 4"
+	}
+	return ""
+}
+
+// The channel width in MHz from the AP's HT mode, or 0 if unknown
+func (d *DeviceInfo) Width() int {
+	digits := strings.Trim(strings.TrimLeft(strings.ToUpper(d.HTMode), "EHTV"), "+-")
+	width, _ := strconv.Atoi(digits)
+	return width
+}
+
+// A compact summary of what's known about the device, e.g. "WiFi 5 · 80MHz · WPS off · 14.2k pkts", or ""
+// if nothing is
+func (d *DeviceInfo) Details() string {
+	var parts []string
+	if generation := d.Generation(); generation != "" {
+		parts = append(parts, generation)
+	}
+	if width := d.Width(); width > 0 {
+		parts = append(parts, fmt.Sprintf("%dMHz", width))
+	}
+	if d.WPS != "" {
+		parts = append(parts, "WPS "+d.WPS)
+	}
+	if d.BeaconRate > 0 {
+		parts = append(parts, fmt.Sprintf("%d beacons/s", d.BeaconRate))
+	}
+	if d.Packets > 0 {
+		parts = append(parts, FormatCount(d.Packets)+" pkts")
+	}
+	return strings.Join(parts, " · ")
+}
+
+// A count shortened for display, e.g. 950, 14.2k or 3.1M
+func FormatCount(n int) string {
+	switch {
+	case n >= 1_000_000:
+		return fmt.Sprintf("%.1fM", float64(n)/1_000_000)
+	case n >= 1000:
+		return fmt.Sprintf("%.1fk", float64(n)/1000)
+	}
+	return strconv.Itoa(n)
+}
+
+// The associated client MACs, sorted
+func (d *DeviceInfo) ClientMACs() []string {
+	clients := make([]string, 0, len(d.AssociatedClients))
+	for client := range d.AssociatedClients {
+		clients = append(clients, client)
+	}
+	slices.Sort(clients)
+	return clients
+}
+
+// Find the device with the given MAC in a Kismet device listing and return its details
+func extractDeviceInfo(devices []map[string]interface{}, mac string) (*DeviceInfo, error) {
+	for _, device := range devices {
+		// Check if the MAC address matches
+		if macAddr, ok := device["base.macaddr"].(string); ok && macAddr == mac {
+			deviceInfo := &DeviceInfo{
+				RSSI:              MinRSSI, // Default RSSI value
+				Channel:           "",
+				Manufacturer:      "Unknown",
+				SSID:              "Unknown",
+				Crypt:             "Unknown",
+				Type:              "Unknown",
+				AssociatedClients: map[string]string{},
+			}
+
+			// Extract fields
+			if rssiVal, ok := device["RSSI"].(float64); ok {
+				deviceInfo.RSSI = int(rssiVal)
+			}
+			deviceInfo.Channel = DeviceChannel(device)
+			makeVal, _ := device["Make"].(string)
+			deviceInfo.Manufacturer = ResolveManufacturer(mac, makeVal)
+			if ssidVal, ok := device["SSID"].(string); ok {
+				deviceInfo.SSID = ssidVal
+			}
+			if cryptVal, ok := device["Crypt"].(string); ok {
+				deviceInfo.Crypt = cryptVal
+			}
+			if typeVal, ok := device["Type"].(string); ok {
+				deviceInfo.Type = typeVal
+			}
+			// Only Wi-Fi devices have these, and only APs the advertised SSID ones
+			deviceInfo.Packets, _ = deviceNumber(device, "Packets")
+			deviceInfo.DataPackets, _ = deviceNumber(device, "DataPackets")
+			deviceInfo.BeaconRate, _ = deviceNumber(device, "BeaconRate")
+			if wps, ok := deviceNumber(device, "WPSState"); ok {
+				deviceInfo.WPS = wpsStatus(wps)
+			}
+			if present, ok := deviceNumber(device, "Handshake"); ok {
+				deviceInfo.Handshake = handshakeCaptured(present)
+			}
+			if htMode, ok := device["HTMode"].(string); ok {
+				deviceInfo.HTMode = strings.TrimSpace(htMode)
+			}
+			// Extract associated clients (if any)
+			if associatedClientsVal, ok := device["AssociatedClients"].(map[string]interface{}); ok {
+				for clientMac, assoc := range associatedClientsVal {
+					deviceInfo.AssociatedClients[clientMac] = fmt.Sprintf("%v", assoc)
+				}
+			}
+
+			return deviceInfo, nil
+		}
+	}
+
+	return nil, errDeviceNotFound
+}
+
+// The manufacturer Kismet gave a device, or if it had none, "randomized" for a locally administered MAC
+// (which has no vendor) or the vendor from the bundled OUI registry marked with an asterisk, so it's clear
+// the name didn't come from Kismet
+func ResolveManufacturer(mac, kismetMake string) string {
+	kismetMake = strings.TrimSpace(kismetMake)
+	if kismetMake != "" && !strings.EqualFold(kismetMake, "Unknown") {
+		return kismetMake
+	}
+	if oui.Randomized(mac) {
+		return "randomized"
+	}
+	if vendor, ok := oui.Lookup(mac); ok {
+		return vendor + "*"
+	}
+	return "Unknown"
+}
+
+// A whole number field of a Kismet device listing, which decodes as a float64 unless the decoder was told
+// to use json.Number
+func deviceNumber(device map[string]interface{}, field string) (int, bool) {
+	switch value := device[field].(type) {
+	case float64:
+		return int(value), true
+	case json.Number:
+		n, err := value.Int64()
+		return int(n), err == nil
+	case int:
+		return value, true
+	}
+	return 0, false
+}
+
+// Describe Kismet's WPS state bits
+func wpsStatus(state int) string {
+	switch {
+	case state&wpsLocked != 0:
+		return "locked"
+	case state&(wpsConfigured|wpsNotConfigured) != 0:
+		return "on"
+	}
+	return "off"
+}
+
+// Whether Kismet's handshake bits hold a crackable pair of messages
+func handshakeCaptured(present int) bool {
+	return present&(handshakeM1|handshakeM2) == handshakeM1|handshakeM2 ||
+		present&(handshakeM2|handshakeM3) == handshakeM2|handshakeM3
+}
+
+// Finds a valid MAC or SSID in a Kismet device listing and returns a MAC, channel and *Target. An SSID
+// target is resolved on the way: its Value becomes the MAC of the access point, with the SSID kept in
+// OriginalValue.
+func findValidTarget(devices []map[string]interface{}, targets []*Target) (string, string, *Target) {
+	// Iterate over targets
+	for _, target := range targets {
+		if target.IsIgnored() || target.Stale {
+			continue
+		}
+
+		// Iterate over devices
+		for _, device := range devices {
+			// Extract device fields
+			deviceMac, _ := device["base.macaddr"].(string)
+			deviceChannel := DeviceChannel(device)
+
+			if target.TType == MAC {
+				if deviceMac == target.Value {
+					return target.Value, deviceChannel, target
+				}
+			} else if target.TType == SSID {
+				if ssidVal, ok := device["SSID"].(string); ok && ssidVal == target.Value {
+					macAddr, _ := device["base.macaddr"].(string)
+					if _, ok := device["base.channel"]; ok {
+						newTarget := target                    // Create a copy of the target
+						newTarget.OriginalValue = target.Value // Store the original SSID
+						newTarget.TType = SSID
+						newTarget.Value = macAddr // Set the value to the MAC address
+						return macAddr, deviceChannel, newTarget
+					}
+				}
+			}
+		}
+	}
+
+	// No valid target found
+	return "", "", nil
+}
@@ -0,0 +1,193 @@
+package tracker
+
+import (
+	"slices"
+	"testing"
+)
+
+// A device as FetchAllDevices returns it, with the aliased field names
+func device(mac, channel, ssid string, rssi float64) map[string]interface{} {
+	d := map[string]interface{}{
+		"base.macaddr": mac,
+		"base.channel": channel,
+		"RSSI":         rssi,
+	}
+	if ssid != "" {
+		d["SSID"] = ssid
+	}
+	return d
+}
+
+func TestExtractDeviceInfo(t *testing.T) {
+	ap := device("10:22:33:44:55:66", "6", "CoffeeShop", -52)
+	ap["Make"] = "Ubiquiti"
+	ap["Crypt"] = "WPA2"
+	ap["Type"] = "Wi-Fi AP"
+	ap["AssociatedClients"] = map[string]interface{}{"32:34:00:00:00:02": "x", "32:34:00:00:00:01": "y"}
+	devices := []map[string]interface{}{device("32:34:00:00:00:01", "6", "", -70), ap}
+
+	info, err := extractDeviceInfo(devices, "10:22:33:44:55:66")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.RSSI != -52 || info.Channel != "6" || info.SSID != "CoffeeShop" || info.Manufacturer != "Ubiquiti" ||
+		info.Crypt != "WPA2" || info.Type != "Wi-Fi AP" {
+		t.Errorf("info = %+v", info)
+	}
+	if clients := info.ClientMACs(); !slices.Equal(clients, []string{"32:34:00:00:00:01", "32:34:00:00:00:02"}) {
+		t.Errorf("ClientMACs() = %v, want them sorted", clients)
+	}
+
+	// Fields Kismet left out fall back to defaults
+	info, err = extractDeviceInfo([]map[string]interface{}{{"base.macaddr": "32:34:00:00:00:01"}}, "32:34:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The client's MAC is locally administered, so there's no vendor to fall back to
+	if info.RSSI != MinRSSI || info.Manufacturer != "randomized" || info.SSID != "Unknown" {
+		t.Errorf("defaults = %+v", info)
+	}
+
+	if _, err := extractDeviceInfo(devices, "AC:34:23:FE:BC:3D"); err != errDeviceNotFound {
+		t.Errorf("missing device: err = %v, want errDeviceNotFound", err)
+	}
+}
+
+func TestDeviceDetails(t *testing.T) {
+	ap := device("10:22:33:44:55:66", "37W6e", "CoffeeShop", -52)
+	ap["Packets"] = 3_100_000.0
+	ap["DataPackets"] = 2_000_000.0
+	ap["WPSState"] = float64(wpsConfigured | wpsLocked)
+	ap["HTMode"] = "HE160"
+	ap["Handshake"] = float64(handshakeM1 | handshakeM2)
+
+	info, err := extractDeviceInfo([]map[string]interface{}{ap}, "10:22:33:44:55:66")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details := info.Details(); details != "WiFi 6E · 160MHz · WPS locked · 3.1M pkts" {
+		t.Errorf("Details() = %q", details)
+	}
+	if !info.Handshake {
+		t.Error("handshake messages 1 and 2 not taken as a captured handshake")
+	}
+
+	for _, tc := range []struct {
+		htMode     string
+		generation string
+		width      int
+	}{
+		{"HT20", "WiFi 4", 20},
+		{"HT40+", "WiFi 4", 40},
+		{"VHT80", "WiFi 5", 80},
+		{"HE80", "WiFi 6", 80},
+		{"EHT320", "WiFi 7", 320},
+		{"", "", 0},
+		{"bogus", "", 0},
+	} {
+		d := &DeviceInfo{HTMode: tc.htMode, Channel: "36"}
+		if d.Generation() != tc.generation || d.Width() != tc.width {
+			t.Errorf("HT mode %q: generation %q, width %d; want %q, %d", tc.htMode, d.Generation(), d.Width(), tc.generation, tc.width)
+		}
+	}
+
+	for state, want := range map[int]string{0: "off", wpsConfigured: "on", wpsNotConfigured: "on", wpsConfigured | wpsLocked: "locked"} {
+		if got := wpsStatus(state); got != want {
+			t.Errorf("wpsStatus(%d) = %q, want %q", state, got, want)
+		}
+	}
+
+	for present, want := range map[int]bool{
+		0:                         false,
+		handshakeM1:               false,
+		handshakeM1 | handshakeM3: false,
+		handshakeM2 | handshakeM3: true,
+		handshakeM1 | handshakeM2: true,
+		handshakeM1 | handshakeM2 | handshakeM3 | 1<<4: true,
+	} {
+		if got := handshakeCaptured(present); got != want {
+			t.Errorf("handshakeCaptured(%#x) = %v, want %v", present, got, want)
+		}
+	}
+
+	// A client without dot11 records, or with fields of the wrong type, has no details
+	client := device("32:34:00:00:00:01", "6", "", -70)
+	client["WPSState"] = "on"
+	client["Packets"] = "lots"
+	info, err = extractDeviceInfo([]map[string]interface{}{client}, "32:34:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details := info.Details(); details != "" || info.WPS != "" || info.Handshake {
+		t.Errorf("client details = %q, WPS = %q, handshake %v; want none", details, info.WPS, info.Handshake)
+	}
+}
+
+func TestResolveManufacturer(t *testing.T) {
+	for _, tc := range []struct {
+		mac, kismetMake, want string
+	}{
+		{"B8:27:EB:00:00:01", "Raspberry Pi", "Raspberry Pi"},
+		{"B8:27:EB:00:00:01", "Unknown", "Raspberry Pi Foundation*"},
+		{"B8:27:EB:00:00:01", "", "Raspberry Pi Foundation*"},
+		{"32:34:00:00:00:01", "unknown", "randomized"},
+		{"32:34:00:00:00:01", "Apple", "Apple"},
+	} {
+		if got := ResolveManufacturer(tc.mac, tc.kismetMake); got != tc.want {
+			t.Errorf("ResolveManufacturer(%q, %q) = %q, want %q", tc.mac, tc.kismetMake, got, tc.want)
+		}
+	}
+}
+
+func TestFormatCount(t *testing.T) {
+	for n, want := range map[int]string{0: "0", 950: "950", 14_200: "14.2k", 3_100_000: "3.1M"} {
+		if got := FormatCount(n); got != want {
+			t.Errorf("FormatCount(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
+
+func TestFindValidTargetByMAC(t *testing.T) {
+	phone := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	devices := []map[string]interface{}{device("10:22:33:44:55:66", "1", "", -60), device("32:34:00:00:00:01", "11", "", -60)}
+
+	value, channel, target := findValidTarget(devices, []*Target{phone})
+	if value != phone.Value || channel != "11" || target != phone {
+		t.Errorf("findValidTarget = %q, %q, %p; want %q, 11, %p", value, channel, target, phone.Value, phone)
+	}
+}
+
+func TestFindValidTargetResolvesSSID(t *testing.T) {
+	cafe := &Target{Value: "CoffeeShop", TType: SSID}
+	devices := []map[string]interface{}{device("10:22:33:44:55:66", "149", "CoffeeShop", -60)}
+
+	value, channel, target := findValidTarget(devices, []*Target{cafe})
+	if value != "10:22:33:44:55:66" || channel != "149" {
+		t.Errorf("findValidTarget = %q, %q; want the access point's MAC and channel", value, channel)
+	}
+	// The configured target itself is resolved, so the list and the lock share it
+	if target != cafe {
+		t.Fatal("resolved target is not the configured one")
+	}
+	if cafe.Value != "10:22:33:44:55:66" || cafe.OriginalValue != "CoffeeShop" || cafe.TType != SSID {
+		t.Errorf("resolved target = %+v", cafe)
+	}
+}
+
+func TestFindValidTargetSkipsIgnoredAndStale(t *testing.T) {
+	ignored := &Target{Value: "32:34:00:00:00:01", TType: MAC, Ignored: true}
+	stale := &Target{Value: "32:34:00:00:00:02", TType: MAC, Stale: true}
+	wanted := &Target{Value: "32:34:00:00:00:03", TType: MAC}
+	devices := []map[string]interface{}{
+		device("32:34:00:00:00:01", "1", "", -40),
+		device("32:34:00:00:00:02", "1", "", -40),
+		device("32:34:00:00:00:03", "1", "", -80),
+	}
+
+	if _, _, target := findValidTarget(devices, []*Target{ignored, stale, wanted}); target != wanted {
+		t.Errorf("found %+v, want the only target that isn't ignored or stale", target)
+	}
+	if value, _, target := findValidTarget(devices, []*Target{ignored, stale}); value != "" || target != nil {
+		t.Errorf("found %q, want nothing", value)
+	}
+}
@@ -0,0 +1,405 @@
+package tracker
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	MinRSSI   = -120            // Floor of the RSSI, and the reading of a target that hasn't been heard
+	Timeout   = 5 * time.Second // A locked target not heard for this long goes quiet and its RSSI decays
+	DecayRate = 10              // dB the RSSI of a quiet target drops on each Tick
+
+	ignoreHistorySize = 20 // Ignore list changes UndoIgnore can step back through
+	MoveConfirmations = 2  // Readings on a new channel in a row before a locked target counts as moved
+)
+
+// Target discovery, locking and RSSI state, without any Kismet requests. The caller feeds each poll's
+// device listing, less any DropConflicts takes out, to Observe and then calls Tick, and carries out the
+// channel commands the events call for: hop back to scanning after Dropped, and lock onto Channel after
+// Heard while ChannelLocked is false, calling Locked once that worked. After Moved the channel is still
+// counted as locked, and the caller locks onto the new Channel and calls Locked again.
+type State struct {
+	Targets       []*Target
+	LockedTarget  *Target // Target being searched for, locked onto once ChannelLocked is set
+	Channel       string
+	ChannelLocked bool
+	LockedAt      time.Time // When the channel was locked to the current target
+	RSSI          int
+	LastReceived  time.Time
+	Quiet         bool          // The locked target has not been heard for longer than Timeout
+	ActiveTag     string        // Only targets with this tag are searched for, empty for all
+	LockDwell     time.Duration // Give up on a locked target not heard for this long, 0 to stay locked
+	StaleAfter    time.Duration // Targets not seen for this long are marked stale, 0 never
+	Multi         bool          // Watching every visible target at once, so nothing is locked
+
+	ignoreHistory [][]ignoreChange // Ignore list changes, most recent last
+	movingTo      string           // Channel the locked target was last heard on, if not the locked one
+	movingSeen    int              // Readings in a row on movingTo
+	conflict      string           // Channels of the records claiming the locked target's MAC, while several do
+}
+
+// A target's Ignored flag from before a change to the ignore list
+type ignoreChange struct {
+	target  *Target
+	ignored bool
+}
+
+func New(targets []*Target, now time.Time) *State {
+	return &State{
+		Targets:      targets,
+		RSSI:         MinRSSI,
+		LastReceived: now,
+	}
+}
+
+type EventKind int
+
+const (
+	Found     EventKind = iota + 1 // A target was picked to search for
+	Heard                          // The target being searched for is in the listing; Reading has its details
+	Dropped                        // The locked target was released after LockDwell without a reading
+	Lost                           // The locked target went quiet
+	Unignored                      // A target selected to search for was taken off the ignore list
+	Moved                          // The locked target moved to another channel; From has the old one
+	Conflict                       // Device records on different channels claim the locked target's MAC; Readings has them
+)
+
+// Something that happened to the state, for the caller to act on and report
+type Event struct {
+	Kind     EventKind
+	Target   *Target
+	Reading  *DeviceInfo   // Set for Heard
+	From     string        // Channel the target moved from, set for Moved
+	Readings []*DeviceInfo // Every record claiming the target's MAC, set for Conflict
+}
+
+// Take in a device listing: mark stale targets, give up on a locked target gone for the whole LockDwell,
+// pick a target to search for if there is none, and record the searched-for target's reading
+func (s *State) Observe(devices []map[string]interface{}, now time.Time) []Event {
+	var events []Event
+	s.markStale(now)
+
+	// A target that's been gone for the whole dwell is let go so discovery can move on to another
+	if s.LockedTarget != nil && s.ChannelLocked && s.LockDwell > 0 && now.Sub(s.LastReceived) > s.LockDwell {
+		events = append(events, Event{Kind: Dropped, Target: s.LockedTarget})
+		s.Release()
+	}
+
+	if s.LockedTarget == nil && !s.Multi {
+		value, channel, target := findValidTarget(devices, s.ActiveTargets())
+		if value != "" {
+			s.LockedTarget = target
+			s.Channel = channel
+			s.ChannelLocked = false
+			s.LockedAt = time.Time{}
+			s.Quiet = false
+			events = append(events, Event{Kind: Found, Target: target})
+		}
+	}
+
+	if s.LockedTarget != nil {
+		deviceInfo, _ := extractDeviceInfo(devices, s.LockedTarget.Value)
+		if deviceInfo != nil {
+			s.Quiet = false
+			s.LockedTarget.UpdateSignal(deviceInfo.RSSI)
+			s.RSSI = deviceInfo.RSSI
+			if s.ChannelLocked {
+				if event, moved := s.followChannel(deviceInfo.Channel); moved {
+					events = append(events, event)
+				}
+			} else if deviceInfo.Channel != "" { // A reading without a channel keeps the last one
+				s.Channel = deviceInfo.Channel
+			}
+			s.LockedTarget.ObserveChannel(deviceInfo.Channel)
+			s.LastReceived = now
+			events = append(events, Event{Kind: Heard, Target: s.LockedTarget, Reading: deviceInfo})
+		}
+	}
+
+	return events
+}
+
+// Look for more than one device record claiming the locked target's MAC on different channels, as when a
+// decoy beacons with a target's BSSID on another channel. Only the record on the target's channel is kept
+// in the returned listing, so its RSSI isn't a mix of the two; if none is on it, all are kept. A Conflict
+// event is returned when this starts or the channels involved change.
+func (s *State) DropConflicts(devices []map[string]interface{}) ([]map[string]interface{}, []Event) {
+	if s.LockedTarget == nil || s.Channel == "" {
+		s.conflict = ""
+		return devices, nil
+	}
+
+	var claims []int // Indexes of the records in devices
+	var channels []string
+	for i, device := range devices {
+		if mac, _ := device["base.macaddr"].(string); mac != s.LockedTarget.Value {
+			continue
+		}
+		claims = append(claims, i)
+		channel := DeviceChannel(device)
+		if channel != "" && !slices.ContainsFunc(channels, func(c string) bool { return SameChannel(c, channel) }) {
+			channels = append(channels, channel)
+		}
+	}
+	if len(channels) < 2 {
+		s.conflict = ""
+		return devices, nil
+	}
+
+	onChannel := slices.ContainsFunc(claims, func(i int) bool { return SameChannel(DeviceChannel(devices[i]), s.Channel) })
+	kept := make([]map[string]interface{}, 0, len(devices))
+	for i, device := range devices {
+		if onChannel && slices.Contains(claims, i) && !SameChannel(DeviceChannel(device), s.Channel) {
+			continue
+		}
+		kept = append(kept, device)
+	}
+
+	slices.SortFunc(channels, CompareChannels)
+	key := strings.Join(channels, ",")
+	if key == s.conflict {
+		return kept, nil
+	}
+	s.conflict = key
+	event := Event{Kind: Conflict, Target: s.LockedTarget}
+	for _, i := range claims {
+		reading, _ := extractDeviceInfo(devices[i:i+1], s.LockedTarget.Value)
+		event.Readings = append(event.Readings, reading)
+	}
+	return kept, []Event{event}
+}
+
+// Track a locked target's reading on another channel, as when a mesh AP steers it or a DFS event moves an
+// AP. It only counts as moved once it has been heard there MoveConfirmations times in a row, so a stray
+// reading doesn't pull the lock off.
+func (s *State) followChannel(channel string) (Event, bool) {
+	if channel == "" || SameChannel(channel, s.Channel) {
+		s.movingTo, s.movingSeen = "", 0
+		return Event{}, false
+	}
+	if SameChannel(channel, s.movingTo) {
+		s.movingSeen++
+	} else {
+		s.movingTo, s.movingSeen = channel, 1
+	}
+	if s.movingSeen < MoveConfirmations {
+		return Event{}, false
+	}
+
+	from := s.Channel
+	s.Channel = channel
+	s.movingTo, s.movingSeen = "", 0
+	return Event{Kind: Moved, Target: s.LockedTarget, From: from}, true
+}
+
+// Record that the channel was locked to the target being searched for
+func (s *State) Locked(now time.Time) {
+	s.ChannelLocked = true
+	s.LockedAt = now
+	s.movingTo, s.movingSeen = "", 0
+}
+
+// Age the reading: the locked target goes quiet once it hasn't been heard for Timeout, and from then
+// on the RSSI decays by DecayRate on every tick until it is heard again
+func (s *State) Tick(now time.Time) []Event {
+	var events []Event
+
+	// Only a target that has been heard since it was picked can go quiet
+	if s.ChannelLocked && !s.Quiet && now.Sub(s.LastReceived) > Timeout {
+		s.Quiet = true
+		events = append(events, Event{Kind: Lost, Target: s.LockedTarget})
+	}
+
+	// Decay RSSI if no signal received in a while
+	if now.Sub(s.LastReceived) > Timeout && s.RSSI > MinRSSI {
+		s.RSSI -= DecayRate
+		if s.RSSI < MinRSSI {
+			s.RSSI = MinRSSI
+		}
+	}
+
+	return events
+}
+
+// Start searching for the given target, unlocking the channel until it is heard. A target on the ignore
+// list is taken off it, since picking it is a clear sign it's wanted.
+func (s *State) SelectTarget(target *Target) []Event {
+	var events []Event
+	if target.IsIgnored() {
+		s.recordIgnore([]*Target{target})
+		target.ToggleIgnore()
+		events = append(events, Event{Kind: Unignored, Target: target})
+	}
+
+	s.Multi = false
+	s.LockedTarget = target
+	s.LockedTarget.ChannelLocked = false
+	s.LockedTarget.Peak = SignalPeak{}
+	s.ChannelLocked = false
+	s.LockedAt = time.Time{}
+	s.Quiet = false
+	s.movingTo, s.movingSeen = "", 0
+	s.conflict = ""
+	return events
+}
+
+// Drop the current target, going back to scanning
+func (s *State) Release() {
+	if s.LockedTarget != nil {
+		s.LockedTarget.Peak = SignalPeak{}
+	}
+	s.LockedTarget = nil
+	s.Channel = ""
+	s.ChannelLocked = false
+	s.LockedAt = time.Time{}
+	s.Quiet = false
+	s.movingTo, s.movingSeen = "", 0
+	s.conflict = ""
+}
+
+// Toggle whether target is ignored, carrying it over to the configured target with the same MAC or SSID,
+// and return whether it is ignored now. The caller releases it to resume the search.
+func (s *State) ToggleIgnore(target *Target) bool {
+	changed := []*Target{target}
+	for _, t := range s.Targets {
+		if (target.TType == MAC && t.Value == target.Value) ||
+			(target.TType == SSID && t.OriginalValue == target.OriginalValue) {
+			if t != target {
+				changed = append(changed, t)
+			}
+			break
+		}
+	}
+	s.recordIgnore(changed)
+
+	target.ToggleIgnore()
+	for _, t := range changed {
+		t.Ignored = target.Ignored
+	}
+	return target.Ignored
+}
+
+// Ignore every target except keep, returning how many were added to the ignore list
+func (s *State) IgnoreAllExcept(keep *Target) int {
+	var changed []*Target
+	for _, target := range s.Targets {
+		if target != keep && !target.IsIgnored() {
+			changed = append(changed, target)
+		}
+	}
+	s.recordIgnore(changed)
+	for _, target := range changed {
+		target.Ignored = true
+	}
+	return len(changed)
+}
+
+// Empty the ignore list, returning how many targets were on it
+func (s *State) UnignoreAll() int {
+	var changed []*Target
+	for _, target := range s.Targets {
+		if target.IsIgnored() {
+			changed = append(changed, target)
+		}
+	}
+	s.recordIgnore(changed)
+	for _, target := range changed {
+		target.Ignored = false
+	}
+	return len(changed)
+}
+
+// Revert the most recent change to the ignore list, returning the targets it restored, or nil if there
+// is nothing left to undo. The caller releases the locked target if it is ignored again.
+func (s *State) UndoIgnore() []*Target {
+	if len(s.ignoreHistory) == 0 {
+		return nil
+	}
+	last := s.ignoreHistory[len(s.ignoreHistory)-1]
+	s.ignoreHistory = s.ignoreHistory[:len(s.ignoreHistory)-1]
+
+	restored := make([]*Target, 0, len(last))
+	for _, change := range last {
+		change.target.Ignored = change.ignored
+		restored = append(restored, change.target)
+	}
+	return restored
+}
+
+// Remember the Ignored flags of targets about to change, dropping the oldest change once the history is full
+func (s *State) recordIgnore(targets []*Target) {
+	if len(targets) == 0 {
+		return
+	}
+	changes := make([]ignoreChange, 0, len(targets))
+	for _, target := range targets {
+		changes = append(changes, ignoreChange{target: target, ignored: target.Ignored})
+	}
+	s.ignoreHistory = append(s.ignoreHistory, changes)
+	if len(s.ignoreHistory) > ignoreHistorySize {
+		s.ignoreHistory = slices.Delete(s.ignoreHistory, 0, 1)
+	}
+}
+
+// Mark targets not seen within StaleAfter as stale, so discovery passes over them, and clear the mark
+// from any seen again. The locked target is left alone.
+func (s *State) markStale(now time.Time) {
+	if s.StaleAfter <= 0 {
+		return
+	}
+	for _, target := range s.Targets {
+		if target == s.LockedTarget {
+			continue
+		}
+		lastSeen := target.LastSeen
+		if lastSeen.IsZero() {
+			// Never seen, so the clock starts from the first check
+			if target.watchedSince.IsZero() {
+				target.watchedSince = now
+			}
+			lastSeen = target.watchedSince
+		}
+		stale := now.Sub(lastSeen) > s.StaleAfter
+		if stale == target.Stale {
+			continue
+		}
+		target.Stale = stale
+		if stale {
+			slog.Info("Target not seen recently, marked stale", "target", target.DisplayValue(), "after", s.StaleAfter)
+		} else {
+			slog.Info("Stale target seen again", "target", target.DisplayValue())
+		}
+	}
+}
+
+// Targets in the active group
+func (s *State) ActiveTargets() []*Target {
+	if s.ActiveTag == "" {
+		return s.Targets
+	}
+
+	var active []*Target
+	for _, target := range s.Targets {
+		if target.HasTag(s.ActiveTag) {
+			active = append(active, target)
+		}
+	}
+	return active
+}
+
+// Every tag used by a target, sorted
+func (s *State) AllTags() []string {
+	var tags []string
+	for _, target := range s.Targets {
+		for _, tag := range target.Tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	slices.Sort(tags)
+	return tags
+}
@@ -0,0 +1,470 @@
+package tracker
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+var start = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// The kinds of events, in order
+func kinds(events []Event) []EventKind {
+	var out []EventKind
+	for _, event := range events {
+		out = append(out, event.Kind)
+	}
+	return out
+}
+
+// A state searching for a single MAC target that has just been heard and locked onto at start
+func lockedState(t *testing.T, rssi float64) (*State, *Target) {
+	t.Helper()
+	target := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	s := New([]*Target{target}, start)
+
+	events := s.Observe([]map[string]interface{}{device(target.Value, "6", "", rssi)}, start)
+	if got := kinds(events); !slices.Equal(got, []EventKind{Found, Heard}) {
+		t.Fatalf("events = %v, want Found then Heard", got)
+	}
+	s.Locked(start)
+	return s, target
+}
+
+func TestObserveFindsAndLocks(t *testing.T) {
+	s, target := lockedState(t, -55)
+
+	if s.LockedTarget != target || s.Channel != "6" || s.RSSI != -55 || !s.LastReceived.Equal(start) {
+		t.Errorf("state after first reading = %+v", s)
+	}
+	if !s.ChannelLocked || !s.LockedAt.Equal(start) {
+		t.Errorf("ChannelLocked = %v, LockedAt = %v; want locked at start", s.ChannelLocked, s.LockedAt)
+	}
+	if !slices.Equal(target.Channels, []string{"6"}) || target.LastRSSI != -55 {
+		t.Errorf("target = %+v", target)
+	}
+
+	// Later readings only report the target being heard
+	events := s.Observe([]map[string]interface{}{device(target.Value, "6", "", -50)}, start.Add(time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Heard}) {
+		t.Errorf("events = %v, want Heard", got)
+	}
+	if events[0].Reading == nil || events[0].Reading.RSSI != -50 || s.RSSI != -50 {
+		t.Errorf("reading = %+v, RSSI = %d", events[0].Reading, s.RSSI)
+	}
+}
+
+func TestObserveWithoutTargets(t *testing.T) {
+	s := New([]*Target{{Value: "32:34:00:00:00:01", TType: MAC}}, start)
+
+	if events := s.Observe([]map[string]interface{}{device("10:22:33:44:55:66", "1", "", -40)}, start); len(events) != 0 {
+		t.Errorf("events = %v, want none", kinds(events))
+	}
+	// A failed device listing is nil and must not disturb the state
+	if events := s.Observe(nil, start); len(events) != 0 || s.LockedTarget != nil {
+		t.Errorf("events = %v, locked = %v after an empty listing", kinds(events), s.LockedTarget)
+	}
+}
+
+func TestObserveResolvesSSID(t *testing.T) {
+	cafe := &Target{Value: "CoffeeShop", TType: SSID}
+	s := New([]*Target{cafe}, start)
+
+	events := s.Observe([]map[string]interface{}{device("10:22:33:44:55:66", "149", "CoffeeShop", -61)}, start)
+	if got := kinds(events); !slices.Equal(got, []EventKind{Found, Heard}) {
+		t.Fatalf("events = %v, want Found then Heard", got)
+	}
+	if s.LockedTarget != cafe || cafe.Value != "10:22:33:44:55:66" || cafe.OriginalValue != "CoffeeShop" {
+		t.Errorf("locked = %+v", s.LockedTarget)
+	}
+
+	// Once resolved it is followed by MAC, even when the beacon no longer carries the SSID
+	s.Locked(start)
+	events = s.Observe([]map[string]interface{}{device("10:22:33:44:55:66", "149", "", -58)}, start.Add(time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Heard}) || s.RSSI != -58 {
+		t.Errorf("events = %v, RSSI = %d; want Heard at -58", got, s.RSSI)
+	}
+	if cafe.DisplayValue() != "CoffeeShop" {
+		t.Errorf("DisplayValue() = %q, want the SSID", cafe.DisplayValue())
+	}
+}
+
+func TestTickDecay(t *testing.T) {
+	s, target := lockedState(t, -50)
+
+	// Nothing happens while the target was heard within Timeout
+	if events := s.Tick(start.Add(Timeout)); len(events) != 0 || s.RSSI != -50 || s.Quiet {
+		t.Fatalf("at Timeout: events = %v, RSSI = %d, quiet = %v", kinds(events), s.RSSI, s.Quiet)
+	}
+
+	// Past it the target goes quiet once and decays on every tick
+	now := start.Add(Timeout + time.Millisecond)
+	events := s.Tick(now)
+	if got := kinds(events); !slices.Equal(got, []EventKind{Lost}) || events[0].Target != target {
+		t.Fatalf("events = %v, want Lost for the target", got)
+	}
+	if s.RSSI != -50-DecayRate || !s.Quiet {
+		t.Errorf("RSSI = %d, quiet = %v; want %d and quiet", s.RSSI, s.Quiet, -50-DecayRate)
+	}
+	if events := s.Tick(now.Add(time.Second)); len(events) != 0 || s.RSSI != -50-2*DecayRate {
+		t.Errorf("second tick: events = %v, RSSI = %d", kinds(events), s.RSSI)
+	}
+
+	// It bottoms out at MinRSSI
+	for range 20 {
+		s.Tick(now.Add(time.Minute))
+	}
+	if s.RSSI != MinRSSI {
+		t.Errorf("RSSI = %d, want the floor %d", s.RSSI, MinRSSI)
+	}
+
+	// Hearing it again ends the quiet spell, and a later silence is reported again
+	s.Observe([]map[string]interface{}{device(target.Value, "6", "", -45)}, now.Add(2*time.Minute))
+	if s.Quiet || s.RSSI != -45 {
+		t.Errorf("after hearing again: quiet = %v, RSSI = %d", s.Quiet, s.RSSI)
+	}
+	if got := kinds(s.Tick(now.Add(2*time.Minute + Timeout + time.Second))); !slices.Equal(got, []EventKind{Lost}) {
+		t.Errorf("events = %v, want Lost again", got)
+	}
+}
+
+func TestTickBeforeLock(t *testing.T) {
+	target := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	s := New([]*Target{target}, start)
+	s.SelectTarget(target)
+	s.RSSI = -60
+
+	// A target that was never heard can't be lost, but the reading left from before still decays
+	if events := s.Tick(start.Add(time.Minute)); len(events) != 0 {
+		t.Errorf("events = %v, want none before the channel is locked", kinds(events))
+	}
+	if s.RSSI != -60-DecayRate {
+		t.Errorf("RSSI = %d, want %d", s.RSSI, -60-DecayRate)
+	}
+}
+
+func TestLockDwellSwitchesTarget(t *testing.T) {
+	s, first := lockedState(t, -50)
+	second := &Target{Value: "32:34:00:00:00:02", TType: MAC}
+	s.Targets = append(s.Targets, second)
+	s.LockDwell = 30 * time.Second
+	listing := []map[string]interface{}{device(second.Value, "11", "", -70)}
+
+	if events := s.Observe(listing, start.Add(s.LockDwell)); len(events) != 0 {
+		t.Fatalf("events = %v before the dwell ran out", kinds(events))
+	}
+	if s.LockedTarget != first {
+		t.Fatal("target dropped before the dwell ran out")
+	}
+
+	events := s.Observe(listing, start.Add(s.LockDwell+time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Dropped, Found, Heard}) {
+		t.Fatalf("events = %v, want Dropped, Found, Heard", got)
+	}
+	if events[0].Target != first || events[1].Target != second || s.LockedTarget != second {
+		t.Errorf("dropped %v, found %v, locked %v", events[0].Target, events[1].Target, s.LockedTarget)
+	}
+	if s.ChannelLocked || s.Channel != "11" {
+		t.Errorf("ChannelLocked = %v, Channel = %q; want the new target's channel, not yet locked", s.ChannelLocked, s.Channel)
+	}
+	if !first.Peak.At.IsZero() {
+		t.Error("dropped target's peak not cleared")
+	}
+}
+
+func TestFollowChannelMove(t *testing.T) {
+	s, target := lockedState(t, -50)
+	at := start
+	observe := func(channel string) []EventKind {
+		at = at.Add(time.Second)
+		return kinds(s.Observe([]map[string]interface{}{device(target.Value, channel, "", -50)}, at))
+	}
+
+	// A single stray reading, or readings that don't agree, leave the lock where it is
+	for _, channel := range []string{"11", "6", "11", "1", "11", "", "6HT40+"} {
+		if got := observe(channel); !slices.Equal(got, []EventKind{Heard}) {
+			t.Fatalf("reading on %q: events = %v, want just Heard", channel, got)
+		}
+		if s.Channel != "6" {
+			t.Fatalf("reading on %q moved the lock to %s", channel, s.Channel)
+		}
+	}
+
+	// Heard on the new channel twice in a row, it has moved
+	observe("52")
+	events := s.Observe([]map[string]interface{}{device(target.Value, "52", "", -50)}, at.Add(time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Moved, Heard}) {
+		t.Fatalf("events = %v, want Moved then Heard", got)
+	}
+	if events[0].From != "6" || events[0].Target != target || s.Channel != "52" || !s.ChannelLocked {
+		t.Errorf("moved from %q, channel = %q, locked = %v; want 6 to 52, still locked", events[0].From, s.Channel, s.ChannelLocked)
+	}
+	s.Locked(at)
+
+	// Staying on the new channel doesn't move it again, and it can move on from there
+	if got := observe("52"); !slices.Equal(got, []EventKind{Heard}) {
+		t.Errorf("events = %v on the channel it moved to", got)
+	}
+	observe("100")
+	if got := observe("100"); !slices.Equal(got, []EventKind{Moved, Heard}) || s.Channel != "100" {
+		t.Errorf("events = %v, channel = %s; want a move to 100", got, s.Channel)
+	}
+}
+
+func TestDropConflicts(t *testing.T) {
+	s, target := lockedState(t, -50)
+	other := device("10:22:33:44:55:66", "1", "", -80)
+	real, decoy := device(target.Value, "6", "", -50), device(target.Value, "149", "", -71)
+
+	// The decoy's record is dropped and the conflict reported, once
+	devices, events := s.DropConflicts([]map[string]interface{}{other, decoy, real})
+	if len(devices) != 2 || devices[0]["base.macaddr"] != other["base.macaddr"] || devices[1]["base.channel"] != "6" {
+		t.Fatalf("kept %v, want the other device and the record on the locked channel", devices)
+	}
+	if got := kinds(events); !slices.Equal(got, []EventKind{Conflict}) {
+		t.Fatalf("events = %v, want Conflict", got)
+	}
+	if r := events[0].Readings; len(r) != 2 || r[0].Channel != "149" || r[0].RSSI != -71 || r[1].Channel != "6" || events[0].Target != target {
+		t.Errorf("readings = %+v, want both records", r)
+	}
+	if _, events := s.DropConflicts([]map[string]interface{}{decoy, real}); len(events) != 0 {
+		t.Errorf("events = %v, want the conflict reported only when it starts", kinds(events))
+	}
+
+	// Only the real reading reaches Observe, so it doesn't count as a move either
+	for range MoveConfirmations {
+		devices, _ := s.DropConflicts([]map[string]interface{}{decoy, real})
+		if got := kinds(s.Observe(devices, start)); !slices.Equal(got, []EventKind{Heard}) || s.RSSI != -50 || s.Channel != "6" {
+			t.Fatalf("events = %v, RSSI %d on %s; want the locked channel's reading", got, s.RSSI, s.Channel)
+		}
+	}
+
+	// The same channel written two ways, or a record without one, isn't a conflict
+	for _, records := range [][]map[string]interface{}{
+		{real, device(target.Value, "6HT40+", "", -52)},
+		{real, device(target.Value, "", "", -52)},
+	} {
+		if devices, events := s.DropConflicts(records); len(devices) != 2 || len(events) != 0 {
+			t.Errorf("records on %v and %v: kept %d, events %v", records[0]["base.channel"], records[1]["base.channel"], len(devices), kinds(events))
+		}
+	}
+
+	// A new conflict after that one ended is reported again, and with neither record on the locked
+	// channel both are kept
+	elsewhere := []map[string]interface{}{device(target.Value, "11", "", -60), decoy}
+	devices, events = s.DropConflicts(elsewhere)
+	if len(devices) != 2 || !slices.Equal(kinds(events), []EventKind{Conflict}) {
+		t.Errorf("kept %d, events %v; want both kept and the conflict reported", len(devices), kinds(events))
+	}
+
+	// Nothing is checked without a target
+	s.Release()
+	if devices, events := s.DropConflicts([]map[string]interface{}{decoy, real}); len(devices) != 2 || len(events) != 0 {
+		t.Errorf("without a target: kept %d, events %v", len(devices), kinds(events))
+	}
+}
+
+func TestMoveNeedsLock(t *testing.T) {
+	target := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	s := New([]*Target{target}, start)
+
+	// Until the channel is locked the reading's channel is simply taken
+	s.Observe([]map[string]interface{}{device(target.Value, "6", "", -50)}, start)
+	events := s.Observe([]map[string]interface{}{device(target.Value, "11", "", -50)}, start.Add(time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Heard}) || s.Channel != "11" {
+		t.Errorf("events = %v, channel = %s; want 11 without a move", got, s.Channel)
+	}
+
+	// Releasing forgets a move in progress
+	s.Locked(start)
+	s.Observe([]map[string]interface{}{device(target.Value, "1", "", -50)}, start.Add(2*time.Second))
+	s.SelectTarget(target)
+	s.Observe([]map[string]interface{}{device(target.Value, "1", "", -50)}, start.Add(3*time.Second))
+	s.Locked(start.Add(3 * time.Second))
+	events = s.Observe([]map[string]interface{}{device(target.Value, "6", "", -50)}, start.Add(4*time.Second))
+	if got := kinds(events); !slices.Equal(got, []EventKind{Heard}) {
+		t.Errorf("events = %v after one reading on a new channel", got)
+	}
+}
+
+func TestIgnoreMovesOnToNextTarget(t *testing.T) {
+	s, first := lockedState(t, -50)
+	second := &Target{Value: "32:34:00:00:00:02", TType: MAC}
+	s.Targets = append(s.Targets, second)
+	listing := []map[string]interface{}{device(first.Value, "6", "", -40), device(second.Value, "11", "", -70)}
+
+	if !s.ToggleIgnore(first) || !first.IsIgnored() {
+		t.Fatal("target not ignored")
+	}
+	s.Release()
+	if s.LockedTarget != nil || s.Channel != "" || s.ChannelLocked || !s.LockedAt.IsZero() {
+		t.Errorf("state after release = %+v", s)
+	}
+
+	// The ignored target is stronger but discovery passes over it
+	s.Observe(listing, start.Add(time.Second))
+	if s.LockedTarget != second {
+		t.Errorf("locked %v, want the target that isn't ignored", s.LockedTarget)
+	}
+
+	// Picking the ignored target by hand takes it off the list and searches for it
+	events := s.SelectTarget(first)
+	if got := kinds(events); !slices.Equal(got, []EventKind{Unignored}) || first.IsIgnored() {
+		t.Errorf("events = %v, ignored = %v; want Unignored", got, first.IsIgnored())
+	}
+	if s.LockedTarget != first || s.ChannelLocked || s.Multi || !first.Peak.At.IsZero() {
+		t.Errorf("state after select = %+v", s)
+	}
+	if events := s.SelectTarget(second); len(events) != 0 {
+		t.Errorf("events = %v selecting a target that isn't ignored", kinds(events))
+	}
+
+	if s.ToggleIgnore(second) != true || s.ToggleIgnore(second) != false {
+		t.Error("ToggleIgnore doesn't toggle")
+	}
+}
+
+func TestIgnoreAll(t *testing.T) {
+	keep := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	targets := []*Target{keep, {Value: "32:34:00:00:00:02", TType: MAC}, {Value: "x", TType: SSID, Ignored: true}}
+	s := New(targets, start)
+
+	if count := s.IgnoreAllExcept(keep); count != 1 {
+		t.Errorf("IgnoreAllExcept = %d, want 1 newly ignored", count)
+	}
+	if keep.IsIgnored() || !targets[1].IsIgnored() || !targets[2].IsIgnored() {
+		t.Errorf("ignored = %v %v %v", keep.Ignored, targets[1].Ignored, targets[2].Ignored)
+	}
+	if count := s.UnignoreAll(); count != 2 {
+		t.Errorf("UnignoreAll = %d, want 2", count)
+	}
+	for _, target := range targets {
+		if target.IsIgnored() {
+			t.Errorf("%s still ignored", target.Value)
+		}
+	}
+}
+
+func TestUndoIgnore(t *testing.T) {
+	first := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	second := &Target{Value: "32:34:00:00:00:02", TType: MAC}
+	third := &Target{Value: "32:34:00:00:00:03", TType: MAC, Ignored: true}
+	s := New([]*Target{first, second, third}, start)
+
+	if restored := s.UndoIgnore(); restored != nil {
+		t.Fatalf("UndoIgnore = %v with nothing to undo", restored)
+	}
+
+	s.ToggleIgnore(first)
+	s.IgnoreAllExcept(first)
+	s.SelectTarget(third)
+
+	// Each change is undone in turn, most recent first
+	if restored := s.UndoIgnore(); !slices.Equal(restored, []*Target{third}) || !third.IsIgnored() {
+		t.Errorf("undo select: restored %v, third ignored = %v", restored, third.IsIgnored())
+	}
+	if restored := s.UndoIgnore(); !slices.Equal(restored, []*Target{second}) || second.IsIgnored() {
+		t.Errorf("undo ignore all: restored %v, second ignored = %v", restored, second.IsIgnored())
+	}
+	if restored := s.UndoIgnore(); !slices.Equal(restored, []*Target{first}) || first.IsIgnored() {
+		t.Errorf("undo toggle: restored %v, first ignored = %v", restored, first.IsIgnored())
+	}
+	if s.UndoIgnore() != nil {
+		t.Error("history not empty after undoing every change")
+	}
+
+	// A change that touched nothing isn't recorded, and the history only goes back so far
+	s.UnignoreAll()
+	s.UnignoreAll()
+	for range ignoreHistorySize + 5 {
+		s.ToggleIgnore(second)
+	}
+	undone := 0
+	for s.UndoIgnore() != nil {
+		undone++
+	}
+	if undone != ignoreHistorySize {
+		t.Errorf("undid %d changes, want %d", undone, ignoreHistorySize)
+	}
+	if !second.IsIgnored() {
+		t.Error("second not back to its state before the oldest change kept")
+	}
+}
+
+func TestUndoIgnoreSyncedTarget(t *testing.T) {
+	configured := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	locked := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	s := New([]*Target{configured}, start)
+
+	s.ToggleIgnore(locked)
+	if !configured.IsIgnored() {
+		t.Fatal("configured copy not ignored with the locked target")
+	}
+	if restored := s.UndoIgnore(); len(restored) != 2 || locked.IsIgnored() || configured.IsIgnored() {
+		t.Errorf("restored %v; locked ignored = %v, configured ignored = %v", restored, locked.IsIgnored(), configured.IsIgnored())
+	}
+}
+
+func TestMarkStale(t *testing.T) {
+	seen := &Target{Value: "32:34:00:00:00:01", TType: MAC}
+	never := &Target{Value: "32:34:00:00:00:02", TType: MAC}
+	s := New([]*Target{seen, never}, start)
+	s.StaleAfter = time.Minute
+	seen.LastSeen = start
+
+	s.Observe(nil, start)
+	s.Observe(nil, start.Add(time.Minute))
+	if seen.Stale || never.Stale {
+		t.Fatal("marked stale before StaleAfter")
+	}
+
+	// The never-seen target's clock started at the first check
+	s.Observe(nil, start.Add(time.Minute+time.Second))
+	if !seen.Stale || !never.Stale {
+		t.Fatalf("stale = %v %v, want both", seen.Stale, never.Stale)
+	}
+
+	// A stale target isn't found, until it is seen again
+	listing := []map[string]interface{}{device(seen.Value, "1", "", -60)}
+	if events := s.Observe(listing, start.Add(2*time.Minute)); len(events) != 0 {
+		t.Errorf("events = %v for a stale target", kinds(events))
+	}
+	seen.LastSeen = start.Add(2 * time.Minute)
+	events := s.Observe(listing, start.Add(2*time.Minute))
+	if seen.Stale || !slices.Equal(kinds(events), []EventKind{Found, Heard}) {
+		t.Errorf("stale = %v, events = %v after being seen again", seen.Stale, kinds(events))
+	}
+
+	// The locked target is never marked
+	s.Observe(nil, start.Add(time.Hour))
+	if seen.Stale {
+		t.Error("locked target marked stale")
+	}
+}
+
+func TestActiveTagAndMulti(t *testing.T) {
+	home := &Target{Value: "32:34:00:00:00:01", TType: MAC, Tags: []string{"home"}}
+	work := &Target{Value: "32:34:00:00:00:02", TType: MAC, Tags: []string{"work", "laptop"}}
+	s := New([]*Target{home, work}, start)
+	listing := []map[string]interface{}{device(home.Value, "1", "", -40), device(work.Value, "6", "", -70)}
+
+	if tags := s.AllTags(); !slices.Equal(tags, []string{"home", "laptop", "work"}) {
+		t.Errorf("AllTags() = %v", tags)
+	}
+
+	// Nothing is picked while watching every target at once
+	s.Multi = true
+	if events := s.Observe(listing, start); len(events) != 0 {
+		t.Errorf("events = %v in multi-target mode", kinds(events))
+	}
+
+	s.Multi = false
+	s.ActiveTag = "work"
+	if active := s.ActiveTargets(); !slices.Equal(active, []*Target{work}) {
+		t.Errorf("ActiveTargets() = %v", active)
+	}
+	s.Observe(listing, start)
+	if s.LockedTarget != work {
+		t.Errorf("locked %v, want the target in the active group", s.LockedTarget)
+	}
+}
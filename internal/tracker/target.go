@@ -0,0 +1,150 @@
+// Package tracker holds target discovery, channel locking and RSSI tracking, kept apart from the Kismet
+// requests and the UI so it can be driven and tested on its own.
+package tracker
+
+import (
+	"cmp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TargetType int
+
+const (
+	MAC TargetType = iota + 1
+	SSID
+)
+
+type Target struct {
+	Value string
+	TType TargetType
+	// This will store the 'value' when it is an SSID for display. The 'value' will now become a MAC
+	OriginalValue string
+	Ignored       bool
+	Stale         bool      // Not seen for optional.stale_after_minutes; skipped like an ignored target until seen again
+	watchedSince  time.Time // First stale check, standing in for LastSeen until the target is seen
+	Search        bool
+	ChannelLocked bool
+	LastRSSI      int       // Signal from the most recent poll that saw this target
+	LastSeen      time.Time // Zero until the target has been seen
+	Channel       string    // Channel the target was last heard on
+	Channels      []string  // Every channel the target has been heard on, in channel order
+	Label         string    // Optional human-readable name shown in place of the MAC or SSID
+	Tags          []string  // Groups the target belongs to, used to filter the list
+	AlertRSSI     int       // RSSI that fires a webhook rssi_above alert, 0 for the global threshold
+	Peak          SignalPeak
+}
+
+// Strongest signal heard from a target since it was last searched for or released
+type SignalPeak struct {
+	RSSI int
+	At   time.Time // Zero until the target has been heard
+}
+
+// Shows the label when there is one, with the MAC or SSID moved to the description
+func (i Target) Title() string {
+	title := i.typedValue()
+	if i.Label != "" {
+		title = i.Label
+	}
+	if i.Stale {
+		title += " [STALE]"
+	}
+	return title
+}
+
+func (i Target) Description() string {
+	if i.Label != "" {
+		return i.typedValue()
+	}
+	return ""
+}
+
+func (i Target) FilterValue() string { return strings.TrimSpace(i.Value + " " + i.Label) }
+
+// The MAC or SSID prefixed with its type
+func (i Target) typedValue() string {
+	if i.TType == MAC {
+		return "MAC: " + i.Value
+	}
+
+	if i.TType == SSID && i.OriginalValue != "" {
+		return "SSID: " + i.OriginalValue
+	}
+
+	return "SSID: " + i.Value
+}
+
+// The label, MAC, or SSID for SSID targets, used when reporting a target
+func (t *Target) DisplayValue() string {
+	if t.Label != "" {
+		return t.Label
+	}
+	if t.TType == SSID && t.OriginalValue != "" {
+		return t.OriginalValue
+	}
+	return t.Value
+}
+
+// Identifies the target by how it was written in the config, which for an SSID target stays the same
+// after its Value is replaced by the MAC
+func (t *Target) ConfigKey() string {
+	if t.TType == SSID {
+		if t.OriginalValue != "" {
+			return "ssid:" + t.OriginalValue
+		}
+		return "ssid:" + t.Value
+	}
+	return "mac:" + t.Value
+}
+
+// Check if the target is in the given group; every target is in the empty group
+func (t *Target) HasTag(tag string) bool {
+	return tag == "" || slices.Contains(t.Tags, tag)
+}
+
+// Check if the Target is currently being ignored
+func (t *Target) IsIgnored() bool {
+	return t.Ignored
+}
+
+// Replace addToIgnoreList and removeFromIgnoreList with a single toggle function
+func (t *Target) ToggleIgnore() *Target {
+	t.Ignored = !t.Ignored
+	return t
+}
+
+// Record a signal reading for the target
+func (t *Target) UpdateSignal(rssi int) {
+	t.LastRSSI = rssi
+	t.LastSeen = time.Now()
+	if t.Peak.At.IsZero() || rssi > t.Peak.RSSI {
+		t.Peak = SignalPeak{RSSI: rssi, At: t.LastSeen}
+	}
+}
+
+// Add a channel the target was heard on to the ones it has been seen on
+func (t *Target) ObserveChannel(channel string) {
+	if channel == "" || slices.Contains(t.Channels, channel) {
+		return
+	}
+	t.Channels = append(t.Channels, channel)
+	slices.SortFunc(t.Channels, CompareChannels)
+}
+
+// Order channels numerically, with any that aren't plain numbers after them by name
+func CompareChannels(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return cmp.Compare(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
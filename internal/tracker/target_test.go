@@ -0,0 +1,97 @@
+package tracker
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestCompareChannels(t *testing.T) {
+	channels := []string{"149", "6", "11", "6HT40", "1", "36"}
+	slices.SortFunc(channels, CompareChannels)
+
+	want := []string{"1", "6", "11", "36", "149", "6HT40"}
+	if !slices.Equal(channels, want) {
+		t.Errorf("sorted channels = %v, want %v", channels, want)
+	}
+}
+
+func TestObserveChannel(t *testing.T) {
+	target := &Target{Value: "10:22:33:44:55:66", TType: MAC}
+	for _, channel := range []string{"11", "", "6", "11", "149"} {
+		target.ObserveChannel(channel)
+	}
+
+	want := []string{"6", "11", "149"}
+	if !slices.Equal(target.Channels, want) {
+		t.Errorf("Channels = %v, want %v", target.Channels, want)
+	}
+}
+
+func TestUpdateSignalKeepsPeak(t *testing.T) {
+	target := &Target{Value: "10:22:33:44:55:66", TType: MAC}
+	for _, rssi := range []int{-70, -48, -60} {
+		target.UpdateSignal(rssi)
+	}
+
+	if target.LastRSSI != -60 {
+		t.Errorf("LastRSSI = %d, want -60", target.LastRSSI)
+	}
+	if target.Peak.RSSI != -48 || target.Peak.At.IsZero() {
+		t.Errorf("Peak = %+v, want -48 dBm with a time", target.Peak)
+	}
+	if target.LastSeen.IsZero() {
+		t.Error("LastSeen not set")
+	}
+}
+
+func TestTargetNames(t *testing.T) {
+	tests := []struct {
+		target      Target
+		title       string
+		description string
+		display     string
+		configKey   string
+	}{
+		{
+			target:    Target{Value: "10:22:33:44:55:66", TType: MAC},
+			title:     "MAC: 10:22:33:44:55:66",
+			display:   "10:22:33:44:55:66",
+			configKey: "mac:10:22:33:44:55:66",
+		},
+		{
+			target:      Target{Value: "10:22:33:44:55:66", TType: MAC, Label: "phone", Stale: true},
+			title:       "phone [STALE]",
+			description: "MAC: 10:22:33:44:55:66",
+			display:     "phone",
+			configKey:   "mac:10:22:33:44:55:66",
+		},
+		{
+			target:    Target{Value: "CoffeeShop", TType: SSID},
+			title:     "SSID: CoffeeShop",
+			display:   "CoffeeShop",
+			configKey: "ssid:CoffeeShop",
+		},
+		{
+			// A resolved SSID target keeps being named and keyed by its SSID
+			target:    Target{Value: "10:22:33:44:55:66", TType: SSID, OriginalValue: "CoffeeShop"},
+			title:     "SSID: CoffeeShop",
+			display:   "CoffeeShop",
+			configKey: "ssid:CoffeeShop",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.target.Title(); got != tt.title {
+			t.Errorf("Title() = %q, want %q", got, tt.title)
+		}
+		if got := tt.target.Description(); got != tt.description {
+			t.Errorf("Description() = %q, want %q", got, tt.description)
+		}
+		if got := tt.target.DisplayValue(); got != tt.display {
+			t.Errorf("DisplayValue() = %q, want %q", got, tt.display)
+		}
+		if got := tt.target.ConfigKey(); got != tt.configKey {
+			t.Errorf("ConfigKey() = %q, want %q", got, tt.configKey)
+		}
+	}
+}
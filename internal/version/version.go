@@ -0,0 +1,95 @@
+// Package version reports which build of rizzyscope is running, from values set at build time or what the
+// Go toolchain recorded in the binary. It's worked out once, the first time it's asked for.
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version.version=v1.2.0 \
+//	  -X github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version.commit=$(git rev-parse --short HEAD) \
+//	  -X github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Anything left unset falls back to what the Go toolchain recorded in the binary, then to "devel"/"unknown".
+var (
+	version   = ""
+	commit    = ""
+	buildDate = ""
+)
+
+// Where the toolchain's build info comes from, replaced in tests
+var readBuildInfo = debug.ReadBuildInfo
+
+// Matches Go pseudo-versions (v0.0.0-20240501120000-abcdef123456) and builds from a modified checkout
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}|\+dirty`)
+
+// The build's version, commit and date with the fallbacks filled in
+type build struct {
+	version, commit, date string
+}
+
+var current = sync.OnceValue(buildVersion)
+
+func buildVersion() build {
+	b := build{version, commit, buildDate}
+
+	if info, ok := readBuildInfo(); ok {
+		// go install module@version records the tag; a build in a checkout gets a pseudo-version, which
+		// the commit already covers
+		if b.version == "" && info.Main.Version != "(devel)" && !pseudoVersion.MatchString(info.Main.Version) {
+			b.version = info.Main.Version
+		}
+		var fromVCS, modified bool
+		for _, setting := range info.Settings {
+			switch {
+			case setting.Key == "vcs.revision" && b.commit == "":
+				b.commit, fromVCS = setting.Value, true
+				if len(b.commit) > 12 {
+					b.commit = b.commit[:12]
+				}
+			case setting.Key == "vcs.modified":
+				modified = setting.Value == "true"
+			case setting.Key == "vcs.time" && b.date == "":
+				b.date = setting.Value
+			}
+		}
+		// A build from a checkout with uncommitted changes isn't quite that commit. One set with -ldflags
+		// is taken as is.
+		if fromVCS && modified {
+			b.commit += "-dirty"
+		}
+	}
+
+	if b.version == "" {
+		b.version = "devel"
+	}
+	if b.commit == "" {
+		b.commit = "unknown"
+	}
+	if b.date == "" {
+		b.date = "unknown"
+	}
+	return b
+}
+
+// The full version line printed by --version
+func String() string {
+	b := current()
+	return fmt.Sprintf("rizzyscope %s (commit %s, built %s, %s %s/%s)", b.version, b.commit, b.date, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
+
+// The version alone, as shown in the TUI
+func Short() string {
+	return current().version
+}
+
+// Sent with every Kismet API request so they can be picked out of Kismet's access log
+func UserAgent() string {
+	return "rizzyscope/" + Short()
+}
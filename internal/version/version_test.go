@@ -0,0 +1,81 @@
+package version
+
+import (
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Replace the ldflags values and the toolchain's build info for the rest of the test
+func fakeBuild(t *testing.T, ldVersion, ldCommit, ldDate string, info *debug.BuildInfo) {
+	t.Helper()
+	savedVersion, savedCommit, savedDate, savedRead, savedCurrent := version, commit, buildDate, readBuildInfo, current
+	t.Cleanup(func() {
+		version, commit, buildDate, readBuildInfo, current = savedVersion, savedCommit, savedDate, savedRead, savedCurrent
+	})
+	version, commit, buildDate = ldVersion, ldCommit, ldDate
+	readBuildInfo = func() (*debug.BuildInfo, bool) { return info, info != nil }
+	current = sync.OnceValue(buildVersion)
+}
+
+func vcsBuild(mainVersion string, settings ...debug.BuildSetting) *debug.BuildInfo {
+	return &debug.BuildInfo{Main: debug.Module{Path: "github.com/GobiasSomeCoffeeCo/rizzyscope", Version: mainVersion}, Settings: settings}
+}
+
+func TestBuildVersion(t *testing.T) {
+	revision := debug.BuildSetting{Key: "vcs.revision", Value: "f807185932f0a1b2c3d4e5f60718293a4b5c6d7e"}
+	vcsTime := debug.BuildSetting{Key: "vcs.time", Value: "2026-10-01T12:00:00Z"}
+	modified := debug.BuildSetting{Key: "vcs.modified", Value: "true"}
+	clean := debug.BuildSetting{Key: "vcs.modified", Value: "false"}
+
+	for _, tt := range []struct {
+		name                           string
+		ldVersion, ldCommit, ldDate    string
+		info                           *debug.BuildInfo
+		wantVersion, wantRev, wantDate string
+	}{
+		{"ldflags win", "v1.2.0", "abc1234", "2026-10-15T00:00:00Z", vcsBuild("(devel)", revision, vcsTime, modified),
+			"v1.2.0", "abc1234", "2026-10-15T00:00:00Z"},
+		{"go install of a tag", "", "", "", vcsBuild("v1.1.0"), "v1.1.0", "unknown", "unknown"},
+		{"checkout build", "", "", "", vcsBuild("(devel)", revision, vcsTime, clean), "devel", "f807185932f0", "2026-10-01T12:00:00Z"},
+		{"pseudo-version", "", "", "", vcsBuild("v0.0.0-20261001120000-f807185932f0", revision, clean), "devel", "f807185932f0", "unknown"},
+		{"dirty checkout", "", "", "", vcsBuild("v0.0.0-20261001120000-f807185932f0+dirty", revision, vcsTime, modified),
+			"devel", "f807185932f0-dirty", "2026-10-01T12:00:00Z"},
+		{"no build info", "", "", "", nil, "devel", "unknown", "unknown"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeBuild(t, tt.ldVersion, tt.ldCommit, tt.ldDate, tt.info)
+			b := buildVersion()
+			if b.version != tt.wantVersion || b.commit != tt.wantRev || b.date != tt.wantDate {
+				t.Errorf("buildVersion() = %q, %q, %q; want %q, %q, %q", b.version, b.commit, b.date, tt.wantVersion, tt.wantRev, tt.wantDate)
+			}
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	fakeBuild(t, "v1.2.0", "", "", nil)
+	if got := UserAgent(); got != "rizzyscope/v1.2.0" {
+		t.Errorf("UserAgent() = %q", got)
+	}
+}
+
+// The build info is read and parsed once, not on every frame or Kismet request
+func TestVersionComputedOnce(t *testing.T) {
+	reads := 0
+	fakeBuild(t, "", "", "", nil)
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		reads++
+		return vcsBuild("v1.1.0"), true
+	}
+
+	for range 3 {
+		if Short() != "v1.1.0" || UserAgent() != "rizzyscope/v1.1.0" || !strings.HasPrefix(String(), "rizzyscope v1.1.0 (commit unknown") {
+			t.Fatalf("Short() = %q, UserAgent() = %q, String() = %q", Short(), UserAgent(), String())
+		}
+	}
+	if reads != 1 {
+		t.Errorf("build info read %d times, want once", reads)
+	}
+}
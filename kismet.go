@@ -6,241 +6,221 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/exec"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
 	"github.com/spf13/viper"
 )
 
 const (
-	MinRSSI = -120 // Minimum RSSI value for progress bar
-	MaxRSSI = -20  // Maximum RSSI value for progress bar
+	MinRSSI = tracker.MinRSSI // Minimum RSSI value for progress bar
+	MaxRSSI = -20             // Maximum RSSI value for progress bar
 
 )
 
 var (
-	cachedUser        string
-	cachedPassword    string
-	credentialsErr    error
-	once              sync.Once                        // Ensures credentials are fetched only once
-	errDeviceNotFound = errors.New("device not found") // Error to match on
+	cachedUser     string
+	cachedPassword string
+	credentialsErr error
+	once           sync.Once // Ensures credentials are fetched only once
+	stdinPassword  string    // Password read by --password-stdin, overrides the config
 )
 
-type DeviceInfo struct {
-	RSSI              int               // Signal strength
-	Channel           string            // Operating channel
-	Manufacturer      string            // Manufacturer of the device
-	SSID              string            // SSID of the device (if applicable)
-	Crypt             string            // Encryption type
-	Type              string            // Device type (AP, Client, etc.)
-	AssociatedClients map[string]string // Map of associated client MAC addresses
-}
-
 // API response structure
 type KismetPayload struct {
 	Fields [][]string `json:"fields"`
 }
 
-// Function to fetch device info from the Kismet API and returns a *DeviceInfo object
-func FetchDeviceInfo(mac string, kismetEndpoint string) (*DeviceInfo, error) {
-	postJson := KismetPayload{
-		Fields: [][]string{
-			{"kismet.device.base.macaddr", "base.macaddr"},
-			{"kismet.device.base.channel", "base.channel"},
-			{"kismet.device.base.signal/kismet.common.signal.last_signal", "RSSI"},
-			{"kismet.device.base.manuf", "Make"},
-			{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.ssid", "SSID"},
-			{"kismet.device.base.crypt", "Crypt"},
-			{"kismet.device.base.type", "Type"},
-			{"dot11.device/dot11.device.associated_client_map", "AssociatedClients"},
-		},
-	}
-
-	jsonData, err := json.Marshal(postJson)
-	if err != nil {
-		log.Printf("Error marshaling JSON: %v", err)
-		return nil, err
-	}
+// Fields requested for each device in the per-poll device listing, aliased to flat keys. This is the
+// superset of what discovery, the locked target's details and the exports need, so one request serves them all.
+var deviceFields = [][]string{
+	{"kismet.device.base.macaddr", "base.macaddr"},
+	{"kismet.device.base.channel", "base.channel"},
+	{"kismet.device.base.first_time", "base.first_time"},
+	{"kismet.device.base.signal/kismet.common.signal.last_signal", "RSSI"},
+	{"kismet.device.base.manuf", "Make"},
+	{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.ssid", "SSID"},
+	{"kismet.device.base.crypt", "Crypt"},
+	{"kismet.device.base.type", "Type"},
+	{"dot11.device/dot11.device.associated_client_map", "AssociatedClients"},
+	{"kismet.device.base.packets.total", "Packets"},
+	{"kismet.device.base.packets.data", "DataPackets"},
+	{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.wps_state", "WPSState"},
+	{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.beaconrate", "BeaconRate"},
+	{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.ht_mode", "HTMode"},
+	{"dot11.device/dot11.device.wpa_present_handshake", "Handshake"},
+}
 
-	kismetEndpoint = fmt.Sprintf("http://%s/devices/last-time/-5/devices.json", kismetEndpoint)
+// Function to lazily pull credentials and store them in global variables so we're not unnecessarily pulling them for every api query.
+func getCachedCredentials() (string, string, error) {
+	once.Do(func() {
+		cachedUser, cachedPassword, credentialsErr = getCredentials()
+	})
+	return cachedUser, cachedPassword, credentialsErr
+}
 
-	req, err := CreateRequest("POST", kismetEndpoint, bytes.NewBuffer(jsonData))
-	if err != nil {
-		log.Printf("Error creating request: %v", err)
-		return nil, err
+// Function to get credentials from configuration. Values set directly (credentials.user/password or their
+// environment variables) take precedence over the secrets file in credentials.file, and --password-stdin
+// overrides any password.
+func getCredentials() (string, string, error) {
+	user := viper.GetString("credentials.user")
+	password := viper.GetString("credentials.password")
+
+	if path := viper.GetString("credentials.file"); path != "" && (user == "" || password == "") {
+		fileUser, filePassword, err := readCredentialsFile(path)
+		if err != nil {
+			return "", "", err
+		}
+		if user == "" {
+			user = fileUser
+		}
+		if password == "" {
+			password = filePassword
+		}
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		// Log the error but do not return it to the user
-		log.Printf("Error making request to Kismet API: %v", err)
-		return nil, nil // Return nil to indicate no data but no critical error
+	if stdinPassword != "" {
+		password = stdinPassword
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		var devices []map[string]interface{}
-		if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
-			log.Printf("Error decoding response: %v", err)
-			return nil, err
-		}
+	if user == "" || password == "" {
+		return "", "", fmt.Errorf("user or password not provided in the configuration")
+	}
 
-		for _, device := range devices {
-			// Check if the MAC address matches
-			if macAddr, ok := device["base.macaddr"].(string); ok && macAddr == mac {
-				deviceInfo := &DeviceInfo{
-					RSSI:              MinRSSI, // Default RSSI value
-					Channel:           "",
-					Manufacturer:      "Unknown",
-					SSID:              "Unknown",
-					Crypt:             "Unknown",
-					Type:              "Unknown",
-					AssociatedClients: map[string]string{},
-				}
+	return user, password, nil
+}
 
-				// Extract fields
-				if rssiVal, ok := device["RSSI"].(float64); ok {
-					deviceInfo.RSSI = int(rssiVal)
-				}
-				if channelVal, ok := device["base.channel"].(string); ok {
-					deviceInfo.Channel = channelVal
-				}
-				if makeVal, ok := device["Make"].(string); ok {
-					deviceInfo.Manufacturer = makeVal
-				}
-				if ssidVal, ok := device["SSID"].(string); ok {
-					deviceInfo.SSID = ssidVal
-				}
-				if cryptVal, ok := device["Crypt"].(string); ok {
-					deviceInfo.Crypt = cryptVal
-				}
-				if typeVal, ok := device["Type"].(string); ok {
-					deviceInfo.Type = typeVal
-				}
-				// Extract associated clients (if any)
-				if associatedClientsVal, ok := device["AssociatedClients"].(map[string]interface{}); ok {
-					for clientMac, assoc := range associatedClientsVal {
-						deviceInfo.AssociatedClients[clientMac] = fmt.Sprintf("%v", assoc)
-					}
-				}
+// Function to read credentials from a secrets file, either JSON ({"user": ..., "password": ...})
+// or key=value lines with # comments
+func readCredentialsFile(path string) (string, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("error reading credentials file: %v", err)
+	}
 
-				return deviceInfo, nil
-			}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		var creds struct {
+			User     string `json:"user"`
+			Password string `json:"password"`
+		}
+		if err := json.Unmarshal(trimmed, &creds); err != nil {
+			return "", "", fmt.Errorf("error parsing credentials file: %v", err)
 		}
+		return creds.User, creds.Password, nil
 	}
 
-	return nil, errDeviceNotFound
-}
-
-// Finds a valid MAC or SSID and returns a MAC, channel, *TargetItem, error
-func FindValidTarget(targets []*TargetItem, kismetEndpoint string) (string, string, *TargetItem, error) {
-	// Prepare the payload for Kismet API request
-	postJson := KismetPayload{
-		Fields: [][]string{
-			{"kismet.device.base.macaddr", "base.macaddr"},
-			{"kismet.device.base.channel", "base.channel"},
-			{"dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.ssid", "SSID"},
-		},
+	var user, password string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return "", "", fmt.Errorf("error parsing credentials file: expected key=value, got %q", line)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "user":
+			user = value
+		case "password":
+			password = value
+		}
 	}
+	return user, password, nil
+}
 
-	// Marshal the payload to JSON
-	jsonData, err := json.Marshal(postJson)
+// Whether a Kismet server answers at endpoint. Any HTTP response counts, since the request carries no
+// credentials.
+func kismetRunning(endpoint string) bool {
+	client := &http.Client{Timeout: time.Second}
+	resp, err := client.Get(fmt.Sprintf("http://%s/system/timestamp.json", endpoint))
 	if err != nil {
-		return "", "", nil, fmt.Errorf("error marshaling JSON: %v", err)
+		return false
 	}
+	resp.Body.Close()
+	return true
+}
 
-	kismetEndpoint = fmt.Sprintf("http://%s/devices/last-time/-5/devices.json", kismetEndpoint)
+// Returned by lockChannel when there is no channel to lock to, instead of sending Kismet an empty one
+var errNoChannel = errors.New("no channel to lock to")
 
-	// Create the HTTP POST request
-	req, err := CreateRequest("POST", kismetEndpoint, bytes.NewBuffer(jsonData))
+// Returned when Kismet turns down the configured login
+var errCredentialsRejected = errors.New("kismet rejected the credentials")
+
+// Fetches every datasource Kismet has, each a map of its fields
+func FetchDatasources(kismetEndpoint string) ([]map[string]interface{}, error) {
+	kismetEndpoint = fmt.Sprintf("http://%s/datasource/all_sources.json", kismetEndpoint)
+	req, err := CreateRequest("GET", kismetEndpoint, nil)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("error creating request: %v", err)
+		return nil, err
 	}
 
-	// Send the request
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := doRequest(client, req)
 	if err != nil {
-		return "", "", nil, fmt.Errorf("error making request to Kismet API: %v", err)
+		slog.Error("Error getting data sources", "err", err)
+		return nil, fmt.Errorf("failed to get data sources: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Check the response status code
-	if resp.StatusCode != http.StatusOK {
-		return "", "", nil, fmt.Errorf("kismet API returned status code %d", resp.StatusCode)
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errCredentialsRejected
 	}
-
-	// Decode the response body
-	var devices []map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
-		return "", "", nil, fmt.Errorf("error decoding response: %v", err)
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		slog.Error("Failed to get data sources", "body", string(body))
+		return nil, fmt.Errorf("failed to get data sources: %s", string(body))
 	}
 
-	// Iterate over targets
-	for _, target := range targets {
-		if target.IsIgnored() {
-			continue
-		}
-
-		// Iterate over devices
-		for _, device := range devices {
-			// Extract device fields
-			deviceMac, _ := device["base.macaddr"].(string)
-			deviceChannel, _ := device["base.channel"].(string)
-			// deviceSSID, _ := device["SSID"].(string)
+	body, _ := io.ReadAll(resp.Body)
 
-			if target.TType == MAC {
-				if deviceMac == target.Value {
-					return target.Value, deviceChannel, target, nil
-				}
-			} else if target.TType == SSID {
-				if ssidVal, ok := device["SSID"].(string); ok && ssidVal == target.Value {
-					macAddr, _ := device["base.macaddr"].(string)
-					channel, ok := device["base.channel"].(string)
-					if ok {
-						newTarget := target                    // Create a copy of the target
-						newTarget.OriginalValue = target.Value // Store the original SSID
-						newTarget.TType = SSID
-						newTarget.Value = macAddr // Set the value to the MAC address
-						return macAddr, channel, newTarget, nil
-					}
-				}
-			}
-		}
+	var sources []map[string]interface{}
+	if err := json.Unmarshal(body, &sources); err != nil {
+		slog.Error("Error decoding JSON", "err", err)
+		return nil, fmt.Errorf("failed to decode JSON: %v", err)
 	}
-
-	// No valid target found
-	return "", "", nil, nil
+	return sources, nil
 }
 
-// Function to lazily pull credentials and store them in global variables so we're not unnecessarily pulling them for every api query.
-func getCachedCredentials() (string, string, error) {
-	once.Do(func() {
-		cachedUser, cachedPassword, credentialsErr = getCredentials()
-	})
-	return cachedUser, cachedPassword, credentialsErr
+// Launching Kismet on the capture interfaces needs root; explain the alternatives if we don't have it
+func checkCanLaunchKismet(euid int, launch bool) error {
+	if !launch || euid == 0 {
+		return nil
+	}
+	return errors.New("launching Kismet needs root. Either run rizzyscope as root (sudo ./rizzyscope), " +
+		"or start Kismet separately (e.g. as a systemd service) and run rizzyscope with --skip-kismet as any user")
 }
 
-// Function to get credentials from configuration
-func getCredentials() (string, string, error) {
-	user := viper.GetString("credentials.user")
-	password := viper.GetString("credentials.password")
+// Launch Kismet on the given interfaces unless skipped, first checking they can do monitor mode
+func startKismet(skip, skipCapabilityCheck bool, ifaces []string) (*exec.Cmd, error) {
+	if skip {
+		return nil, nil
+	}
 
-	if user == "" || password == "" {
-		return "", "", fmt.Errorf("user or password not provided in the configuration")
+	if !skipCapabilityCheck {
+		if err := checkMonitorCapability(ifaces); err != nil {
+			return nil, err
+		}
 	}
 
-	return user, password, nil
+	kismet, err := LaunchKismet(ifaces)
+	if err != nil {
+		return nil, errors.New("Kismet couldn't launch. Please ensure Kimset is installed and in your $PATH.")
+	}
+	return kismet, nil
 }
 
 // Launch Kismet automatically without user interaction
 func LaunchKismet(ifaces []string) (*exec.Cmd, error) {
-	log.Println("Launching Kismet...")
+	slog.Info("Launching Kismet...")
 
 	// Initialize the arguments with kismet command
 	args := []string{}
@@ -261,11 +241,21 @@ func LaunchKismet(ifaces []string) (*exec.Cmd, error) {
 		return cmd, fmt.Errorf("failed to start Kismet: %v", err)
 	}
 
-	log.Println("Kismet launched successfully")
+	slog.Info("Kismet launched successfully")
 
 	return cmd, nil
 }
 
+// Kill the Kismet process if we launched one
+func stopKismet(kismet *exec.Cmd) {
+	if kismet != nil {
+		err := kismet.Process.Kill()
+		if err != nil {
+			slog.Error("Unable to kill Kismet process. Please check if Kismet is still running.", "err", err)
+		}
+	}
+}
+
 // Function to create an HTTP request with credentials
 func CreateRequest(method, url string, body io.Reader) (*http.Request, error) {
 	user, password, err := getCachedCredentials()
@@ -287,46 +277,66 @@ func CreateRequest(method, url string, body io.Reader) (*http.Request, error) {
 	return req, nil
 }
 
-// Function to get UUID for a specific interface
+// The interface isn't one of Kismet's datasources, e.g. a typo in the config or Kismet still opening it
+type interfaceNotFoundError struct {
+	iface   string
+	sources []string // Interfaces Kismet does have
+}
+
+func (e *interfaceNotFoundError) Error() string {
+	if len(e.sources) == 0 {
+		return fmt.Sprintf("interface %s is not a Kismet datasource, and Kismet has none", e.iface)
+	}
+	return fmt.Sprintf("interface %s is not a Kismet datasource (Kismet has %s)", e.iface, strings.Join(e.sources, ", "))
+}
+
+// Function to get UUID for a specific interface. Any Kismet source options after the name
+// (wlan0:channels="1,6") are ignored.
 func GetUUIDForInterface(interfaceName string, kismetEndpoint string) (string, error) {
-	kismetEndpoint = fmt.Sprintf("http://%s/datasource/all_sources.json", kismetEndpoint)
-	req, err := CreateRequest("GET", kismetEndpoint, nil)
+	source, err := findDatasource(interfaceName, kismetEndpoint)
 	if err != nil {
 		return "", err
 	}
+	uuid, _ := source["kismet.datasource.uuid"].(string)
+	return uuid, nil
+}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
+// The Kismet datasource capturing on interfaceName, which may carry source options after a colon
+func findDatasource(interfaceName string, kismetEndpoint string) (map[string]interface{}, error) {
+	sources, err := FetchDatasources(kismetEndpoint)
 	if err != nil {
-		log.Printf("Error getting data sources: %v", err)
-		return "", fmt.Errorf("failed to get data sources: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Failed to get data sources: %s", string(body))
-		os.Exit(1)
-		return "", fmt.Errorf("failed to get data sources: %s", string(body))
-	}
-
-	body, _ := io.ReadAll(resp.Body)
-
-	var sources []map[string]interface{}
-	if err := json.Unmarshal(body, &sources); err != nil {
-		log.Printf("Error decoding JSON: %v", err)
-		return "", fmt.Errorf("failed to decode JSON: %v", err)
+		return nil, err
 	}
 
+	interfaceName, _, _ = strings.Cut(interfaceName, ":")
+	notFound := &interfaceNotFoundError{iface: interfaceName}
 	for _, source := range sources {
-		if source["kismet.datasource.interface"] == interfaceName {
-			if uuid, ok := source["kismet.datasource.uuid"].(string); ok {
-				return uuid, nil
+		name, _ := source["kismet.datasource.interface"].(string)
+		if name == interfaceName {
+			if _, ok := source["kismet.datasource.uuid"].(string); ok {
+				return source, nil
 			}
 		}
+		notFound.sources = append(notFound.sources, name)
 	}
 
-	return "", fmt.Errorf("UUID not found for interface %s", interfaceName)
+	return nil, notFound
+}
+
+// Whether a datasource lists its channels as frequencies in MHz, as some SDR-backed sources do, rather
+// than channel numbers
+func frequencyChannels(source map[string]interface{}) bool {
+	channels, _ := source["kismet.datasource.channels"].([]interface{})
+	if len(channels) == 0 {
+		return false
+	}
+	for _, channel := range channels {
+		name, _ := channel.(string)
+		if mhz, err := strconv.Atoi(strings.TrimSpace(name)); err != nil || mhz < 2400 {
+			return false
+		}
+	}
+	return true
 }
 
 func hopChannel(uuid string, kismetEndpoint string) error {
@@ -334,21 +344,21 @@ func hopChannel(uuid string, kismetEndpoint string) error {
 
 	req, err := CreateRequest("POST", kismetEndpoint, nil)
 	if err != nil {
-		log.Printf("Failed to create request: %v", err)
+		slog.Error("Failed to create request", "err", err)
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
-		log.Printf("Failed to send request: %v", err)
+		slog.Error("Failed to send request", "err", err)
 		return fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Failed to unlock channel: %s", string(body))
+		slog.Error("Failed to unlock channel", "body", string(body))
 		return fmt.Errorf("failed to unlock channel: %s", string(body))
 	}
 
@@ -357,53 +367,95 @@ func hopChannel(uuid string, kismetEndpoint string) error {
 
 // Function to lock the channel for a specific interface UUID
 func lockChannel(uuid, channel, kismetEndpoint string) error {
+	if channel == "" {
+		return errNoChannel
+	}
 	kismetEndpoint = fmt.Sprintf("http://%s/datasource/by-uuid/%s/set_channel.cmd", kismetEndpoint, uuid)
 
 	payload := map[string]string{"channel": channel}
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		log.Printf("Failed to marshal JSON: %v", err)
+		slog.Error("Failed to marshal JSON", "err", err)
 		return fmt.Errorf("failed to marshal JSON: %v", err)
 	}
 
 	req, err := CreateRequest("POST", kismetEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Failed to create request: %v", err)
+		slog.Error("Failed to create request", "err", err)
 		return fmt.Errorf("failed to create request: %v", err)
 	}
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
-		log.Printf("Failed to send request: %v", err)
+		slog.Error("Failed to send request", "err", err)
 		return fmt.Errorf("failed to send request: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("Failed to lock channel: %s", string(body))
+		slog.Error("Failed to lock channel", "body", string(body))
 		return fmt.Errorf("failed to lock channel: %s", string(body))
 	}
 
 	return nil
 }
 
-// Fetches all device data from the Kismet API
+// Record the last signal of every target seen in a device listing from FetchAllDevices, returning a
+// sample for each
+func updateTargetSignals(targets []*TargetItem, devices []map[string]interface{}) []sample {
+	var samples []sample
+	now := time.Now()
+
+	for _, device := range devices {
+		mac, _ := device["base.macaddr"].(string)
+		channel := tracker.DeviceChannel(device)
+		ssid, _ := device["SSID"].(string)
+
+		rssi, ok := device["RSSI"].(float64)
+		if !ok {
+			continue
+		}
+
+		for _, target := range targets {
+			// Once an SSID target has been resolved its Value holds the MAC
+			resolved := target.TType == MAC || target.OriginalValue != ""
+			if (resolved && target.Value == mac) || (!resolved && ssid != "" && target.Value == ssid) {
+				target.UpdateSignal(int(rssi))
+				if channel != "" {
+					target.Channel = channel
+					target.ObserveChannel(channel)
+				}
+				samples = append(samples, sample{time: now, target: target, mac: mac, ssid: ssid, channel: channel, rssi: int(rssi)})
+			}
+		}
+	}
+
+	return samples
+}
+
+// Fetches every device heard in the last five seconds from the Kismet API, simplified to deviceFields.
+// This is the one device request made per poll.
 func FetchAllDevices(kismetEndpoint string) ([]map[string]interface{}, error) {
+	jsonData, err := json.Marshal(KismetPayload{Fields: deviceFields})
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling JSON: %v", err)
+	}
+
 	kismetEndpoint = fmt.Sprintf("http://%s/devices/last-time/-5/devices.json", kismetEndpoint)
 
 	// Use CreateRequest instead of http.NewRequest to include authentication
-	req, err := CreateRequest("GET", kismetEndpoint, nil)
+	req, err := CreateRequest("POST", kismetEndpoint, bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("Error creating request: %v", err)
+		slog.Error("Error creating request", "err", err)
 		return nil, err
 	}
 
 	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := doRequest(client, req)
 	if err != nil {
-		log.Printf("Error making request to Kismet API: %v", err)
+		slog.Error("Error making request to Kismet API", "err", err)
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -416,9 +468,107 @@ func FetchAllDevices(kismetEndpoint string) ([]map[string]interface{}, error) {
 
 	var devices []map[string]interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&devices); err != nil {
-		log.Printf("Error decoding response: %v", err)
+		slog.Error("Error decoding response", "err", err)
 		return nil, err
 	}
 
 	return devices, nil
 }
+
+// What Kismet reports about itself, read once at startup so problems with the fields it returns can be
+// matched to its release
+type kismetStatus struct {
+	Version string // e.g. "2023-07-R1"
+	Build   string // e.g. "git 1f6e0a3" or "built Jul 12 2023", "" if Kismet doesn't say
+	Sources int    // Datasources that are running
+}
+
+// Fetches Kismet's version and build from the Kismet API, and counts its running datasources. The status
+// fields differ between Kismet releases, so any that are missing are left empty rather than failing.
+func FetchKismetStatus(kismetEndpoint string) (*kismetStatus, error) {
+	req, err := CreateRequest("GET", fmt.Sprintf("http://%s/system/status.json", kismetEndpoint), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, errCredentialsRejected
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kismet API returned status code %d", resp.StatusCode)
+	}
+
+	var fields map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&fields); err != nil {
+		return nil, fmt.Errorf("error decoding the status: %v", err)
+	}
+	status := &kismetStatus{Version: "(unknown version)"}
+	if version, _ := fields["kismet.system.version"].(string); version != "" {
+		status.Version = version
+	}
+	if git, _ := fields["kismet.system.git"].(string); git != "" {
+		status.Build = "git " + git[:min(len(git), 7)]
+	} else if built, _ := fields["kismet.system.build_time"].(string); built != "" {
+		status.Build = "built " + built
+	}
+
+	sources, err := FetchDatasources(kismetEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	for _, source := range sources {
+		if running, _ := source["kismet.datasource.running"].(bool); running {
+			status.Sources++
+		}
+	}
+	return status, nil
+}
+
+// A position from Kismet's GPS
+type gpsFix struct {
+	lat float64
+	lon float64
+	alt float64
+}
+
+// Fetches the current GPS position from the Kismet API, or nil if Kismet has no 2D/3D fix
+func FetchGPSLocation(kismetEndpoint string) (*gpsFix, error) {
+	kismetEndpoint = fmt.Sprintf("http://%s/gps/location.json", kismetEndpoint)
+
+	req, err := CreateRequest("GET", kismetEndpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: 2 * time.Second}
+	resp, err := doRequest(client, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("kismet API returned status code %d", resp.StatusCode)
+	}
+
+	var location struct {
+		Fix      int       `json:"kismet.common.location.fix"`
+		GeoPoint []float64 `json:"kismet.common.location.geopoint"` // [lon, lat]
+		Alt      float64   `json:"kismet.common.location.alt"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&location); err != nil {
+		return nil, err
+	}
+
+	if location.Fix < 2 || len(location.GeoPoint) != 2 {
+		return nil, nil
+	}
+	return &gpsFix{lat: location.GeoPoint[1], lon: location.GeoPoint[0], alt: location.Alt}, nil
+}
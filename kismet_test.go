@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+	"github.com/spf13/viper"
+)
+
+const (
+	testUser     = "kismet"
+	testPassword = "hunter2"
+	testUUID     = "5FE308BD-0000-0000-0000-00C0CAB4E1B6"
+)
+
+// Start a fake Kismet with a wlan0 datasource and point the client's credentials at it for the test
+func fakeKismet(t *testing.T) *testkismet.Server {
+	t.Helper()
+	server := testkismet.New(testUser, testPassword)
+	server.AddSource("wlan0", testUUID)
+	t.Cleanup(server.Close)
+
+	useCredentials(t, testUser, testPassword)
+	kismetTransport = http.DefaultTransport
+	return server
+}
+
+// Configure the Kismet login. Credentials are cached for the life of the process, so the cache is reset
+// before and after the test.
+func useCredentials(t *testing.T, user, password string) {
+	t.Helper()
+	viper.Set("credentials.user", user)
+	viper.Set("credentials.password", password)
+	once = sync.Once{}
+	t.Cleanup(func() {
+		viper.Set("credentials.user", "")
+		viper.Set("credentials.password", "")
+		once = sync.Once{}
+	})
+}
+
+func TestFetchAllDevices(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(
+		testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "CoffeeShop", Channel: "1", RSSI: -60, Type: "Wi-Fi AP", Clients: []string{"32:34:00:00:00:01"}},
+		testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "1", RSSI: -71, Manuf: "Apple", Type: "Wi-Fi Client"},
+	)
+
+	devices, err := FetchAllDevices(server.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2", len(devices))
+	}
+
+	// Every device comes back under the aliases in deviceFields
+	ap := devices[0]
+	if ap["base.macaddr"] != "10:22:33:44:55:66" || ap["base.channel"] != "1" || ap["RSSI"] != -60.0 || ap["SSID"] != "CoffeeShop" {
+		t.Errorf("access point = %v", ap)
+	}
+	if clients, _ := ap["AssociatedClients"].(map[string]interface{}); len(clients) != 1 {
+		t.Errorf("AssociatedClients = %v, want the one client", ap["AssociatedClients"])
+	}
+	if devices[1]["Make"] != "Apple" {
+		t.Errorf("client = %v", devices[1])
+	}
+
+	requests := server.RequestsTo("/devices/last-time/-5/devices.json")
+	if len(requests) != 1 || requests[0].Method != http.MethodPost {
+		t.Fatalf("device requests = %+v, want one POST", requests)
+	}
+	var payload KismetPayload
+	if err := json.Unmarshal(requests[0].Body, &payload); err != nil || len(payload.Fields) != len(deviceFields) {
+		t.Errorf("request body = %s, want the field list", requests[0].Body)
+	}
+}
+
+func TestCredentialsAttached(t *testing.T) {
+	server := fakeKismet(t)
+
+	if _, err := FetchAllDevices(server.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+	if err := hopChannel(testUUID, server.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetUUIDForInterface("wlan0", server.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, req := range server.Requests() {
+		if req.User != testUser || req.Password != testPassword {
+			t.Errorf("%s %s sent user %q and password %q", req.Method, req.Path, req.User, req.Password)
+		}
+	}
+}
+
+func TestCredentialsRejected(t *testing.T) {
+	server := fakeKismet(t)
+	useCredentials(t, testUser, "wrong")
+
+	if _, err := FetchDatasources(server.Endpoint()); err != errCredentialsRejected {
+		t.Errorf("FetchDatasources err = %v, want errCredentialsRejected", err)
+	}
+	if _, err := FetchAllDevices(server.Endpoint()); err == nil {
+		t.Error("FetchAllDevices succeeded with the wrong password")
+	}
+	if _, err := FetchKismetStatus(server.Endpoint()); err != errCredentialsRejected {
+		t.Errorf("FetchKismetStatus err = %v, want errCredentialsRejected", err)
+	}
+}
+
+func TestFetchKismetStatus(t *testing.T) {
+	server := fakeKismet(t)
+	server.AddSource("wlan1", "5FE308BD-0000-0000-0000-000000000002")
+
+	status, err := FetchKismetStatus(server.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *status != (kismetStatus{Version: "testkismet", Build: "git 0123456", Sources: 2}) {
+		t.Errorf("status = %+v", *status)
+	}
+
+	// The version shows in the status bar once it's known
+	h, _ := testHunt(t, server)
+	h.logKismetStatus()
+	m := &Model{hunt: h, styles: NewStyles(themePresets[defaultThemeName])}
+	if bar := m.renderStatusBar(200); !strings.Contains(bar, "(Kismet testkismet)") {
+		t.Errorf("status bar = %q, want the Kismet version", bar)
+	}
+}
+
+func TestGetUUIDForInterface(t *testing.T) {
+	server := fakeKismet(t)
+	server.AddSource("wlan1", "5FE308BD-0000-0000-0000-000000000002")
+
+	for _, iface := range []string{"wlan0", `wlan0:channels="1,6,11"`} {
+		uuid, err := GetUUIDForInterface(iface, server.Endpoint())
+		if err != nil || uuid != testUUID {
+			t.Errorf("GetUUIDForInterface(%q) = %q, %v; want %q", iface, uuid, err, testUUID)
+		}
+	}
+
+	_, err := GetUUIDForInterface("wlan9", server.Endpoint())
+	notFound, ok := err.(*interfaceNotFoundError)
+	if !ok {
+		t.Fatalf("err = %v, want an *interfaceNotFoundError", err)
+	}
+	if notFound.iface != "wlan9" || len(notFound.sources) != 2 {
+		t.Errorf("err = %+v, want wlan9 and the two sources Kismet has", notFound)
+	}
+}
+
+func TestLockAndHopChannel(t *testing.T) {
+	server := fakeKismet(t)
+
+	if err := lockChannel(testUUID, "11", server.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+	if channel := server.Channel(testUUID); channel != "11" {
+		t.Errorf("locked to %q, want 11", channel)
+	}
+
+	requests := server.RequestsTo("/set_channel.cmd")
+	if len(requests) != 1 || requests[0].Path != "/datasource/by-uuid/"+testUUID+"/set_channel.cmd" {
+		t.Fatalf("set_channel requests = %+v", requests)
+	}
+	var body map[string]string
+	if err := json.Unmarshal(requests[0].Body, &body); err != nil || len(body) != 1 || body["channel"] != "11" {
+		t.Errorf("set_channel body = %s, want {\"channel\":\"11\"}", requests[0].Body)
+	}
+
+	if err := hopChannel(testUUID, server.Endpoint()); err != nil {
+		t.Fatal(err)
+	}
+	if channel := server.Channel(testUUID); channel != "" {
+		t.Errorf("still locked to %q after set_hop", channel)
+	}
+
+	// An empty channel is never sent, since Kismet rejects it
+	if err := lockChannel(testUUID, "", server.Endpoint()); err != errNoChannel {
+		t.Errorf("lockChannel with no channel: err = %v, want errNoChannel", err)
+	}
+	if n := len(server.RequestsTo("/set_channel.cmd")); n != 1 {
+		t.Errorf("got %d set_channel requests, want only the first", n)
+	}
+
+	// Commands for a datasource Kismet doesn't have fail
+	if err := lockChannel("no-such-uuid", "6", server.Endpoint()); err == nil {
+		t.Error("lockChannel succeeded for an unknown datasource")
+	}
+}
+
+func TestCheckCanLaunchKismet(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		euid    int
+		launch  bool
+		wantErr bool
+	}{
+		{"root launching Kismet", 0, true, false},
+		{"root attaching to a running Kismet", 0, false, false},
+		{"non-root launching Kismet", 1000, true, true},
+		{"non-root attaching to a running Kismet", 1000, false, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCanLaunchKismet(tt.euid, tt.launch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkCanLaunchKismet(%d, %v) = %v, want an error: %v", tt.euid, tt.launch, err, tt.wantErr)
+			}
+			// The refusal explains both ways around it
+			if err != nil {
+				for _, option := range []string{"sudo ./rizzyscope", "--skip-kismet"} {
+					if !strings.Contains(err.Error(), option) {
+						t.Errorf("error doesn't mention %s: %v", option, err)
+					}
+				}
+			}
+		})
+	}
+}
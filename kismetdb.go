@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// How far back a device listing looks, matching the last-time/-5 endpoint
+const kismetdbHeardWindow = 5 * time.Second
+
+// A packet from a kismetdb log, reduced to what the replay needs
+type kismetdbPacket struct {
+	time          time.Time
+	mac           string
+	signal        int
+	lat, lon, alt float64
+}
+
+// Serves a Kismet log (.kismet file) in place of a live Kismet server. The packets are played back in
+// timestamp order at their original pace (scaled by speed), and a device listing holds every logged device
+// heard within the last five seconds of the log with its signal at that point. Channel lock and hop
+// commands are logged and otherwise ignored, since the log can't be retuned.
+type kismetdbTransport struct {
+	interfaces []string
+	sources    []map[string]any
+	raw        map[string][]byte // Device JSON by MAC, decoded when first heard
+	packets    []kismetdbPacket  // In time order
+	origin     time.Time
+	speed      float64
+
+	mu       sync.Mutex
+	start    time.Time // When the first request was served
+	next     int       // Index of the next packet to play
+	last     map[string]kismetdbPacket
+	fix      *kismetdbPacket
+	devices  map[string]map[string]any
+	finished bool
+}
+
+// Read the datasources, devices and packets from the kismetdb log at path, which is opened read-only
+func newKismetdbTransport(path string, speed float64) (*kismetdbTransport, error) {
+	// SQLite's own error for a missing file is unhelpful
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("error opening kismetdb: %v", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("error opening kismetdb: %v", err)
+	}
+	defer db.Close()
+
+	k := &kismetdbTransport{
+		raw:     make(map[string][]byte),
+		speed:   speed,
+		last:    make(map[string]kismetdbPacket),
+		devices: make(map[string]map[string]any),
+	}
+	if err := k.readSources(db); err != nil {
+		return nil, err
+	}
+	if err := k.readDevices(db); err != nil {
+		return nil, err
+	}
+	if err := k.readPackets(db); err != nil {
+		return nil, err
+	}
+
+	if len(k.packets) == 0 {
+		return nil, fmt.Errorf("kismetdb %s has no packets with a signal to replay", path)
+	}
+	k.origin = k.packets[0].time
+	return k, nil
+}
+
+func (k *kismetdbTransport) readSources(db *sql.DB) error {
+	rows, err := db.Query("SELECT uuid, interface FROM datasources")
+	if err != nil {
+		return fmt.Errorf("error reading kismetdb datasources: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var uuid, iface string
+		if err := rows.Scan(&uuid, &iface); err != nil {
+			return fmt.Errorf("error reading kismetdb datasources: %v", err)
+		}
+		k.interfaces = append(k.interfaces, iface)
+		k.sources = append(k.sources, map[string]any{
+			"kismet.datasource.interface": iface,
+			"kismet.datasource.uuid":      uuid,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading kismetdb datasources: %v", err)
+	}
+	if len(k.interfaces) == 0 {
+		return fmt.Errorf("kismetdb has no datasources")
+	}
+	return nil
+}
+
+func (k *kismetdbTransport) readDevices(db *sql.DB) error {
+	rows, err := db.Query("SELECT devmac, device FROM devices")
+	if err != nil {
+		return fmt.Errorf("error reading kismetdb devices: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mac string
+		var device []byte
+		if err := rows.Scan(&mac, &device); err != nil {
+			return fmt.Errorf("error reading kismetdb devices: %v", err)
+		}
+		k.raw[mac] = device
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading kismetdb devices: %v", err)
+	}
+	return nil
+}
+
+func (k *kismetdbTransport) readPackets(db *sql.DB) error {
+	rows, err := db.Query(`SELECT ts_sec, ts_usec, sourcemac, signal, lat, lon, alt FROM packets
+		WHERE signal != 0 ORDER BY ts_sec, ts_usec`)
+	if err != nil {
+		return fmt.Errorf("error reading kismetdb packets: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var sec, usec int64
+		var p kismetdbPacket
+		if err := rows.Scan(&sec, &usec, &p.mac, &p.signal, &p.lat, &p.lon, &p.alt); err != nil {
+			return fmt.Errorf("error reading kismetdb packets: %v", err)
+		}
+		// Packets from devices Kismet didn't keep (e.g. corrupt frames) have nothing to list
+		if _, ok := k.raw[p.mac]; !ok {
+			continue
+		}
+		p.time = time.Unix(sec, usec*int64(time.Microsecond))
+		k.packets = append(k.packets, p)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error reading kismetdb packets: %v", err)
+	}
+	return nil
+}
+
+func (k *kismetdbTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		body, _ = io.ReadAll(req.Body)
+		req.Body.Close()
+	}
+
+	path := req.URL.Path
+	if isChannelCommand(path) {
+		slog.Info("Kismetdb replay: ignoring channel command", "path", path)
+		return jsonResponse(req, map[string]any{})
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	now := k.advance()
+
+	switch {
+	case path == "/datasource/all_sources.json":
+		return jsonResponse(req, k.sources)
+
+	case path == "/gps/location.json":
+		return jsonResponse(req, k.location())
+
+	case strings.HasPrefix(path, "/alerts/last-time/"):
+		// Alerts aren't replayed
+		return jsonResponse(req, map[string]any{"kismet.alert.list": []any{}})
+
+	case path == "/devices/last-time/-5/devices.json" && req.Method == http.MethodGet:
+		return jsonResponse(req, k.heard(now, nil))
+
+	case path == "/devices/last-time/-5/devices.json":
+		var payload KismetPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return syntheticResponse(req, http.StatusBadRequest, err.Error()), nil
+		}
+		return jsonResponse(req, k.heard(now, payload.Fields))
+
+	default:
+		return syntheticResponse(req, http.StatusNotFound, "not in a kismetdb log: "+path), nil
+	}
+}
+
+// Play every packet up to the equivalent point in the log, counted from the first request, and return
+// that point
+func (k *kismetdbTransport) advance() time.Time {
+	if k.start.IsZero() {
+		k.start = time.Now()
+	}
+	now := k.origin.Add(time.Duration(float64(time.Since(k.start)) * k.speed))
+
+	for ; k.next < len(k.packets) && !k.packets[k.next].time.After(now); k.next++ {
+		p := k.packets[k.next]
+		k.last[p.mac] = p
+		if p.lat != 0 || p.lon != 0 {
+			k.fix = &k.packets[k.next]
+		}
+	}
+
+	if k.next == len(k.packets) && !k.finished {
+		k.finished = true
+		slog.Info("Kismetdb replay reached the end of the log")
+	}
+	return now
+}
+
+// Every device heard within the window before now as a full Kismet device record, or simplified to fields
+// if given
+func (k *kismetdbTransport) heard(now time.Time, fields [][]string) []map[string]any {
+	devices := []map[string]any{}
+	for mac, p := range k.last {
+		if now.Sub(p.time) > kismetdbHeardWindow {
+			continue
+		}
+		record, err := k.device(mac)
+		if err != nil {
+			slog.Debug("Skipping unreadable kismetdb device", "mac", mac, "err", err)
+			continue
+		}
+
+		// The stored record is the device's final state, so swap in the signal from this point in the log
+		record = maps.Clone(record)
+		record["kismet.device.base.last_time"] = float64(p.time.Unix())
+		record["kismet.device.base.signal"] = map[string]any{
+			"kismet.common.signal.last_signal": float64(p.signal),
+		}
+
+		if fields == nil {
+			devices = append(devices, record)
+			continue
+		}
+		devices = append(devices, simplifyDevice(record, fields))
+	}
+	return devices
+}
+
+// The decoded device record for mac
+func (k *kismetdbTransport) device(mac string) (map[string]any, error) {
+	if record, ok := k.devices[mac]; ok {
+		return record, nil
+	}
+	var record map[string]any
+	if err := json.Unmarshal(k.raw[mac], &record); err != nil {
+		return nil, err
+	}
+	k.devices[mac] = record
+	return record, nil
+}
+
+// The position from the latest packet that had one
+func (k *kismetdbTransport) location() map[string]any {
+	if k.fix == nil {
+		return map[string]any{"kismet.common.location.fix": 0}
+	}
+	fix := 2
+	if k.fix.alt != 0 {
+		fix = 3
+	}
+	return map[string]any{
+		"kismet.common.location.fix":      fix,
+		"kismet.common.location.geopoint": []float64{k.fix.lon, k.fix.lat},
+		"kismet.common.location.alt":      k.fix.alt,
+	}
+}
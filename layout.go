@@ -0,0 +1,228 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	narrowWidth      = 100 // Below this width the panes are stacked in a single column
+	wideListHeight   = 10  // Rows given to the target list in the two-column layout
+	wideTopHeight    = 21  // Rows taken by the top row of the two-column layout (the list, RSSI bar and chart)
+	wideChartLevels  = 7   // Y-axis levels drawn in the chart in the two-column layout
+	minChartLevels   = 2   // Fewer levels than this and the stacked layout drops the chart
+	stackedChartMax  = 4   // The chart is shortened to at most this many levels when stacked
+	minKismetRows    = 2   // Fewer rows than this and the stacked layout drops the Kismet pane
+	stackedHelpLines = 1   // The stacked layout shows a one-line help footer
+	minWindowWidth   = 40  // Narrower than this and a "terminal too small" message is shown instead
+	minListHeight    = 4   // Fewest target list rows in the stacked layout
+	chartExtraRows   = 6   // Chart rows besides its levels: the zero line, both axes, the blank last row and the border
+	paneBorderRows   = 2   // Top and bottom border around every pane
+	widePaddingRows  = 2   // Vertical padding, top and bottom, inside the two-column layout's panes
+	listChromeRows   = 2   // The "Targets" header above the target list and the blank line below it
+	rssiBarRows      = 2   // The RSSI label and the bar under it
+	infoHeaderRows   = 2   // The info pane's title and lock status lines
+	tempSpacerRows   = 1   // Blank line between the real-time lines and the temp messages
+	kismetHeaderRows = 1   // The Kismet pane's title
+	focusFooterRows  = 1   // Key hints under the pane in a focused view
+
+	resizeDebounce = 100 * time.Millisecond // Resize events this close together are applied once
+)
+
+// Sent resizeDebounce after a tea.WindowSizeMsg; only the one for the latest resize is applied
+type resizeMsg struct {
+	seq int
+}
+
+// Size budgets for every pane, computed from the window size on each tea.WindowSizeMsg.
+// Widths are the outer width of a pane including its border.
+type layout struct {
+	stacked      bool
+	width        int
+	height       int
+	leftWidth    int // Outer width of the left column (the full width when stacked)
+	rightWidth   int // Outer width of the right column (the full width when stacked)
+	listHeight   int // Rows given to the target list model
+	chartLevels  int // Y-axis levels drawn in the RSSI chart, 0 hides the chart
+	realTimeRows int // Real-time lines shown in the info pane
+	realTimeH    int // Height of the info pane, 0 lets it size to its content
+	kismetRows   int // Kismet data rows shown, 0 hides the Kismet pane
+}
+
+// Work out pane budgets for a window. Wide terminals get the 2x2 grid, narrow ones a single stacked column
+// of target list, RSSI bar, a shortened chart and the info pane, with the Kismet pane only if rows remain.
+// Wide but short terminals are stacked too, since the grid can't shrink vertically.
+func computeLayout(width, height, realTimeLines, tempCount, kismetRows int) layout {
+	realTimeH := infoHeaderRows + realTimeLines + tempSpacerRows + tempCount + widePaddingRows
+	if width >= narrowWidth && height >= wideTopHeight+realTimeH+paneBorderRows {
+		left := width/2 + 2
+		return layout{
+			width:        width,
+			height:       height,
+			leftWidth:    left,
+			rightWidth:   width - left,
+			listHeight:   wideListHeight,
+			chartLevels:  wideChartLevels,
+			realTimeRows: realTimeLines,
+			realTimeH:    realTimeH,
+			kismetRows:   kismetRows,
+		}
+	}
+
+	l := layout{
+		stacked:    true,
+		width:      width,
+		height:     height,
+		leftWidth:  width,
+		rightWidth: width,
+		listHeight: clamp(height/3, minListHeight, wideListHeight),
+	}
+
+	// Stacked panes have no vertical padding
+	remaining := height
+	remaining -= listChromeRows + l.listHeight + stackedHelpLines + paneBorderRows
+	remaining -= rssiBarRows + paneBorderRows
+
+	infoOverhead := infoHeaderRows + tempSpacerRows + tempCount + paneBorderRows
+	minInfoRows := min(realTimeLines, 3)
+
+	l.chartLevels = clamp(remaining-(minInfoRows+infoOverhead)-chartExtraRows, 0, stackedChartMax)
+	if l.chartLevels < minChartLevels {
+		l.chartLevels = 0
+	} else {
+		remaining -= l.chartLevels + chartExtraRows
+	}
+
+	l.realTimeRows = clamp(remaining-infoOverhead, 1, realTimeLines)
+	remaining -= l.realTimeRows + infoOverhead
+
+	if rows := min(remaining-kismetHeaderRows-paneBorderRows, kismetRows); rows >= minKismetRows {
+		l.kismetRows = rows
+	}
+
+	return l
+}
+
+// Record a new window size and apply it once resizing has settled. Dragging a terminal edge sends a
+// burst of size messages, and relaying out on each one makes the chart flicker.
+func (m *Model) deferResize(msg tea.WindowSizeMsg) tea.Cmd {
+	m.pendingSize = msg
+	m.resizeSeq++
+	seq := m.resizeSeq
+	return tea.Tick(resizeDebounce, func(time.Time) tea.Msg {
+		return resizeMsg{seq: seq}
+	})
+}
+
+// Apply the pending window size if no resize has arrived since msg was scheduled
+func (m *Model) applyResize(msg resizeMsg) {
+	if msg.seq != m.resizeSeq {
+		return
+	}
+	m.windowWidth = m.pendingSize.Width
+	m.windowHeight = m.pendingSize.Height
+	m.applyLayout()
+	m.resizeLogViewer()
+}
+
+// Fewest rows the stacked layout fits in: the shortest target list, the RSSI bar, an info pane with
+// a single real-time line and the status bar
+func minWindowHeight(tempCount int) int {
+	targets := listChromeRows + minListHeight + stackedHelpLines + paneBorderRows
+	rssi := rssiBarRows + paneBorderRows
+	info := infoHeaderRows + 1 + tempSpacerRows + tempCount + paneBorderRows
+	return targets + rssi + info + statusBarLines
+}
+
+// Whether the window is too small for any layout
+func (m *Model) tooSmall() bool {
+	return m.windowWidth < minWindowWidth || m.windowHeight < minWindowHeight(m.tempMessageCount)
+}
+
+// Shown in place of the panes while the window is too small
+func (m *Model) viewTooSmall() string {
+	msg := fmt.Sprintf("Terminal too small (need at least %dx%d, have %dx%d)",
+		minWindowWidth, minWindowHeight(m.tempMessageCount), m.windowWidth, m.windowHeight)
+	return lipgloss.Place(m.windowWidth, m.windowHeight, lipgloss.Center, lipgloss.Center,
+		lipgloss.NewStyle().Width(m.windowWidth).Align(lipgloss.Center).Render(msg))
+}
+
+// Recompute the layout for the current window and resize the list and progress bar to match. The
+// layout is computed for at least the minimum size so pane widths never go negative.
+func (m *Model) applyLayout() {
+	// The status bar's row is taken off the top of the budget for every view
+	width := max(m.windowWidth, minWindowWidth)
+	height := max(m.windowHeight, minWindowHeight(m.tempMessageCount)) - statusBarLines
+	m.layout = computeLayout(width, height, m.realTimeLines, m.tempMessageCount, kismetDataSize)
+	if m.screenView != viewGrid {
+		m.layout = focusLayout(width, height)
+	}
+
+	m.progress.Width = m.layout.rightWidth - 2 - m.paneHPadding()*2
+	if m.progress.Width > maxWidth {
+		m.progress.Width = maxWidth
+	}
+	if m.progress.Width < 0 {
+		m.progress.Width = 0
+	}
+
+	m.targetList.SetWidth(m.layout.leftWidth - 2 - m.paneHPadding()*2)
+	m.targetList.SetHeight(m.layout.listHeight)
+	m.targetList.SetShowTitle(!m.layout.stacked)
+	m.targetList.SetShowStatusBar(!m.layout.stacked)
+
+	// The chart is never wider than the window, so that much RSSI history is enough to fill it
+	m.rssiData.resize(max(m.layout.width, rssiHistorySize))
+	m.computeBounds()
+}
+
+// Horizontal padding inside each pane; the stacked layout uses a tighter padding to save space
+func (m *Model) paneHPadding() int {
+	if m.layout.stacked {
+		return 1
+	}
+	return padding
+}
+
+// Base pane style for the current layout
+func (m *Model) basePane() lipgloss.Style {
+	if m.layout.stacked {
+		return m.styles.Pane.Padding(0, 1)
+	}
+	return m.styles.Pane
+}
+
+// Render the single-column layout used on narrow terminals
+func (m *Model) viewStacked() string {
+	panes := []string{
+		m.renderTargetListWithHelp(m.layout.leftWidth),
+		m.renderRSSIProgressBar(m.layout.leftWidth),
+	}
+
+	if m.layout.chartLevels > 0 {
+		panes = append(panes, m.renderSignalPane(m.layout.leftWidth, m.layout.chartLevels))
+	}
+
+	infoPane := m.renderInfoPane(m.layout.leftWidth)
+	panes = append(panes, infoPane)
+
+	if m.layout.kismetRows > 0 {
+		panes = append(panes, m.renderKismetPane("Kismet Real-Time Data", m.kismetLines(m.layout.kismetRows), m.layout.leftWidth))
+	}
+	panes = append(panes, m.renderStatusBar(m.layout.width))
+
+	return lipgloss.JoinVertical(lipgloss.Left, panes...)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version"
+)
+
+// Query parameters that carry Kismet credentials and must never be logged. Kismet takes an API key as
+// apikey, or a session token under the name of its KISMET cookie.
+var credentialParams = []string{"user", "password", "apikey", "KISMET"}
+
+// Handler that writes every record to the log file and mirrors it to the console, or into the
+// in-TUI message log while the TUI owns the terminal
+type logHandler struct {
+	level   slog.Leveler
+	file    slog.Handler
+	console slog.Handler
+	sink    *logSink
+}
+
+func newLogHandler(file io.Writer, console io.Writer, sink *logSink, level slog.Leveler) *logHandler {
+	opts := &slog.HandlerOptions{Level: level}
+	return &logHandler{
+		level:   level,
+		file:    slog.NewTextHandler(file, opts),
+		console: slog.NewTextHandler(console, opts),
+		sink:    sink,
+	}
+}
+
+func (h *logHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	fileErr := h.file.Handle(ctx, r)
+	if h.sink.capture(r) {
+		return fileErr
+	}
+	return errors.Join(fileErr, h.console.Handle(ctx, r))
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{level: h.level, file: h.file.WithAttrs(attrs), console: h.console.WithAttrs(attrs), sink: h.sink}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{level: h.level, file: h.file.WithGroup(name), console: h.console.WithGroup(name), sink: h.sink}
+}
+
+// Collects log records while the TUI owns the terminal so they can be shown in the message log
+// instead of being written over the screen. Records can come from any goroutine.
+type logSink struct {
+	mu      sync.Mutex
+	active  bool
+	entries []logEntry
+}
+
+// Start or stop capturing records
+func (s *logSink) setActive(active bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active = active
+}
+
+// Store the record if capturing, reporting whether it was captured
+func (s *logSink) capture(r slog.Record) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.active {
+		return false
+	}
+
+	text := r.Message
+	r.Attrs(func(a slog.Attr) bool {
+		text += fmt.Sprintf(" %s=%v", a.Key, a.Value)
+		return true
+	})
+
+	s.entries = append(s.entries, logEntry{time: r.Time, level: levelFromSlog(r.Level), text: text})
+	return true
+}
+
+// Take every entry captured since the last drain
+func (s *logSink) drain() []logEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := s.entries
+	s.entries = nil
+	return entries
+}
+
+func levelFromSlog(l slog.Level) logLevel {
+	switch {
+	case l >= slog.LevelError:
+		return levelError
+	case l >= slog.LevelWarn:
+		return levelWarn
+	case l >= slog.LevelInfo:
+		return levelInfo
+	default:
+		return levelDebug
+	}
+}
+
+// Return the URL as a string with any credential query parameters redacted
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+
+	redacted := *u
+	q := redacted.Query()
+	for _, param := range credentialParams {
+		if q.Has(param) {
+			q.Set(param, "REDACTED")
+		}
+	}
+	redacted.RawQuery = q.Encode()
+	redacted.User = nil
+
+	return redacted.String()
+}
+
+// Send a Kismet API request, logging method, URL (minus credentials), status, latency and byte counts at debug level.
+// Errors from the HTTP client embed the request URL, so it is redacted before the error is returned.
+func doRequest(client *http.Client, req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	target := redactURL(req.URL)
+
+	if client.Transport == nil {
+		client.Transport = kismetTransport
+	}
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", version.UserAgent())
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = target
+		}
+		slog.Debug("Kismet request failed", "method", req.Method, "url", target, "latency", time.Since(start), "err", err)
+		return nil, err
+	}
+
+	resp.Body = &loggedBody{
+		ReadCloser: resp.Body,
+		method:     req.Method,
+		url:        target,
+		status:     resp.StatusCode,
+		reqBytes:   req.ContentLength,
+		start:      start,
+	}
+	return resp, nil
+}
+
+// Response body that counts the bytes read and logs the completed request when closed
+type loggedBody struct {
+	io.ReadCloser
+	method   string
+	url      string
+	status   int
+	reqBytes int64
+	read     int64
+	start    time.Time
+}
+
+func (b *loggedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	return n, err
+}
+
+func (b *loggedBody) Close() error {
+	slog.Debug("Kismet request",
+		"method", b.method,
+		"url", b.url,
+		"status", b.status,
+		"latency", time.Since(b.start).Round(time.Millisecond),
+		"request_bytes", max(b.reqBytes, 0),
+		"response_bytes", b.read,
+	)
+	return b.ReadCloser.Close()
+}
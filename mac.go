@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const macFormats = "AA:BB:CC:DD:EE:FF, AA-BB-CC-DD-EE-FF, AABB.CCDD.EEFF or AABBCCDDEEFF"
+
+// A MAC address from the config, flags or a targets file that couldn't be used
+type InvalidMACError struct {
+	Input  string // As it was given
+	Reason string
+}
+
+func (e *InvalidMACError) Error() string {
+	return fmt.Sprintf("invalid MAC address %q: %s", e.Input, e.Reason)
+}
+
+// Parse a MAC address written with colons, dashes, Cisco-style dots or no separators at all, and return
+// it in the AA:BB:CC:DD:EE:FF form Kismet uses. Multicast and broadcast addresses are rejected, since no
+// device transmits from one.
+func formatMAC(mac string) (string, error) {
+	input := mac
+	mac = strings.TrimSpace(mac)
+
+	var groups []string
+	switch {
+	case strings.ContainsRune(mac, ':'):
+		groups = splitMAC(mac, ":", 6, 2)
+	case strings.ContainsRune(mac, '-'):
+		groups = splitMAC(mac, "-", 6, 2)
+	case strings.ContainsRune(mac, '.'):
+		groups = splitMAC(mac, ".", 3, 4)
+	default:
+		groups = splitMAC(mac, "", 1, 12)
+	}
+	if groups == nil {
+		return "", &InvalidMACError{Input: input, Reason: "expected " + macFormats}
+	}
+
+	hex := strings.Join(groups, "")
+	for _, c := range hex {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return "", &InvalidMACError{Input: input, Reason: fmt.Sprintf("%q is not a hex digit", c)}
+		}
+	}
+
+	// The low bit of the first octet marks a group address; broadcast is all ones
+	if first, _ := strconv.ParseUint(hex[:2], 16, 8); first&1 == 1 {
+		return "", &InvalidMACError{Input: input, Reason: "multicast or broadcast address, which can never be a target"}
+	}
+
+	hex = strings.ToUpper(hex)
+	return fmt.Sprintf("%s:%s:%s:%s:%s:%s", hex[0:2], hex[2:4], hex[4:6], hex[6:8], hex[8:10], hex[10:12]), nil
+}
+
+// One error listing every MAC in macs that formatMAC rejects, or nil if they're all valid
+func checkMACs(macs []string) error {
+	var invalid []string
+	for _, mac := range macs {
+		if _, err := formatMAC(mac); err != nil {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(invalid, "; "))
+}
+
+// Whether s is laid out like a MAC address in one of the forms formatMAC takes, whatever its characters.
+// A bare value counts when it's 12 letters and digits, no more than two of them outside hex, so a typo
+// in a MAC isn't mistaken for an SSID.
+func macShaped(s string) bool {
+	s = strings.TrimSpace(s)
+	if splitMAC(s, ":", 6, 2) != nil || splitMAC(s, "-", 6, 2) != nil || splitMAC(s, ".", 3, 4) != nil {
+		return true
+	}
+	if len(s) != 12 {
+		return false
+	}
+	nonHex := 0
+	for _, c := range s {
+		switch {
+		case strings.ContainsRune("0123456789abcdefABCDEF", c):
+		case c >= 'g' && c <= 'z', c >= 'G' && c <= 'Z':
+			nonHex++
+		default:
+			return false
+		}
+	}
+	return nonHex <= 2
+}
+
+// Split mac on sep into count groups of size characters each, or nil if it isn't shaped that way
+func splitMAC(mac, sep string, count, size int) []string {
+	groups := []string{mac}
+	if sep != "" {
+		groups = strings.Split(mac, sep)
+	}
+	if len(groups) != count {
+		return nil
+	}
+	for _, g := range groups {
+		if len(g) != size {
+			return nil
+		}
+	}
+	return groups
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFormatMAC(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   string // Part of the reason, if the input is rejected
+	}{
+		{"aa:bb:cc:dd:ee:f0", "AA:BB:CC:DD:EE:F0", ""},
+		{"AA-BB-CC-DD-EE-F0", "AA:BB:CC:DD:EE:F0", ""},
+		{"aabb.ccdd.eef0", "AA:BB:CC:DD:EE:F0", ""},
+		{"AaBbCcDdEeF0", "AA:BB:CC:DD:EE:F0", ""},
+		{"  32:34:00:00:00:01\n", "32:34:00:00:00:01", ""},
+
+		// Wrong shape
+		{"", "", "expected"},
+		{"aa:bb:cc:dd:ee", "", "expected"},
+		{"aa:bb:cc:dd:ee:f0:11", "", "expected"},
+		{"a:bb:cc:dd:ee:f00", "", "expected"},
+		{"aabb.ccdd.eef", "", "expected"},
+		{"aabbccddeef", "", "expected"},
+		{"aabbccddeef00", "", "expected"},
+		{"aa:bb-cc:dd:ee:f0", "", "expected"},
+		{"hello-world-12", "", "expected"},
+
+		// Right shape, not hex
+		{"aa:bb:cc:dd:ee:fg", "", `'g' is not a hex digit`},
+		{"zzbb.ccdd.eef0", "", `'z' is not a hex digit`},
+		{"helloworld!!", "", `'h' is not a hex digit`},
+
+		// Group addresses
+		{"ff:ff:ff:ff:ff:ff", "", "multicast or broadcast"},
+		{"01:00:5e:00:00:fb", "", "multicast or broadcast"},
+		{"33-33-00-00-00-01", "", "multicast or broadcast"},
+	}
+	for _, tt := range tests {
+		got, err := formatMAC(tt.input)
+		if tt.err == "" {
+			if err != nil || got != tt.want {
+				t.Errorf("formatMAC(%q) = %q, %v, want %q", tt.input, got, err, tt.want)
+			}
+			continue
+		}
+		var invalid *InvalidMACError
+		if !errors.As(err, &invalid) || invalid.Input != tt.input || !strings.Contains(invalid.Reason, tt.err) {
+			t.Errorf("formatMAC(%q) = %q, %v, want an InvalidMACError for %q", tt.input, got, err, tt.err)
+		}
+	}
+}
+
+func TestCheckMACs(t *testing.T) {
+	if err := checkMACs([]string{"aa:bb:cc:dd:ee:f0", "aabbccddeef0"}); err != nil {
+		t.Errorf("valid MACs: %v", err)
+	}
+	err := checkMACs([]string{"aa:bb:cc:dd:ee:f0", "nope", "ff:ff:ff:ff:ff:ff"})
+	if err == nil || !strings.Contains(err.Error(), `"nope"`) || !strings.Contains(err.Error(), `"ff:ff:ff:ff:ff:ff"`) {
+		t.Errorf("checkMACs = %v, want both invalid MACs listed", err)
+	}
+}
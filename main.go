@@ -1,100 +1,662 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
+	"net/http"
 	"os"
-	"os/exec"
-	"regexp"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version"
 	"github.com/charmbracelet/bubbles/list"
-	"github.com/charmbracelet/bubbles/progress"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-// Clear the terminal screen
-func clearScreen() {
-    cmd := exec.Command("clear") // For Linux/Mac
-    cmd.Stdout = os.Stdout
-    cmd.Run()
+func main() {
+	os.Exit(run())
 }
 
-func formatMAC(mac string) (string, error) {
-	cleanMAC := regexp.MustCompile(`[^0-9A-Fa-f]`).ReplaceAllString(mac, "")
-
-	if len(cleanMAC) != 12 {
-		return "", fmt.Errorf("invalid MAC address: %s", mac)
+// Everything main does, returning the exit code so the deferred cleanup runs on every path
+func run() int {
+	if len(os.Args) > 1 && os.Args[1] == "history" {
+		return runHistory(os.Args[2:])
 	}
-
-	formattedMAC := strings.ToUpper(fmt.Sprintf("%s:%s:%s:%s:%s:%s",
-		cleanMAC[0:2], cleanMAC[2:4], cleanMAC[4:6],
-		cleanMAC[6:8], cleanMAC[8:10], cleanMAC[10:12]))
-
-	return formattedMAC, nil
-}
-
-func main() {
-	if os.Geteuid() != 0 {
-		fmt.Println("Run as root...")
-		os.Exit(1)
+	if len(os.Args) > 1 && os.Args[1] == "list-interfaces" {
+		return runListInterfaces(os.Args[2:])
 	}
 
 	pflag.StringSliceP("mac", "m", []string{}, "MAC address(es) of the device(s)")
 	pflag.StringSliceP("ssid", "s", []string{}, "SSID of the device(s)")
 	pflag.StringSliceP("interface", "i", []string{}, "Interface name")
-	pflag.StringP("config", "c", "", "Path to config file")
+	pflag.StringArray("target-tag", []string{}, "Tag a target as target=tag1,tag2; repeat for more targets (optional.target_tags)")
+	configPath := pflag.StringP("config", "c", "", "Path to config file (.toml, .yaml, .yml or .json)")
+	configType := pflag.String("config-type", "", "Format of the config file if its extension doesn't say: toml, yaml or json")
+	profile := pflag.String("profile", "", "Use the [profiles.<name>] section of the config (default: default_profile); ? lists them")
 	pflag.StringP("kismet-endpoint", "u", "127.0.0.1:2501", "Kismet server endpoint ip:port")
 	skipKismet := pflag.BoolP("skip-kismet", "k", false, "Skip launching Kismet (use if kismet is already running)")
+	skipCapabilityCheck := pflag.Bool("skip-capability-check", false, "Launch Kismet without checking the interfaces support monitor mode")
+	logFilePath := pflag.String("log-file", "", "Write log output to this file (default: a new temp file)")
+	noTUI := pflag.Bool("no-tui", false, "Print one line per reading to stdout instead of running the TUI")
+	output := pflag.String("output", "text", "Headless output format: text or json (json implies --no-tui)")
+	recordPath := pflag.String("record", "", "Append every RSSI sample to this file (.csv, or .jsonl for JSON lines)")
+	recordTrackPath := pflag.String("record-track", "", "Write the GPS track to this file on exit (.gpx, or .kml colored by RSSI)")
+	pflag.String("export-wigle", "", "Write every device seen to this file as a WiGLE CSV on exit (optional.wigle_csv)")
+	pflag.String("metrics-listen", "", "Serve Prometheus metrics on this address, e.g. :9205 (optional.metrics_addr)")
+	pflag.String("api-listen", "", "Serve a read-only JSON API of the current state on this address, e.g. :9206 (optional.api_addr)")
+	debug := pflag.Bool("debug", false, "Log debug messages, including every Kismet API request")
+	showVersion := pflag.Bool("version", false, "Print the version, commit, build date and Go version and exit")
+	check := pflag.Bool("check", false, "Check the config, credentials and Kismet (or what launching it needs), print the results and exit")
+	dryRun := pflag.Bool("dry-run", false, "Same as --check")
+	printConfig := pflag.Bool("print-config", false, "Print the effective configuration from flags, environment, config file and defaults as JSON (secrets redacted) and exit")
+	initConfig := pflag.Bool("init", false, "Write a commented config.toml template to the current directory and exit")
+	force := pflag.Bool("force", false, "Let --init overwrite an existing config.toml")
+	recordSessionPath := pflag.String("record-session", "", "Archive every Kismet API response to this file for --replay")
+	replayPath := pflag.String("replay", "", "Replay a --record-session file instead of talking to Kismet (no root needed)")
+	kismetdbPath := pflag.String("kismetdb", "", "Replay a Kismet log (.kismet file) instead of talking to Kismet (no root needed)")
+	replaySpeed := pflag.Float64("replay-speed", 1, "Playback speed for --replay and --kismetdb, e.g. 4 for four times as fast")
+	demo := pflag.Bool("demo", false, "Track simulated targets instead of real ones (no radio, Kismet or root needed)")
+	seed := pflag.Int64("seed", 0, "Seed for --demo so a simulation can be repeated (default: random)")
+	passwordStdin := pflag.Bool("password-stdin", false, "Read the Kismet password from the first line of stdin")
+	duration := pflag.Duration("duration", 0, "Exit after running this long, e.g. 10m (default: until quit)")
+	exitOnFound := pflag.Bool("exit-on-found", false, "Exit the first time a target is locked (exit code 3 if --duration elapses first)")
+	pflag.Usage = printUsage
+	// Dispatched here rather than with the other subcommands so the scripts can list the flags above
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		return runCompletion(os.Args[2:], pflag.CommandLine)
+	}
 	pflag.Parse()
+	*check = *check || *dryRun
 
-	configPath := viper.GetString("config")
-	if configPath == "" {
-		viper.SetConfigName("config")
-		viper.SetConfigType("toml")
-		viper.AddConfigPath(".")
-	} else {
-		viper.SetConfigFile(configPath)
+	if *showVersion {
+		fmt.Println(version.String())
+		return exitOK
 	}
 
-	if err := viper.ReadInConfig(); err != nil {
-		fmt.Println("Error reading config file:", err)
-		os.Exit(1)
+	if *duration < 0 {
+		fmt.Println("--duration can't be negative")
+		return exitUsage
+	}
+
+	if *initConfig {
+		if err := writeDefaultConfig(defaultConfigPath, *force); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		fmt.Printf("Wrote %s\n", defaultConfigPath)
+		return exitOK
+	}
+
+	if *recordSessionPath != "" && *replayPath != "" {
+		fmt.Println("--record-session and --replay can't be used together")
+		return 1
+	}
+	offlineSources := 0
+	for _, set := range []bool{*replayPath != "", *kismetdbPath != "", *demo} {
+		if set {
+			offlineSources++
+		}
+	}
+	if offlineSources > 1 {
+		fmt.Println("Only one of --replay, --kismetdb and --demo can be used")
+		return 1
+	}
+
+	// A replay or demo never touches Kismet or the capture interface, so it needs neither root nor Kismet
+	offline := offlineSources > 0
+
+	if *passwordStdin {
+		password, err := readPasswordStdin()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		stdinPassword = password
+	}
+
+	// Targets can be piped in, e.g. grep ... | rizzyscope; a terminal on stdin is left alone
+	stdinPiped := stdinIsPiped()
+	if stdinPiped && !*passwordStdin {
+		targets, err := readTargetList(os.Stdin)
+		if err != nil {
+			fmt.Println("Error reading targets from stdin:", err)
+			return 1
+		}
+		pipedTargets = targets
+	}
+
+	logFile, err := openLogFile(*logFilePath)
+	if err != nil {
+		fmt.Println("Error opening log file:", err)
+		return 1
+	}
+	defer logFile.Close()
+
+	if *output != "text" && *output != "json" {
+		fmt.Printf("Unknown --output %q, expected text or json\n", *output)
+		return 1
+	}
+
+	// In JSON mode stdout carries only events, so log messages go to the log file alone
+	console := io.Writer(os.Stderr)
+	if *output == "json" {
+		*noTUI = true
+		console = io.Discard
+	}
+
+	if *replaySpeed <= 0 {
+		fmt.Println("--replay-speed must be greater than 0")
+		return 1
+	}
+
+	if *replayPath != "" {
+		replay, err := newReplayTransport(*replayPath, *replaySpeed)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		kismetTransport = replay
+		*skipKismet = true
+	}
+
+	var kismetdb *kismetdbTransport
+	if *kismetdbPath != "" {
+		kismetdb, err = newKismetdbTransport(*kismetdbPath, *replaySpeed)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		kismetTransport = kismetdb
+		*skipKismet = true
+	}
+
+	if *demo {
+		if !pflag.CommandLine.Changed("seed") {
+			*seed = time.Now().UnixNano()
+		}
+		kismetTransport = newDemoTransport(*seed)
+		*skipKismet = true
+	}
+
+	if *recordSessionPath != "" {
+		recording, err := newRecordingTransport(*recordSessionPath, kismetTransport)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer recording.Close()
+		kismetTransport = recording
+	}
+
+	logLevel := slog.LevelInfo
+	if *debug {
+		logLevel = slog.LevelDebug
+	}
+
+	// Until the TUI starts, log output goes to the console as well as the log file
+	sink := &logSink{}
+	slog.SetDefault(slog.New(newLogHandler(logFile, console, sink, logLevel)))
+
+	if err := setupConfig(*configPath, *configType); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	viper.SetDefault("optional.realtime_lines", 7)
+	viper.SetDefault("optional.realtime_history", 1000)
+	viper.SetDefault("optional.temp_message_count", 3)
+	viper.SetDefault("optional.temp_message_seconds", 3)
+	viper.SetDefault("optional.mouse", true)
+	viper.SetDefault("optional.show_percentage", false)
+	viper.SetDefault("optional.multi_target_count", 5)
+	viper.SetDefault("optional.rotate_dwell_seconds", 5)
+	viper.SetDefault("optional.cycle_interval_seconds", 30)
+	viper.SetDefault("optional.rssi_display", string(rssiDBm))
+	viper.SetDefault("optional.notify_cooldown_seconds", 60)
+	viper.SetDefault("optional.state_file", defaultStatePath())
+	viper.SetDefault("optional.webhook_events", []string{alertTargetFound, alertTargetLost, alertRSSIAbove, alertProximity, alertColocation})
+	viper.SetDefault("optional.colocation_rssi_dbm", -70)
+	viper.SetDefault("optional.colocation_window_seconds", 10)
+	viper.SetDefault("optional.webhook_rssi_threshold", -50)
+	viper.SetDefault("optional.webhook_min_interval_seconds", 60)
+	viper.SetDefault("mqtt.topic_prefix", "rizzyscope")
+	if offline {
+		// Requests still carry credentials, but nothing checks them
+		viper.SetDefault("credentials.user", "offline")
+		viper.SetDefault("credentials.password", "offline")
+	}
+
+	// Everything can come from flags and the environment, so a missing config.toml is fine as long as
+	// validateSettings finds what it needs. One that exists but can't be read or parsed is not.
+	if *profile == "?" {
+		if err := viper.ReadInConfig(); err != nil {
+			fmt.Println("Error reading config file:", describeParseError(err, viper.ConfigFileUsed(), configFormat))
+			return 1
+		}
+		listProfiles(os.Stdout, viper.GetViper())
+		return exitOK
+	}
+	activeProfile = *profile
+
+	if err := readConfig(); err != nil {
+		if !errors.As(err, &viper.ConfigFileNotFoundError{}) {
+			if *check {
+				return failCheckConfig(err)
+			}
+			fmt.Println("Error reading config file:", err)
+			return 1
+		}
+		if *profile != "" {
+			fmt.Printf("No config file found to take profile %q from\n", *profile)
+			return 1
+		}
+		slog.Info("No config file found, using flags and environment variables")
 	}
 
 	if err := viper.BindPFlag("required.target_mac", pflag.Lookup("mac")); err != nil {
-		log.Printf("Error in parsing MAC flag/config: %v", err)
+		slog.Error("Error in parsing MAC flag/config", "err", err)
 	}
 
 	if err := viper.BindPFlag("required.interface", pflag.Lookup("interface")); err != nil {
-		log.Printf("Error in parsing interface flag/config: %v", err)
+		slog.Error("Error in parsing interface flag/config", "err", err)
 	}
 
 	if err := viper.BindPFlag("optional.kismet_endpoint", pflag.Lookup("kismet-endpoint")); err != nil {
-		log.Printf("Error in parsing kismet-endpoint flag/config: %v", err)
+		slog.Error("Error in parsing kismet-endpoint flag/config", "err", err)
 	}
+	// Every Kismet request is built from the normalized endpoint, so a bad one is caught here rather than
+	// failing each request
+	endpoint, err := parseKismetEndpoint(viper.GetString("optional.kismet_endpoint"))
+	if err != nil {
+		if *check {
+			return failCheckConfig(err)
+		}
+		fmt.Println(err)
+		return exitError
+	}
+	viper.Set("optional.kismet_endpoint", endpoint)
 
 	if err := viper.BindPFlag("optional.target_ssid", pflag.Lookup("ssid")); err != nil {
-		log.Printf("Error in parsing 'ssid' flag/config: %v", err)
+		slog.Error("Error in parsing 'ssid' flag/config", "err", err)
+	}
+
+	if err := viper.BindPFlag("optional.target_tags", pflag.Lookup("target-tag")); err != nil {
+		slog.Error("Error in parsing target-tag flag/config", "err", err)
+	}
+
+	if err := viper.BindPFlag("optional.metrics_addr", pflag.Lookup("metrics-listen")); err != nil {
+		slog.Error("Error in parsing metrics-listen flag/config", "err", err)
+	}
+
+	if err := viper.BindPFlag("optional.api_addr", pflag.Lookup("api-listen")); err != nil {
+		slog.Error("Error in parsing api-listen flag/config", "err", err)
+	}
+
+	if err := viper.BindPFlag("optional.wigle_csv", pflag.Lookup("export-wigle")); err != nil {
+		slog.Error("Error in parsing export-wigle flag/config", "err", err)
+	}
+	wiglePath := viper.GetString("optional.wigle_csv")
+
+	if *printConfig {
+		if err := printEffectiveConfig(os.Stdout); err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		return exitOK
+	}
+
+	targets := loadTargets()
+	if len(pipedTargets) > 0 {
+		slog.Info("Read targets from stdin", "count", len(pipedTargets))
+	}
+	if *demo {
+		targets = demoTargets()
+		viper.Set("required.interface", []string{demoInterface})
+		slog.Info("Running a demo with simulated targets", "seed", *seed)
+	}
+	if kismetdb != nil {
+		// Match the log's own capture interfaces, whatever the config names
+		viper.Set("required.interface", kismetdb.interfaces)
+		slog.Info("Replaying a Kismet log", "path", *kismetdbPath, "interfaces", kismetdb.interfaces)
+	}
+
+	if *check {
+		return runCheck(os.Stdout, targets, checkOptions{
+			skipKismet:          *skipKismet,
+			skipCapabilityCheck: *skipCapabilityCheck,
+			offline:             offline,
+			euid:                os.Geteuid(),
+		})
+	}
+
+	_, _, credentialsErr := getCachedCredentials()
+	if err := validateSettings(targets, configList("required.interface"), credentialsErr); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	// Root is only needed to launch Kismet, so attaching to one that's already running works as any user
+	if !*skipKismet && kismetRunning(viper.GetString("optional.kismet_endpoint")) {
+		slog.Info("Kismet is already running, attaching to it", "endpoint", viper.GetString("optional.kismet_endpoint"))
+		*skipKismet = true
+	}
+	if err := checkCanLaunchKismet(os.Geteuid(), !*skipKismet); err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	t := newHunt(targets, configList("required.interface"), viper.GetString("optional.kismet_endpoint"))
+	t.dumpDir = viper.GetString("optional.dump_dir")
+	t.LockDwell = time.Duration(viper.GetInt("optional.lock_dwell_seconds")) * time.Second
+	t.StaleAfter = time.Duration(viper.GetInt("optional.stale_after_minutes")) * time.Minute
+	t.rotateDwell = time.Duration(viper.GetInt("optional.rotate_dwell_seconds")) * time.Second
+	t.cycleInterval = time.Duration(viper.GetInt("optional.cycle_interval_seconds")) * time.Second
+	if threshold := viper.GetInt("optional.proximity_threshold_dbm"); threshold != 0 {
+		t.proximity = newProximityWatch(threshold)
+	}
+	if viper.GetBool("optional.deauth_watch") {
+		t.deauth = newDeauthWatch()
+	}
+	if pairs := configList("optional.colocation_pairs"); len(pairs) > 0 {
+		window := time.Duration(viper.GetInt("optional.colocation_window_seconds")) * time.Second
+		t.colocation = newColocationWatch(pairs, targets, viper.GetInt("optional.colocation_rssi_dbm"), window)
+	}
+
+	if *recordPath != "" {
+		rec, err := newRecorder(*recordPath, int64(viper.GetInt("optional.record_max_mb"))*1024*1024)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer closeRecorder(rec)
+		t.recorder = rec
+	}
+
+	if dbPath := viper.GetString("optional.db_path"); dbPath != "" {
+		store, err := openSQLiteStore(dbPath)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		t.sightings = newSightingWriter(store)
+		defer closeSightings(t.sightings)
+	}
+
+	if webhookURL := viper.GetString("optional.webhook_url"); webhookURL != "" {
+		t.alerts = newWebhookNotifier(
+			webhookURL,
+			configList("optional.webhook_events"),
+			viper.GetInt("optional.webhook_rssi_threshold"),
+			time.Duration(viper.GetInt("optional.webhook_min_interval_seconds"))*time.Second,
+		)
+	}
+
+	syslogger, err := newSyslogLogger(viper.GetBool("optional.syslog"), viper.GetString("optional.syslog_addr"),
+		viper.GetInt("optional.webhook_rssi_threshold"))
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	if syslogger != nil {
+		t.syslog = syslogger
+		defer syslogger.Close()
 	}
 
+	if viper.GetString("mqtt.broker") != "" {
+		publisher, err := newMQTTPublisher()
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		t.mqtt = publisher
+		defer publisher.Close()
+	}
+
+	// The TUI always keeps the track so it can be exported with x
+	if *recordTrackPath != "" || !*noTUI {
+		t.track = newHuntTrack()
+	}
+
+	if wiglePath != "" {
+		t.wigle = newWigleLog()
+	}
+
+	// The bell goes to stderr so it never lands in the middle of the TUI's or JSON output on stdout
+	t.notifier = newFoundNotifier(
+		viper.GetBool("optional.bell_on_found"),
+		viper.GetBool("optional.desktop_notify"),
+		time.Duration(viper.GetInt("optional.notify_cooldown_seconds"))*time.Second,
+		os.Stderr,
+	)
+
+	var metricsServer *http.Server
+	if metricsAddr := viper.GetString("optional.metrics_addr"); metricsAddr != "" {
+		t.metrics = newMetrics()
+		metricsServer, err = serveMetrics(metricsAddr, t.metrics)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer stopServer(metricsServer)
+	}
+
+	var apiServer *http.Server
+	if apiAddr := viper.GetString("optional.api_addr"); apiAddr != "" {
+		t.api = newStateAPI()
+		apiServer, err = serveAPI(apiAddr, t.api)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		defer stopServer(apiServer)
+	}
+
+	if *noTUI {
+		kismet, err := startKismet(*skipKismet, *skipCapabilityCheck, t.iface)
+		if err != nil {
+			fmt.Println(err)
+			return 1
+		}
+		time.Sleep(3 * time.Second)
+
+		var out emitter = &textEmitter{w: os.Stdout}
+		if *output == "json" {
+			out = newJSONEmitter(os.Stdout)
+		}
+
+		code := runHeadless(t, kismet, out, newRunLimit(*duration, *exitOnFound, time.Now()))
+		saveTrack(t.track, *recordTrackPath)
+		saveWigle(t.wigle, wiglePath)
+		return code
+	}
+
+	theme, themeWarnings := LoadTheme()
+	for _, warning := range themeWarnings {
+		fmt.Printf("Warning: %s\n", warning)
+	}
+
+	display, err := parseRSSIDisplay(viper.GetString("optional.rssi_display"))
+	if err != nil {
+		fmt.Printf("Warning: %v, using dbm\n", err)
+	}
+
+	themeName := viper.GetString("theme.name")
+	if _, ok := themePresets[themeName]; !ok {
+		themeName = defaultThemeName
+	}
+
+	m := Model{
+		progress:       newProgressBar(theme, viper.GetBool("optional.show_percentage")),
+		hunt:           t,
+		realTimeOutput: newRing[logEntry](viper.GetInt("optional.realtime_history")),
+		windowWidth:    80,
+		windowHeight:   24,
+		targetList:     list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 10),
+		kismetData:     newRing[kismetEntry](kismetDataSize),
+		styles:         NewStyles(theme),
+
+		realTimeLines:       viper.GetInt("optional.realtime_lines"),
+		tempMessageCount:    viper.GetInt("optional.temp_message_count"),
+		tempMessages:        newRing[tempMessage](viper.GetInt("optional.temp_message_count")),
+		tempMessageDuration: time.Duration(viper.GetInt("optional.temp_message_seconds")) * time.Second,
+		confirmQuit:         viper.GetBool("optional.confirm_quit"),
+		rssiDisplay:         display,
+		themeName:           themeName,
+		targetSort:          sortBySignal,
+		multiCount:          max(viper.GetInt("optional.multi_target_count"), 1),
+		logView:             newLogViewer(),
+		logSink:             sink,
+		trackPath:           *recordTrackPath,
+		wiglePath:           wiglePath,
+
+		pollHealth: newRing[bool](pollHealthSize),
+	}
+	m.targetList.SetDelegate(newTargetDelegate(m.styles, func() *TargetItem { return m.LockedTarget }))
+	m.targetList.SetShowHelp(false)
+	m.applyLayout()
+
+	statePath := viper.GetString("optional.state_file")
+	restoreState(&m, statePath)
+	m.syncTargetList()
+
+	if eventLogPath := viper.GetString("optional.event_log"); eventLogPath != "" {
+		eventLog, err := os.OpenFile(eventLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			fmt.Println("Error opening event log:", err)
+			return 1
+		}
+		defer eventLog.Close()
+		m.eventLog = eventLog
+	}
+
+	m.kismet, err = startKismet(*skipKismet, *skipCapabilityCheck, m.iface)
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+
+	time.Sleep(3 * time.Second)
+
+	var opts []tea.ProgramOption
+	if viper.GetBool("optional.mouse") {
+		opts = append(opts, tea.WithMouseCellMotion())
+	}
+	if stdinPiped {
+		// Stdin was used up by the password or targets, so take key presses from the terminal
+		opts = append(opts, tea.WithInputTTY())
+	}
+
+	// Keep log records from writing over the TUI; they go to the log file and the message log instead
+	sink.setActive(true)
+	m.logKismetStatus()
+	p := tea.NewProgram(&m, opts...)
+	forwardSIGHUP(p)
+	forwardDumpSignals(p)
+	if stopWatching, err := watchConfigFile(func() { p.Send(configReloadMsg{}) }); err != nil {
+		slog.Warn("Config changes won't be picked up until SIGHUP", "err", err)
+	} else {
+		defer stopWatching()
+	}
+	m.limit = newRunLimit(*duration, *exitOnFound, time.Now())
+	_, err = p.Run()
+	sink.setActive(false)
+
+	// Anything logged after the last Update never made it into the message log
+	for _, e := range sink.drain() {
+		fmt.Fprintf(os.Stderr, "%s %s\n", e.level, e.text)
+	}
+	fmt.Printf("Log written to %s\n", logFile.Name())
+	saveTrack(m.track, *recordTrackPath)
+	saveWigle(m.wigle, wiglePath)
+	persistState(&m, statePath)
+
+	if err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
+
+	if m.fatalErr != nil {
+		fmt.Println("Error:", m.fatalErr)
+		return exitError
+	}
+
+	if m.exitReason != "" {
+		fmt.Println(m.exitReason)
+	}
+	return m.exitCode
+}
+
+// Write the GPS track to path on exit, if --record-track was given
+func saveTrack(track *huntTrack, path string) {
+	if path == "" {
+		return
+	}
+	if track.empty() {
+		slog.Warn("No GPS fix during the session, track not written")
+		return
+	}
+	if err := track.export(path); err != nil {
+		slog.Error("Error exporting track", "err", err)
+		return
+	}
+	slog.Info("Track written", "path", path)
+}
+
+// Write the WiGLE CSV to path on exit, if --export-wigle was given
+func saveWigle(wigle *wigleLog, path string) {
+	if path == "" {
+		return
+	}
+	if err := wigle.export(path); err != nil {
+		slog.Error("Error exporting WiGLE CSV", "err", err)
+		return
+	}
+	slog.Info("WiGLE CSV written", "path", path, "devices", len(wigle.devices))
+}
+
+// Build the targets from the MACs, SSIDs, labels and tags in the config
+func loadTargets() []*TargetItem {
 	// Read MACs and SSIDs from Viper
-	rawTargetMACs := viper.GetStringSlice("required.target_mac")
-	targetSSIDs := viper.GetStringSlice("optional.target_ssid")
+	rawTargetMACs := configList("required.target_mac")
+	rawTargetSSIDs := configList("optional.target_ssid")
 
-	// Format and validate MAC addresses
+	// Format and validate MAC addresses, reporting every invalid one together
 	var targetMACs []string
+	var invalid []error
 	for _, mac := range rawTargetMACs {
 		formattedMAC, err := formatMAC(mac)
 		if err != nil {
-			fmt.Printf("Warning: %v\n", err)
+			invalid = append(invalid, err)
 			continue
 		}
 		targetMACs = append(targetMACs, formattedMAC)
 	}
+	if len(invalid) > 0 {
+		slog.Warn(fmt.Sprintf("Skipping %d invalid target MAC(s)", len(invalid)), "err", errors.Join(invalid...))
+	}
+
+	// Trim and validate SSIDs the same way
+	var targetSSIDs []string
+	invalid = nil
+	for _, ssid := range rawTargetSSIDs {
+		formattedSSID, err := formatSSID(ssid)
+		if err != nil {
+			invalid = append(invalid, err)
+			continue
+		}
+		targetSSIDs = append(targetSSIDs, formattedSSID)
+	}
+	if len(invalid) > 0 {
+		slog.Warn(fmt.Sprintf("Skipping %d invalid target SSID(s)", len(invalid)), "err", errors.Join(invalid...))
+	}
 
 	// Build the targets slice
 	var targets []*TargetItem
@@ -104,39 +666,188 @@ func main() {
 	for _, ssid := range targetSSIDs {
 		targets = append(targets, &TargetItem{Value: ssid, TType: SSID})
 	}
+	targets, duplicates := dedupTargets(withPipedTargets(targets))
+	if duplicates > 0 {
+		slog.Info(fmt.Sprintf("Removed %d duplicate target(s)", duplicates))
+	}
 
-	m := Model{
-		progress:       progress.New(progress.WithGradient("#ff5555", "#50fa7b"), progress.WithoutPercentage()),
-		rssi:           MinRSSI,
-		lastReceived:   time.Now(),
-		targets:        targets,
-		iface:          viper.GetStringSlice("required.interface"),
-		realTimeOutput: []string{},
-		ignoreList:     []string{},
-		windowWidth:    80,
-		targetList:     list.New([]list.Item{}, list.NewDefaultDelegate(), 40, 10),
-		kismetEndpoint: viper.GetString("optional.kismet_endpoint"),
-		kismetData:     make([]string, 0),
-		maxDataSize:    10,
+	labels := parseTargetAssignments(viper.GetStringSlice("optional.target_labels"), "optional.target_labels")
+	tags := parseTargetAssignments(viper.GetStringSlice("optional.target_tags"), "optional.target_tags")
+	thresholds := parseTargetAssignments(viper.GetStringSlice("optional.target_alert_rssi"), "optional.target_alert_rssi")
+	for _, target := range targets {
+		target.Label = labels[target.Value]
+		for _, tag := range strings.Split(tags[target.Value], ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				target.Tags = append(target.Tags, tag)
+			}
+		}
+		if threshold, ok := thresholds[target.Value]; ok {
+			rssi, err := strconv.Atoi(threshold)
+			if err != nil {
+				slog.Warn("Ignoring invalid alert RSSI", "target", target.Value, "rssi", threshold)
+				continue
+			}
+			target.AlertRSSI = rssi
+		}
 	}
 
-	if *skipKismet {
-		m.kismet = nil
-	} else {
-		kismet, err := LaunchKismet(m.iface)
-		if err != nil {
-			fmt.Println("Kismet couldn't launch. Please ensure Kimset is installed and in your $PATH.")
-			os.Exit(1)
+	return targets
+}
+
+// Parse "target=value" config entries into a map keyed by the formatted MAC, or the SSID as written
+func parseTargetAssignments(entries []string, key string) map[string]string {
+	values := make(map[string]string)
+	for _, entry := range entries {
+		target, value, ok := strings.Cut(entry, "=")
+		target, value = strings.TrimSpace(target), strings.TrimSpace(value)
+		if !ok || target == "" || value == "" {
+			slog.Warn("Ignoring malformed entry, expected target=value", "key", key, "entry", entry)
+			continue
+		}
+		if mac, err := formatMAC(target); err == nil {
+			target = mac
 		}
+		values[target] = value
+	}
+	return values
+}
 
-		m.kismet = kismet
+// Finish the session recording, if there is one
+func closeRecorder(rec *recorder) {
+	if rec == nil {
+		return
 	}
+	if err := rec.Close(); err != nil {
+		slog.Error("Error closing recording", "err", err)
+	}
+}
 
-	time.Sleep(3 * time.Second)
-	clearScreen()
+// Write out any queued sightings and close the database, if there is one
+func closeSightings(w *sightingWriter) {
+	if w == nil {
+		return
+	}
+	if err := w.Close(); err != nil {
+		slog.Error("Error closing database", "err", err)
+	}
+}
 
-	if _, err := tea.NewProgram(&m).Run(); err != nil {
-		fmt.Println("Error:", err)
-		os.Exit(1)
+// Config file formats, in the order the default search tries config.<ext> in the current directory
+var configFormats = []string{"toml", "yaml", "yml", "json"}
+
+// The format of the config file in use, for error messages
+var configFormat = "toml"
+
+// Point viper at the config file, defaulting to config.toml (then .yaml, .yml or .json) in the current
+// directory, and let RIZZYSCOPE_<SECTION>_<KEY> environment variables override any key (e.g.
+// RIZZYSCOPE_CREDENTIALS_USER). The format comes from the file's extension unless configType is set.
+func setupConfig(configPath, configType string) error {
+	viper.SetEnvPrefix("RIZZYSCOPE")
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	bindConfigEnv()
+
+	configType = strings.ToLower(configType)
+	if configType != "" && !slices.Contains(configFormats, configType) {
+		return fmt.Errorf("unsupported config type %q, expected toml, yaml or json", configType)
+	}
+
+	if configPath == "" {
+		configPath = findDefaultConfig(configType)
+		if configPath == "" {
+			// Nothing there, so ReadInConfig reports a ConfigFileNotFoundError
+			viper.SetConfigName("config")
+			viper.SetConfigType("toml")
+			viper.AddConfigPath(".")
+			return nil
+		}
+	}
+
+	format := configType
+	if format == "" {
+		format = strings.ToLower(strings.TrimPrefix(filepath.Ext(configPath), "."))
+		if !slices.Contains(configFormats, format) {
+			return fmt.Errorf("can't tell the format of %s from its extension, set --config-type to toml, yaml or json", configPath)
+		}
+	}
+	configFormat = format
+	viper.SetConfigFile(configPath)
+	viper.SetConfigType(format)
+	return nil
+}
+
+// The first config.<ext> in the current directory, trying only configType if it's set
+func findDefaultConfig(configType string) string {
+	formats := configFormats
+	if configType != "" {
+		formats = []string{configType}
+	}
+	for _, format := range formats {
+		path := "config." + format
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// Read the config file, naming the format it was parsed as if it's malformed, and apply the active profile
+func readConfig() error {
+	if err := viper.ReadInConfig(); err != nil {
+		return describeParseError(err, viper.ConfigFileUsed(), configFormat)
+	}
+	return applyProfile(viper.GetViper(), activeProfile)
+}
+
+// Name the file and format in a viper parse error, passing any other error through
+func describeParseError(err error, path, format string) error {
+	var parseErr viper.ConfigParseError
+	if errors.As(err, &parseErr) {
+		name := strings.ToUpper(format)
+		if format == "yml" {
+			name = "YAML"
+		}
+		return fmt.Errorf("%s is not valid %s: %v", path, name, err)
+	}
+	return err
+}
+
+// Check that the settings a session can't run without are there, wherever they came from, and list every
+// one that's missing
+func validateSettings(targets []*TargetItem, ifaces []string, credentialsErr error) error {
+	var missing []string
+	if len(targets) == 0 {
+		missing = append(missing, "at least one target: required.target_mac or optional.target_ssid (--mac, --ssid or piped on stdin)")
+	}
+	if len(ifaces) == 0 {
+		missing = append(missing, "required.interface (--interface)")
+	}
+	if credentialsErr != nil {
+		missing = append(missing, "credentials.user and credentials.password (RIZZYSCOPE_CREDENTIALS_USER/PASSWORD, credentials.file or --password-stdin)")
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing required settings:\n  %s", strings.Join(missing, "\n  "))
+}
+
+// Open the log file for appending, creating a new temp file when no path is given
+func openLogFile(path string) (*os.File, error) {
+	if path == "" {
+		return os.CreateTemp("", "rizzyscope-*.log")
+	}
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// Read the Kismet password from the first line of stdin
+func readPasswordStdin() (string, error) {
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", fmt.Errorf("error reading password from stdin: %v", err)
+	}
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("--password-stdin given but no password was read from stdin")
 	}
+	return password, nil
 }
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+func (l logLevel) String() string {
+	switch l {
+	case levelDebug:
+		return "DEBUG"
+	case levelWarn:
+		return "WARN"
+	case levelError:
+		return "ERROR"
+	default:
+		return "INFO"
+	}
+}
+
+// A single timestamped entry in the message log
+type logEntry struct {
+	time  time.Time
+	level logLevel
+	text  string
+}
+
+// Move anything logged since the last update into the message log
+func (m *Model) drainLogSink() {
+	if m.logSink == nil {
+		return
+	}
+	for _, e := range m.logSink.drain() {
+		m.pushLogEntry(e)
+	}
+}
+
+// Entries at or above the minimum level, which is what the log panes show
+func (m *Model) shownLogEntries() []logEntry {
+	var shown []logEntry
+	for i := range m.realTimeOutput.len() {
+		if e := m.realTimeOutput.at(i); e.level >= m.minLogLevel {
+			shown = append(shown, e)
+		}
+	}
+	return shown
+}
+
+// Number of entries shownLogEntries would return, without copying them
+func (m *Model) shownLogCount() int {
+	count := 0
+	for i := range m.realTimeOutput.len() {
+		if m.realTimeOutput.at(i).level >= m.minLogLevel {
+			count++
+		}
+	}
+	return count
+}
+
+// Switch the log panes between every entry and only warnings and errors
+func (m *Model) toggleWarningsOnly() {
+	if m.minLogLevel == levelWarn {
+		m.minLogLevel = levelDebug
+	} else {
+		m.minLogLevel = levelWarn
+	}
+	m.realTimeScroll = 0
+	m.refreshLogViewer()
+}
+
+// Format an entry for display, colored by severity
+func (m *Model) formatLogEntry(e logEntry) string {
+	stamp := m.styles.Help.Render(e.time.Format("15:04:05"))
+	switch e.level {
+	case levelDebug:
+		return stamp + " " + m.styles.Help.Render(e.text)
+	case levelWarn:
+		return stamp + " " + m.styles.Warn.Render(e.text)
+	case levelError:
+		return stamp + " " + m.styles.Bad.Render(e.text)
+	default:
+		return stamp + " " + e.text
+	}
+}
+
+// Full-screen, searchable viewport over the whole message log
+type logViewer struct {
+	open      bool
+	viewport  viewport.Model
+	input     textinput.Model
+	searching bool   // Search input has focus
+	query     string // Active search, matched case-insensitively
+	matches   []int  // Log lines matching the query
+	match     int    // Index into matches of the current match
+}
+
+func newLogViewer() logViewer {
+	input := textinput.New()
+	input.Prompt = "/"
+	return logViewer{viewport: viewport.New(0, 0), input: input}
+}
+
+// Open the log viewer scrolled to the newest entry
+func (m *Model) openLogViewer() {
+	m.logView.open = true
+	m.resizeLogViewer()
+	m.refreshLogViewer()
+	m.logView.viewport.GotoBottom()
+}
+
+// Size the viewport to the window, leaving room for the header, footer and border
+func (m *Model) resizeLogViewer() {
+	m.logView.viewport.Width = max(m.windowWidth-6, 0)
+	m.logView.viewport.Height = max(m.windowHeight-6, 1)
+}
+
+// Re-render the log into the viewport, following new entries if we were already at the bottom
+func (m *Model) refreshLogViewer() {
+	if !m.logView.open {
+		return
+	}
+
+	followTail := m.logView.viewport.AtBottom()
+	query := strings.ToLower(m.logView.query)
+
+	m.logView.matches = m.logView.matches[:0]
+	entries := m.shownLogEntries()
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		line := m.formatLogEntry(e)
+		if query != "" && strings.Contains(strings.ToLower(e.text), query) {
+			m.logView.matches = append(m.logView.matches, i)
+			line = m.styles.Header.Render("▶ ") + line
+		}
+		lines[i] = line
+	}
+	m.logView.viewport.SetContent(strings.Join(lines, "\n"))
+
+	if followTail {
+		m.logView.viewport.GotoBottom()
+	}
+}
+
+// Jump the viewport to the next (or previous) search match
+func (m *Model) jumpToMatch(delta int) {
+	if len(m.logView.matches) == 0 {
+		return
+	}
+	m.logView.match = (m.logView.match + delta + len(m.logView.matches)) % len(m.logView.matches)
+	m.logView.viewport.SetYOffset(m.logView.matches[m.logView.match])
+}
+
+// Handle keys while the log viewer is open
+func (m *Model) updateLogViewer(msg tea.KeyMsg) tea.Cmd {
+	if m.logView.searching {
+		switch msg.String() {
+		case "enter":
+			m.logView.searching = false
+			m.logView.query = m.logView.input.Value()
+			m.logView.input.Blur()
+			m.refreshLogViewer()
+			m.logView.match = -1
+			m.jumpToMatch(1)
+			return nil
+		case "esc":
+			m.logView.searching = false
+			m.logView.input.Blur()
+			return nil
+		}
+		var cmd tea.Cmd
+		m.logView.input, cmd = m.logView.input.Update(msg)
+		return cmd
+	}
+
+	switch msg.String() {
+	case "esc", "L":
+		m.logView.open = false
+		return nil
+	case "/":
+		m.logView.searching = true
+		m.logView.input.Reset()
+		return m.logView.input.Focus()
+	case "w":
+		m.toggleWarningsOnly()
+		return nil
+	case "n":
+		m.jumpToMatch(1)
+		return nil
+	case "N":
+		m.jumpToMatch(-1)
+		return nil
+	case "home", "g":
+		m.logView.viewport.GotoTop()
+		return nil
+	case "end", "G":
+		m.logView.viewport.GotoBottom()
+		return nil
+	}
+
+	var cmd tea.Cmd
+	m.logView.viewport, cmd = m.logView.viewport.Update(msg)
+	return cmd
+}
+
+// Render the full-screen log viewer
+func (m *Model) renderLogViewer() string {
+	title := fmt.Sprintf("Message log (%d entries)", m.realTimeOutput.len())
+	if m.minLogLevel == levelWarn {
+		title += " • warnings only"
+	}
+	if m.logView.query != "" {
+		title += fmt.Sprintf(" • %q: %d matches", m.logView.query, len(m.logView.matches))
+	}
+
+	footer := m.styles.Help.Render("↑/↓ PgUp/PgDn scroll • g/G top/bottom • / search • n/N next/prev match • w warnings only • Esc/L close")
+	if m.logView.searching {
+		footer = m.logView.input.View()
+	}
+
+	body := lipgloss.JoinVertical(lipgloss.Left,
+		m.styles.Header.Render(title),
+		m.logView.viewport.View(),
+		footer,
+	)
+
+	return m.styles.Pane.Padding(0, 2).Width(max(m.windowWidth-2, 0)).Render(body)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Prometheus metrics for the tracker. The metric names and labels are documented in the README; keep them stable.
+type metrics struct {
+	registry        *prometheus.Registry
+	targetRSSI      *prometheus.GaugeVec
+	rssiSeries      map[*TargetItem]rssiLabels // Labels of each target's RSSI series, deleted once they change or the target is gone
+	lockedChannel   prometheus.Gauge
+	sinceLastPacket prometheus.Gauge
+	kismetUp        prometheus.Gauge
+	requestErrors   prometheus.Counter
+	channelCommands *prometheus.CounterVec
+	targetsLocked   prometheus.Gauge
+	pollDuration    prometheus.Histogram
+}
+
+func newMetrics() *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		targetRSSI: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rizzyscope_target_rssi",
+			Help: "Last RSSI seen for each target, in dBm.",
+		}, []string{"mac", "ssid"}),
+		rssiSeries: make(map[*TargetItem]rssiLabels),
+		lockedChannel: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rizzyscope_locked_channel",
+			Help: "Channel the capture interface is locked to, 0 while hopping.",
+		}),
+		sinceLastPacket: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rizzyscope_seconds_since_last_packet",
+			Help: "Seconds since the locked target was last heard.",
+		}),
+		kismetUp: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rizzyscope_kismet_up",
+			Help: "Whether the last Kismet device listing succeeded.",
+		}),
+		requestErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "rizzyscope_kismet_request_errors_total",
+			Help: "Kismet API requests that failed.",
+		}),
+		channelCommands: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rizzyscope_channel_commands_total",
+			Help: "Channel lock and hop commands sent to Kismet.",
+		}, []string{"command"}),
+		targetsLocked: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "rizzyscope_targets_locked",
+			Help: "Targets currently locked onto (heard and their channel locked).",
+		}),
+		pollDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "rizzyscope_poll_duration_seconds",
+			Help:    "Time taken by each poll of Kismet, including the channel lock.",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		}),
+	}
+
+	m.registry.MustRegister(m.targetRSSI, m.lockedChannel, m.sinceLastPacket, m.kismetUp, m.requestErrors, m.channelCommands,
+		m.targetsLocked, m.pollDuration)
+	return m
+}
+
+// Update the metrics after a poll that took elapsed
+func (m *metrics) observe(t *hunt, result pollResult, elapsed time.Duration) {
+	m.pollDuration.Observe(elapsed.Seconds())
+
+	for _, s := range result.samples {
+		labels := rssiLabels{mac: s.mac, ssid: s.ssid}
+		if old, ok := m.rssiSeries[s.target]; ok && old != labels {
+			m.dropRSSISeries(s.target)
+		}
+		m.rssiSeries[s.target] = labels
+		m.targetRSSI.WithLabelValues(labels.mac, labels.ssid).Set(float64(s.rssi))
+	}
+	// Targets removed from the config by a reload stop being scraped
+	for target := range m.rssiSeries {
+		if !slices.Contains(t.Targets, target) {
+			m.dropRSSISeries(target)
+		}
+	}
+
+	if result.devices != nil {
+		m.kismetUp.Set(1)
+	} else {
+		m.kismetUp.Set(0)
+	}
+
+	errs := len(result.errs)
+	if result.lockErr != nil {
+		errs++
+	}
+	m.requestErrors.Add(float64(errs))
+
+	if t.ChannelLocked {
+		m.lockedChannel.Set(channelNumber(t.Channel))
+		m.targetsLocked.Set(1)
+	} else {
+		m.lockedChannel.Set(0)
+		m.targetsLocked.Set(0)
+	}
+
+	if t.LockedTarget != nil && !t.LastReceived.IsZero() {
+		m.sinceLastPacket.Set(time.Since(t.LastReceived).Seconds())
+	} else {
+		m.sinceLastPacket.Set(0)
+	}
+}
+
+// The labels of a target's RSSI series
+type rssiLabels struct {
+	mac  string
+	ssid string
+}
+
+// Forget a target's RSSI series, deleting it unless another target is reported under the same labels
+func (m *metrics) dropRSSISeries(target *TargetItem) {
+	labels := m.rssiSeries[target]
+	delete(m.rssiSeries, target)
+	for _, other := range m.rssiSeries {
+		if other == labels {
+			return
+		}
+	}
+	m.targetRSSI.DeleteLabelValues(labels.mac, labels.ssid)
+}
+
+// Nothing is locked any more, so there's no last packet to count from
+func (m *metrics) released() {
+	m.sinceLastPacket.Set(0)
+}
+
+// Count a channel lock or hop command
+func (m *metrics) channelCommand(command string) {
+	m.channelCommands.WithLabelValues(command).Inc()
+}
+
+// The channel number from a Kismet channel such as "6" or "36HT40+"
+func channelNumber(channel string) float64 {
+	end := strings.IndexFunc(channel, func(r rune) bool { return r < '0' || r > '9' })
+	if end == -1 {
+		end = len(channel)
+	}
+	n, _ := strconv.Atoi(channel[:end])
+	return float64(n)
+}
+
+// The handler for /metrics
+func (m *metrics) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{}))
+	return mux
+}
+
+// Serve /metrics on addr until Shutdown is called on the returned server
+func serveMetrics(addr string, m *metrics) (*http.Server, error) {
+	return serveHTTP("metrics", addr, m.handler())
+}
+
+// Listen on addr before returning, so a port that's taken is reported while the caller can still exit,
+// then serve handler in the background until Shutdown is called on the returned server
+func serveHTTP(name, addr string, handler http.Handler) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("%s server can't listen on %s: %v", name, addr, err)
+	}
+	server := &http.Server{Addr: listener.Addr().String(), Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Server stopped", "server", name, "err", err)
+		}
+	}()
+	return server, nil
+}
+
+// Stop the metrics or API server, giving in-flight requests a moment to finish
+func stopServer(server *http.Server) {
+	if server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		slog.Error("Error stopping server", "addr", server.Addr, "err", err)
+	}
+}
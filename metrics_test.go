@@ -0,0 +1,143 @@
+package main
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+)
+
+// Scrape the metrics the way Prometheus would and return the text exposition
+func scrapeMetrics(t *testing.T, m *metrics) string {
+	t.Helper()
+	server := httptest.NewServer(m.handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMetricsScrape(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "CoffeeShop", Channel: "36HT40+", RSSI: -52, Type: "Wi-Fi AP"})
+	h, uuid := testHunt(t, kismet, &TargetItem{Value: "10:22:33:44:55:66", TType: MAC})
+	h.metrics = newMetrics()
+	h.poll(uuid)
+
+	scrape := scrapeMetrics(t, h.metrics)
+	for _, line := range []string{
+		"# TYPE rizzyscope_target_rssi gauge",
+		`rizzyscope_target_rssi{mac="10:22:33:44:55:66",ssid="CoffeeShop"} -52`,
+		"# TYPE rizzyscope_locked_channel gauge",
+		"rizzyscope_locked_channel 36",
+		"# TYPE rizzyscope_seconds_since_last_packet gauge",
+		"# TYPE rizzyscope_kismet_up gauge",
+		"rizzyscope_kismet_up 1",
+		"# TYPE rizzyscope_kismet_request_errors_total counter",
+		"rizzyscope_kismet_request_errors_total 0",
+		"# TYPE rizzyscope_channel_commands_total counter",
+		`rizzyscope_channel_commands_total{command="lock"} 1`,
+		"# TYPE rizzyscope_targets_locked gauge",
+		"rizzyscope_targets_locked 1",
+		"# TYPE rizzyscope_poll_duration_seconds histogram",
+		"rizzyscope_poll_duration_seconds_count 1",
+	} {
+		if !strings.Contains(scrape, line+"\n") {
+			t.Errorf("scrape is missing %q", line)
+		}
+	}
+	if t.Failed() {
+		t.Logf("scrape:\n%s", scrape)
+	}
+}
+
+func TestMetricsDropRemovedTargets(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(
+		testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "CoffeeShop", Channel: "6", RSSI: -52, Type: "Wi-Fi AP"},
+		testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -70, Type: "Wi-Fi Client"},
+	)
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h, uuid := testHunt(t, kismet, ap, phone)
+	h.metrics = newMetrics()
+
+	h.poll(uuid)
+	scrape := scrapeMetrics(t, h.metrics)
+	for _, series := range []string{
+		`rizzyscope_target_rssi{mac="10:22:33:44:55:66",ssid="CoffeeShop"} -52`,
+		`rizzyscope_target_rssi{mac="32:34:00:00:00:01",ssid=""} -70`,
+	} {
+		if !strings.Contains(scrape, series) {
+			t.Errorf("scrape is missing %s:\n%s", series, scrape)
+		}
+	}
+
+	// A reload that drops the phone drops its series
+	h.Targets = []*TargetItem{ap}
+	h.poll(uuid)
+	if scrape := scrapeMetrics(t, h.metrics); strings.Contains(scrape, `mac="32:34:00:00:00:01"`) {
+		t.Errorf("removed target still scraped:\n%s", scrape)
+	}
+}
+
+func TestMetricsResetSinceLastPacketOnRelease(t *testing.T) {
+	kismet := fakeKismet(t)
+	kismet.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -57, Type: "Wi-Fi Client"})
+	h, uuid := testHunt(t, kismet, &TargetItem{Value: "32:34:00:00:00:01", TType: MAC})
+	h.metrics = newMetrics()
+	h.poll(uuid)
+
+	h.metrics.sinceLastPacket.Set(42)
+	if err := h.release(uuid); err != nil {
+		t.Fatal(err)
+	}
+	if scrape := scrapeMetrics(t, h.metrics); !strings.Contains(scrape, "rizzyscope_seconds_since_last_packet 0\n") {
+		t.Errorf("seconds since last packet not reset on release:\n%s", scrape)
+	}
+}
+
+func TestServeMetricsPortTaken(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	// The error comes back from serveMetrics, before anything starts, rather than only reaching the log
+	if server, err := serveMetrics(taken.Addr().String(), newMetrics()); err == nil {
+		stopServer(server)
+		t.Fatal("serveMetrics on a port in use succeeded")
+	} else if !strings.Contains(err.Error(), "metrics server can't listen on "+taken.Addr().String()) {
+		t.Errorf("error = %v", err)
+	}
+	if _, err := serveAPI(taken.Addr().String(), newStateAPI()); err == nil || !strings.Contains(err.Error(), "API server can't listen") {
+		t.Errorf("serveAPI on a port in use = %v, want an error", err)
+	}
+
+	// A free port is served once serveMetrics returns
+	server, err := serveMetrics("127.0.0.1:0", newMetrics())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stopServer(server)
+	resp, err := http.Get("http://" + server.Addr + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("scrape returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const doubleClickWindow = 400 * time.Millisecond // Max time between clicks on the same row to count as a double-click
+
+// Screen rectangle occupied by a pane
+type rect struct {
+	x, y, w, h int
+}
+
+func (r rect) contains(x, y int) bool {
+	return x >= r.x && x < r.x+r.w && y >= r.y && y < r.y+r.h
+}
+
+// Pane rectangles worked out from the layout so mouse events can be hit-tested
+type paneBounds struct {
+	targets      rect
+	realTime     rect
+	listItemsTop int // Screen row of the first target list item
+}
+
+// Rows the list model draws above its first item. The title bar and status bar are each one line plus one
+// line of bottom padding; with the title hidden but filtering enabled the empty title bar is just its padding.
+func (m *Model) listChromeHeight() int {
+	h := 0
+	if m.targetList.ShowTitle() {
+		h += 2
+	} else if m.targetList.FilteringEnabled() {
+		h++
+	}
+	if m.targetList.ShowStatusBar() {
+		h += 2
+	}
+	return h
+}
+
+// Lines the key hints under the target list take once wrapped to the pane's inner width
+func (m *Model) helpLines(width int) int {
+	inner := width - 2 - m.paneHPadding()*2
+	return lipgloss.Height(lipgloss.NewStyle().Width(inner).Render(m.renderCustomHelpText(inner)))
+}
+
+// Work out where the target list and real-time panes sit for the current layout and view. Called
+// whenever either changes, so View only renders.
+func (m *Model) computeBounds() {
+	m.bounds = paneBounds{}
+	if m.screenView != viewGrid && m.screenView != viewTargets {
+		return
+	}
+
+	vpad := 1
+	if m.layout.stacked {
+		vpad = 0
+	}
+	targetsH := listChromeRows + m.layout.listHeight + m.helpLines(m.layout.leftWidth) + vpad*2 + paneBorderRows
+	m.bounds.targets = rect{w: m.layout.leftWidth, h: targetsH}
+	// Border, padding and the "Targets" header sit above the list model
+	m.bounds.listItemsTop = 1 + vpad + 1 + m.listChromeHeight()
+	if m.screenView != viewGrid {
+		return
+	}
+
+	if !m.layout.stacked {
+		// The info pane starts below the taller of the target pane and the RSSI bar and chart
+		m.bounds.realTime = rect{y: max(targetsH, wideTopHeight), w: m.layout.leftWidth, h: m.layout.realTimeH + paneBorderRows}
+		return
+	}
+
+	// Stacked under the target pane, the RSSI bar pane and the chart if it's shown
+	y := targetsH + rssiBarRows + paneBorderRows
+	if m.layout.chartLevels > 0 {
+		y += m.layout.chartLevels + chartExtraRows
+	}
+	h := infoHeaderRows + m.layout.realTimeRows + tempSpacerRows + m.tempMessageCount + paneBorderRows
+	m.bounds.realTime = rect{y: y, w: m.layout.leftWidth, h: h}
+}
+
+// Map a screen row to a target list index, or -1 if the row isn't on an item
+func (m *Model) listIndexAt(y int) int {
+	perPage := m.targetList.Paginator.PerPage
+	itemHeight := 2 + 1 // Default delegate draws title and description followed by one spacing line
+	rel := y - m.bounds.listItemsTop
+	if rel < 0 || rel%itemHeight == itemHeight-1 {
+		return -1
+	}
+
+	row := rel / itemHeight
+	if row >= perPage {
+		return -1
+	}
+
+	index := m.targetList.Paginator.Page*m.targetList.Paginator.PerPage + row
+	if index >= len(m.targetList.VisibleItems()) {
+		return -1
+	}
+	return index
+}
+
+// Handle mouse wheel and click events using the pane bounds for the current layout
+func (m *Model) handleMouse(msg tea.MouseMsg, uuid string) tea.Cmd {
+	switch {
+	case m.bounds.targets.contains(msg.X, msg.Y):
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.targetList.CursorUp()
+		case tea.MouseButtonWheelDown:
+			m.targetList.CursorDown()
+		case tea.MouseButtonLeft:
+			if msg.Action != tea.MouseActionPress {
+				return nil
+			}
+			m.focus = focusTargets
+
+			index := m.listIndexAt(msg.Y)
+			if index < 0 {
+				return nil
+			}
+
+			doubleClick := index == m.lastClickIndex && time.Since(m.lastClickAt) < doubleClickWindow
+			m.lastClickIndex = index
+			m.lastClickAt = time.Now()
+
+			m.targetList.Select(index)
+			if doubleClick {
+				m.lastClickAt = time.Time{}
+				if selectedItem, ok := m.targetList.SelectedItem().(*TargetItem); ok {
+					m.pauseCycle()
+					m.selectTarget(selectedItem, uuid)
+				}
+			}
+		}
+
+	case m.bounds.realTime.contains(msg.X, msg.Y):
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			m.scrollRealTime(1)
+		case tea.MouseButtonWheelDown:
+			m.scrollRealTime(-1)
+		case tea.MouseButtonLeft:
+			if msg.Action == tea.MouseActionPress {
+				m.focus = focusRealTime
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/spf13/viper"
+)
+
+const mqttQueueSize = 256 // Messages buffered while publishing before new ones are dropped
+
+// MQTT connection states shown in the TUI
+const (
+	mqttConnecting int32 = iota
+	mqttConnected
+	mqttReconnecting
+)
+
+type mqttMessage struct {
+	topic   string
+	payload []byte
+}
+
+// Publishes live tracking state as retained JSON. Publishing happens on its own goroutine and messages are
+// dropped while the broker is unreachable, so a lost connection never holds up tracking.
+type mqttPublisher struct {
+	client mqtt.Client
+	prefix string
+	state  atomic.Int32
+	queue  chan mqttMessage
+	done   chan struct{}
+}
+
+// Connect to the broker configured in the [mqtt] section, in the background
+func newMQTTPublisher() (*mqttPublisher, error) {
+	prefix := strings.TrimSuffix(viper.GetString("mqtt.topic_prefix"), "/")
+	p := &mqttPublisher{
+		prefix: prefix,
+		queue:  make(chan mqttMessage, mqttQueueSize),
+		done:   make(chan struct{}),
+	}
+
+	clientID := viper.GetString("mqtt.client_id")
+	if clientID == "" {
+		hostname, _ := os.Hostname()
+		clientID = "rizzyscope-" + hostname
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(viper.GetString("mqtt.broker")).
+		SetClientID(clientID).
+		SetUsername(viper.GetString("mqtt.username")).
+		SetPassword(viper.GetString("mqtt.password")).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetConnectRetryInterval(5*time.Second).
+		SetMaxReconnectInterval(30*time.Second).
+		// The broker publishes this for us if we drop off without disconnecting
+		SetWill(p.topic("status"), "offline", 1, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			p.state.Store(mqttConnected)
+			c.Publish(p.topic("status"), 1, true, "online")
+			slog.Info("Connected to MQTT broker")
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			p.state.Store(mqttReconnecting)
+			slog.Warn("Lost connection to MQTT broker", "err", err)
+		}).
+		SetReconnectingHandler(func(mqtt.Client, *mqtt.ClientOptions) {
+			p.state.Store(mqttReconnecting)
+		})
+
+	tlsConfig, err := mqttTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	p.client = mqtt.NewClient(opts)
+	p.client.Connect() // With ConnectRetry this keeps trying in the background
+	go p.run()
+
+	return p, nil
+}
+
+// Build the TLS config from mqtt.tls_* keys, or nil if none are set
+func mqttTLSConfig() (*tls.Config, error) {
+	caFile := viper.GetString("mqtt.tls_ca")
+	certFile := viper.GetString("mqtt.tls_cert")
+	keyFile := viper.GetString("mqtt.tls_key")
+	insecure := viper.GetBool("mqtt.tls_insecure")
+	if caFile == "" && certFile == "" && !insecure {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: insecure}
+	if caFile != "" {
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading MQTT CA certificate: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading MQTT client certificate: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+func (p *mqttPublisher) topic(parts ...string) string {
+	return p.prefix + "/" + strings.Join(parts, "/")
+}
+
+// Publish every sample from a poll and the overall tracking state
+func (p *mqttPublisher) publish(t *hunt, samples []sample) {
+	for _, s := range samples {
+		payload := map[string]any{
+			"target":    s.target.DisplayValue(),
+			"mac":       s.mac,
+			"ssid":      s.ssid,
+			"rssi":      s.rssi,
+			"channel":   s.channel,
+			"locked":    s.locked,
+			"timestamp": s.time.UTC(),
+		}
+		if s.clients != nil {
+			payload["clients"] = s.clients
+		}
+		p.enqueue(p.topic("target", s.mac, "rssi"), payload)
+	}
+
+	state := map[string]any{
+		"searching":      t.LockedTarget == nil,
+		"channel_locked": t.ChannelLocked,
+		"rssi":           t.RSSI,
+		"timestamp":      time.Now().UTC(),
+	}
+	if t.LockedTarget != nil {
+		state["target"] = t.LockedTarget.DisplayValue()
+		state["mac"] = t.LockedTarget.Value
+		state["channel"] = t.Channel
+	}
+	p.enqueue(p.topic("state"), state)
+}
+
+func (p *mqttPublisher) enqueue(topic string, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	select {
+	case p.queue <- mqttMessage{topic: topic, payload: payload}:
+	default:
+		// Dropped; the next sample replaces it anyway since every topic is retained
+	}
+}
+
+func (p *mqttPublisher) run() {
+	defer close(p.done)
+	for msg := range p.queue {
+		if p.state.Load() != mqttConnected {
+			continue
+		}
+		p.client.Publish(msg.topic, 0, true, msg.payload)
+	}
+}
+
+// Short connection status for the TUI
+func (p *mqttPublisher) status() string {
+	switch p.state.Load() {
+	case mqttConnected:
+		return "mqtt: connected"
+	case mqttReconnecting:
+		return "mqtt: reconnecting"
+	default:
+		return "mqtt: connecting"
+	}
+}
+
+// Mark rizzyscope offline and disconnect
+func (p *mqttPublisher) Close() {
+	close(p.queue)
+	<-p.done
+
+	if p.client.IsConnected() {
+		p.client.Publish(p.topic("status"), 1, true, "offline").WaitTimeout(2 * time.Second)
+	}
+	p.client.Disconnect(250)
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Targets seen recently (see visibleFor), strongest first, at most n of them
+func (h *hunt) visibleTargets(n int) []*TargetItem {
+	var visible []*TargetItem
+	for _, target := range h.ActiveTargets() {
+		if !target.IsIgnored() && !target.LastSeen.IsZero() && time.Since(target.LastSeen) <= h.visibleFor() {
+			visible = append(visible, target)
+		}
+	}
+	slices.SortFunc(visible, func(a, b *TargetItem) int {
+		if c := cmp.Compare(b.LastRSSI, a.LastRSSI); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Title(), b.Title())
+	})
+	return visible[:min(n, len(visible))]
+}
+
+// Switch between following one locked target and watching every visible target at once. Multi-target mode
+// drops the lock and either hops channels or, with rotate_dwell_seconds set, dwells on each target's channel
+// in turn.
+func (m *Model) toggleMulti(uuid string) {
+	if m.Multi {
+		if err := m.stopRotation(uuid); err != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+		}
+		m.addTempMessage("Multi-target view off, searching for a target to lock")
+		return
+	}
+
+	if m.LockedTarget != nil {
+		if err := m.release(uuid); err != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+		}
+	}
+	m.stopCycle()
+	m.Multi = true
+	m.addTempMessage("Multi-target view: watching every visible target without locking")
+}
+
+// The strongest visible target's RSSI, standing in for the locked target's in multi-target mode
+func (m *Model) multiRSSI() int {
+	if visible := m.visibleTargets(1); len(visible) > 0 {
+		return visible[0].LastRSSI
+	}
+	return MinRSSI
+}
+
+// Render a row per visible target, each with its own bar, in place of the RSSI chart. It takes the same
+// number of lines as the chart so switching views doesn't move the other panes.
+func (m *Model) renderMultiRSSI(width int, height int) string {
+	if width <= 29 || height <= 0 {
+		return ""
+	}
+
+	lines := height + 4 // The chart's levels plus its axis and label lines
+	inner := width - 2 - m.paneHPadding()*2
+	visible := m.visibleTargets(min(m.multiCount, lines))
+
+	nameWidth := min(20, inner/3)
+	readoutWidth := 9
+	bar := m.progress
+	bar.Width = max(inner-nameWidth-readoutWidth-4, 5)
+	bar.ShowPercentage = false // Each row has its own readout
+
+	sampled := m.sampledTargets()
+	rows := make([]string, 0, lines)
+	for _, target := range visible {
+		// Marks the targets on the channel the rotation is sampling
+		marker := "  "
+		if slices.Contains(sampled, target) {
+			marker = m.styles.Good.Render("▸ ")
+		}
+		name := target.Title()
+		if lipgloss.Width(name) > nameWidth {
+			name = string([]rune(name)[:nameWidth-1]) + "…"
+		}
+		name += strings.Repeat(" ", nameWidth-lipgloss.Width(name))
+		rows = append(rows, marker+name+" "+bar.ViewAs(signalQuality(target.LastRSSI))+" "+m.formatRSSI(target.LastRSSI))
+	}
+	if len(rows) == 0 {
+		rows = append(rows, m.styles.Help.Render("No targets seen in the last "+m.visibleFor().String()))
+	}
+	for len(rows) < lines {
+		rows = append(rows, "")
+	}
+
+	return m.basePane().
+		Width(width - 2).
+		Render(strings.Join(rows, "\n"))
+}
+
+// The status line under the real-time title in multi-target mode: the channel being sampled and its targets
+func (m *Model) renderRotationStatus() string {
+	if !m.rotating() {
+		return "hopping channels"
+	}
+	left := time.Until(m.rotateUntil).Round(time.Second)
+	if m.rotateChannel == "" {
+		return fmt.Sprintf("hopping for new targets (%s left)", left)
+	}
+	names := make([]string, 0, 1)
+	for _, target := range m.sampledTargets() {
+		names = append(names, target.DisplayValue())
+	}
+	return fmt.Sprintf("sampling channel %s: %s (%s left)", m.rotateChannel, strings.Join(names, ", "), left)
+}
+
+// The RSSI chart, or the per-target bars in multi-target mode
+func (m *Model) renderSignalPane(width int, height int) string {
+	if m.Multi {
+		return m.renderMultiRSSI(width, height)
+	}
+	return m.renderRSSIOverTimeChart(width, height)
+}
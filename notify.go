@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os/exec"
+	"time"
+)
+
+// Rings the terminal bell and/or sends a desktop notification when a target is locked onto, so a hunt
+// left running in the background doesn't miss it
+type foundNotifier struct {
+	bell       bool
+	notifySend string // Path to notify-send, empty to skip desktop notifications
+	cooldown   time.Duration
+	out        io.Writer // Where the bell is written
+	last       map[string]time.Time
+}
+
+// Set up from optional.bell_on_found and optional.desktop_notify, or nil if both are off
+func newFoundNotifier(bell, desktop bool, cooldown time.Duration, out io.Writer) *foundNotifier {
+	n := &foundNotifier{bell: bell, cooldown: cooldown, out: out, last: make(map[string]time.Time)}
+
+	if desktop {
+		path, err := exec.LookPath("notify-send")
+		if err != nil {
+			slog.Warn("optional.desktop_notify is set but notify-send was not found")
+		}
+		n.notifySend = path
+	}
+
+	if !n.bell && n.notifySend == "" {
+		return nil
+	}
+	return n
+}
+
+// Notify that target was found, unless it was already notified within the cooldown
+func (n *foundNotifier) found(target *TargetItem, channel string, rssi int) {
+	key := target.ConfigKey()
+	if last, ok := n.last[key]; ok && time.Since(last) < n.cooldown {
+		return
+	}
+	n.last[key] = time.Now()
+
+	n.notify("Target found", fmt.Sprintf("%s on channel %s at %d dBm", target.DisplayValue(), channel, rssi))
+}
+
+// Notify that the locked target came within proximity_threshold_dbm. The proximity watch already keeps this
+// to once per approach, so there's no cooldown.
+func (n *foundNotifier) near(target *TargetItem, rssi int) {
+	n.notify("Target within reach", fmt.Sprintf("%s at %d dBm", target.DisplayValue(), rssi))
+}
+
+// Ring the bell and send a desktop notification, whichever are enabled
+func (n *foundNotifier) notify(title, body string) {
+	if n.bell {
+		fmt.Fprint(n.out, "\a")
+	}
+
+	if n.notifySend != "" {
+		cmd := exec.Command(n.notifySend, "--app-name=rizzyscope", title, body)
+		if err := cmd.Start(); err != nil {
+			slog.Warn("Error sending desktop notification", "err", err)
+			return
+		}
+		go cmd.Wait()
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// The profile picked with --profile, empty for the config's default_profile (if any)
+var activeProfile string
+
+// Overlay [defaults] and then [profiles.<name>] onto the top-level sections of the config read into v. name
+// falls back to default_profile; with neither, only [defaults] is applied. Tables are merged key by key, but
+// any other value, lists included, replaces the one beneath it outright.
+func applyProfile(v *viper.Viper, name string) error {
+	if defaults, ok := v.Get("defaults").(map[string]interface{}); ok {
+		if err := v.MergeConfigMap(defaults); err != nil {
+			return fmt.Errorf("error applying [defaults]: %v", err)
+		}
+	}
+
+	if name == "" {
+		name = v.GetString("default_profile")
+		if name == "" {
+			return nil
+		}
+	}
+
+	profile, ok := v.Get("profiles." + name).(map[string]interface{})
+	if !ok {
+		names := profileNames(v)
+		if len(names) == 0 {
+			return fmt.Errorf("unknown profile %q, the config has no [profiles.<name>] sections", name)
+		}
+		return fmt.Errorf("unknown profile %q, available: %s", name, strings.Join(names, ", "))
+	}
+	if err := v.MergeConfigMap(profile); err != nil {
+		return fmt.Errorf("error applying profile %q: %v", name, err)
+	}
+	return nil
+}
+
+// The profiles defined in the config, sorted
+func profileNames(v *viper.Viper) []string {
+	var names []string
+	for name := range v.GetStringMap("profiles") {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Print the profiles for --profile ?, marking the default
+func listProfiles(out io.Writer, v *viper.Viper) {
+	names := profileNames(v)
+	if len(names) == 0 {
+		fmt.Fprintln(out, "No profiles defined; add [profiles.<name>] sections to the config")
+		return
+	}
+	for _, name := range names {
+		if name == v.GetString("default_profile") {
+			name += " (default)"
+		}
+		fmt.Fprintln(out, name)
+	}
+}
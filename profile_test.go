@@ -0,0 +1,101 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+const profileConfig = `
+default_profile = "office"
+
+[required]
+target_mac = ["32:34:00:00:00:01", "32:34:00:00:00:02"]
+interface = ["wlan0"]
+
+[optional]
+kismet_endpoint = "127.0.0.1:2501"
+lock_dwell_seconds = 30
+
+[defaults.optional]
+stale_after_minutes = 10
+
+[profiles.office.optional]
+kismet_endpoint = "10.0.0.5:2501"
+
+[profiles.field.required]
+target_mac = ["32:34:00:00:00:09"]
+
+[profiles.field.optional]
+lock_dwell_seconds = 120
+`
+
+// Read profileConfig into a fresh viper and apply the named profile
+func readProfileConfig(t *testing.T, name string) (*viper.Viper, error) {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(strings.NewReader(profileConfig)); err != nil {
+		t.Fatal(err)
+	}
+	return v, applyProfile(v, name)
+}
+
+func TestApplyProfileOverridesScalars(t *testing.T) {
+	v, err := readProfileConfig(t, "field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetInt("optional.lock_dwell_seconds"); got != 120 {
+		t.Errorf("lock_dwell_seconds = %d, want the profile's 120", got)
+	}
+	// Keys the profile doesn't set keep the top-level value, and [defaults] fills in the rest
+	if got := v.GetString("optional.kismet_endpoint"); got != "127.0.0.1:2501" {
+		t.Errorf("kismet_endpoint = %q, want the top-level value", got)
+	}
+	if got := v.GetInt("optional.stale_after_minutes"); got != 10 {
+		t.Errorf("stale_after_minutes = %d, want the [defaults] 10", got)
+	}
+
+	// Without --profile, default_profile is used
+	v, err = readProfileConfig(t, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.GetString("optional.kismet_endpoint"); got != "10.0.0.5:2501" {
+		t.Errorf("kismet_endpoint = %q, want the office profile's", got)
+	}
+}
+
+func TestApplyProfileReplacesLists(t *testing.T) {
+	v, err := readProfileConfig(t, "field")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"32:34:00:00:00:09"}
+	if got := v.GetStringSlice("required.target_mac"); !slices.Equal(got, want) {
+		t.Errorf("target_mac = %q, want only the profile's %q", got, want)
+	}
+	if got := v.GetStringSlice("required.interface"); !slices.Equal(got, []string{"wlan0"}) {
+		t.Errorf("interface = %q, want the top-level list", got)
+	}
+}
+
+func TestApplyProfileUnknown(t *testing.T) {
+	_, err := readProfileConfig(t, "lab")
+	if err == nil || err.Error() != `unknown profile "lab", available: field, office` {
+		t.Errorf("applyProfile(lab) = %v, want it to list the available profiles", err)
+	}
+
+	v := viper.New()
+	v.SetConfigType("toml")
+	if err := v.ReadConfig(strings.NewReader("[optional]\nlock_dwell_seconds = 30\n")); err != nil {
+		t.Fatal(err)
+	}
+	err = applyProfile(v, "lab")
+	if err == nil || !strings.Contains(err.Error(), "has no [profiles.<name>] sections") {
+		t.Errorf("applyProfile(lab) without profiles = %v", err)
+	}
+}
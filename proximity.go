@@ -0,0 +1,54 @@
+package main
+
+import "math"
+
+const (
+	proximitySmoothing  = 0.3 // Weight of the newest reading in the smoothed RSSI, as in recordings
+	proximityHysteresis = 5   // dB the smoothed RSSI has to fall below the threshold before the alert can fire again
+)
+
+// Fires once when the locked target's smoothed RSSI rises to optional.proximity_threshold_dbm, the "within
+// arm's reach" signal. It re-arms only after the signal falls proximityHysteresis dB below the threshold,
+// so a reading wavering around it doesn't alert over and over.
+type proximityWatch struct {
+	threshold int
+	target    *TargetItem // Target the smoothed RSSI belongs to
+	smoothed  float64
+	near      bool
+}
+
+func newProximityWatch(threshold int) *proximityWatch {
+	return &proximityWatch{threshold: threshold}
+}
+
+// Feed a reading of the locked target, returning whether it just came within reach
+func (w *proximityWatch) observe(target *TargetItem, rssi int) bool {
+	if target != w.target {
+		w.reset()
+		w.target = target
+		w.smoothed = float64(rssi)
+	} else {
+		w.smoothed = proximitySmoothing*float64(rssi) + (1-proximitySmoothing)*w.smoothed
+	}
+
+	switch {
+	case !w.near && w.smoothed >= float64(w.threshold):
+		w.near = true
+		return true
+	case w.near && w.smoothed < float64(w.threshold-proximityHysteresis):
+		w.near = false
+	}
+	return false
+}
+
+// The smoothed RSSI, rounded to whole dBm
+func (w *proximityWatch) smoothedRSSI() int {
+	return int(math.Round(w.smoothed))
+}
+
+// Start over, e.g. when the locked target is released
+func (w *proximityWatch) reset() {
+	w.target = nil
+	w.smoothed = 0
+	w.near = false
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const recordSmoothing = 0.3 // Weight of the newest sample in the smoothed RSSI
+
+// A single RSSI reading for a target
+type sample struct {
+	time    time.Time
+	target  *TargetItem
+	mac     string
+	ssid    string
+	channel string
+	rssi    int
+	locked  bool     // Channel was locked to this target when it was read
+	clients []string // Associated clients, only known for the locked target's reading
+}
+
+var csvHeader = []string{"timestamp", "target", "mac", "channel", "rssi", "smoothed_rssi", "locked"}
+
+// Appends every sample to a CSV or JSONL file (picked by extension), flushing after each poll
+// and starting a new file once maxBytes is reached
+type recorder struct {
+	path     string
+	jsonl    bool
+	maxBytes int64 // 0 disables rotation
+
+	file *os.File
+	buf  *bufio.Writer
+	csv  *csv.Writer
+
+	started  time.Time
+	smoothed map[*TargetItem]float64
+	peaks    map[string]int // Peak RSSI by target display value
+}
+
+func newRecorder(path string, maxBytes int64) (*recorder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	r := &recorder{
+		path:     path,
+		jsonl:    ext == ".jsonl" || ext == ".json",
+		maxBytes: maxBytes,
+		started:  time.Now(),
+		smoothed: make(map[*TargetItem]float64),
+		peaks:    make(map[string]int),
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Open the recording for appending, writing the CSV header if the file is new
+func (r *recorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening recording: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error opening recording: %v", err)
+	}
+
+	r.file = file
+	r.buf = bufio.NewWriter(file)
+	r.csv = csv.NewWriter(r.buf)
+
+	if !r.jsonl && info.Size() == 0 {
+		r.csv.Write(csvHeader)
+	}
+	return nil
+}
+
+// Write a batch of samples and flush them to disk
+func (r *recorder) record(samples []sample) {
+	for _, s := range samples {
+		smoothed, ok := r.smoothed[s.target]
+		if !ok {
+			smoothed = float64(s.rssi)
+		}
+		smoothed = recordSmoothing*float64(s.rssi) + (1-recordSmoothing)*smoothed
+		r.smoothed[s.target] = smoothed
+
+		name := s.target.DisplayValue()
+		if peak, ok := r.peaks[name]; !ok || s.rssi > peak {
+			r.peaks[name] = s.rssi
+		}
+
+		if r.jsonl {
+			r.writeJSON(map[string]any{
+				"type":          "sample",
+				"timestamp":     s.time.UTC().Format(time.RFC3339Nano),
+				"target":        name,
+				"mac":           s.mac,
+				"channel":       s.channel,
+				"rssi":          s.rssi,
+				"smoothed_rssi": round1(smoothed),
+				"locked":        s.locked,
+			})
+		} else {
+			r.csv.Write([]string{
+				s.time.UTC().Format(time.RFC3339Nano),
+				name,
+				s.mac,
+				s.channel,
+				strconv.Itoa(s.rssi),
+				strconv.FormatFloat(round1(smoothed), 'f', 1, 64),
+				strconv.FormatBool(s.locked),
+			})
+		}
+	}
+
+	if err := r.flush(); err != nil {
+		slog.Error("Error writing recording", "err", err)
+		return
+	}
+
+	if r.maxBytes > 0 {
+		if info, err := r.file.Stat(); err == nil && info.Size() >= r.maxBytes {
+			if err := r.rotate(); err != nil {
+				slog.Error("Error rotating recording", "err", err)
+			}
+		}
+	}
+}
+
+func (r *recorder) writeJSON(v any) {
+	line, _ := json.Marshal(v)
+	r.buf.Write(line)
+	r.buf.WriteByte('\n')
+}
+
+func (r *recorder) flush() error {
+	r.csv.Flush()
+	if err := r.csv.Error(); err != nil {
+		return err
+	}
+	return r.buf.Flush()
+}
+
+// Move the full recording aside with a timestamp in its name and start a new one
+func (r *recorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+
+	ext := filepath.Ext(r.path)
+	rotated := fmt.Sprintf("%s-%s%s", strings.TrimSuffix(r.path, ext), time.Now().Format("20060102T150405"), ext)
+	if err := os.Rename(r.path, rotated); err != nil {
+		return err
+	}
+	slog.Info("Rotated recording", "file", rotated)
+
+	return r.open()
+}
+
+// Append the session summary (duration and peak RSSI per target) and close the file
+func (r *recorder) Close() error {
+	duration := time.Since(r.started).Round(time.Second)
+
+	targets := make([]string, 0, len(r.peaks))
+	for name := range r.peaks {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+
+	if r.jsonl {
+		r.writeJSON(map[string]any{
+			"type":             "summary",
+			"duration_seconds": duration.Seconds(),
+			"peak_rssi":        r.peaks,
+		})
+	} else {
+		// Comment lines, which CSV readers such as pandas can skip with comment='#'
+		r.csv.Flush()
+		fmt.Fprintf(r.buf, "# duration=%s\n", duration)
+		for _, name := range targets {
+			fmt.Fprintf(r.buf, "# peak_rssi %s=%d\n", name, r.peaks[name])
+		}
+	}
+
+	if err := r.flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	return r.file.Close()
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// Sent into the TUI when SIGHUP or an edit to the config file asks for the config to be re-read
+type configReloadMsg struct{}
+
+// Editors often save with several writes, or a write and a rename, so changes are settled for this long
+// before a reload
+const configSettleTime = 250 * time.Millisecond
+
+// Send a configReloadMsg into the program on every SIGHUP
+func forwardSIGHUP(p *tea.Program) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			p.Send(configReloadMsg{})
+		}
+	}()
+}
+
+// Call changed whenever the config file in use is written, created or replaced, until the returned stop
+// function is called. Does nothing if no config file was loaded. viper.WatchConfig isn't used because it
+// re-reads the config from its own goroutine, racing with the UI reading it; here the reload itself is left
+// to the caller's goroutine.
+func watchConfigFile(changed func()) (stop func(), err error) {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return func() {}, nil
+	}
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("error watching config file: %v", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error watching config file: %v", err)
+	}
+	// Watch the directory rather than the file so a save that replaces the file is still seen
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("error watching config file: %v", err)
+	}
+
+	go func() {
+		var settle *time.Timer
+		for {
+			select {
+			case e, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(e.Name) != path || !e.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+					continue
+				}
+				if settle != nil {
+					settle.Stop()
+				}
+				settle = time.AfterFunc(configSettleTime, changed)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				slog.Warn("Error watching config file", "err", err)
+			}
+		}
+	}()
+	return func() { watcher.Close() }, nil
+}
+
+// Check a config file before adopting it, so a broken edit leaves the running config alone. Environment
+// variables and flags aren't considered, since they can't have changed.
+func checkConfigFile(path, format string) error {
+	v := viper.New()
+	v.SetConfigFile(path)
+	v.SetConfigType(format)
+	if err := v.ReadInConfig(); err != nil {
+		return describeParseError(err, path, format)
+	}
+	if err := applyProfile(v, activeProfile); err != nil {
+		return err
+	}
+
+	if err := checkMACs(v.GetStringSlice("required.target_mac")); err != nil {
+		return fmt.Errorf("required.target_mac: %v", err)
+	}
+	if name := v.GetString("theme.name"); name != "" {
+		if _, ok := themePresets[name]; !ok {
+			return fmt.Errorf("unknown theme %q", name)
+		}
+	}
+	if display := v.GetString("optional.rssi_display"); display != "" {
+		if _, err := parseRSSIDisplay(display); err != nil {
+			return err
+		}
+	}
+	if v.GetInt("optional.lock_dwell_seconds") < 0 {
+		return fmt.Errorf("optional.lock_dwell_seconds can't be negative")
+	}
+	if v.GetInt("optional.stale_after_minutes") < 0 {
+		return fmt.Errorf("optional.stale_after_minutes can't be negative")
+	}
+	if v.GetInt("optional.proximity_threshold_dbm") > 0 {
+		return fmt.Errorf("optional.proximity_threshold_dbm must be negative (dBm), or 0 to disable")
+	}
+	if v.GetInt("optional.rotate_dwell_seconds") < 0 {
+		return fmt.Errorf("optional.rotate_dwell_seconds can't be negative")
+	}
+	if v.IsSet("optional.cycle_interval_seconds") && v.GetInt("optional.cycle_interval_seconds") <= 0 {
+		return fmt.Errorf("optional.cycle_interval_seconds must be positive")
+	}
+	return nil
+}
+
+// Re-read the config file, reconcile the targets with it and apply the settings that can change while
+// running: lock_dwell_seconds, stale_after_minutes, rotate_dwell_seconds, cycle_interval_seconds,
+// proximity_threshold_dbm, webhook_rssi_threshold and notify_cooldown_seconds, plus whatever applyUI (if
+// set) applies, returning the keys it changed. A config that doesn't parse or check out is rejected and
+// the old one kept. Returns a summary of the changes.
+func (h *hunt) reloadConfig(applyUI func() []string) (string, error) {
+	if path := viper.ConfigFileUsed(); path != "" {
+		if err := checkConfigFile(path, configFormat); err != nil {
+			return "", fmt.Errorf("config not reloaded, keeping the previous one: %v", err)
+		}
+	}
+	if err := readConfig(); err != nil {
+		return "", fmt.Errorf("config not reloaded, keeping the previous one: %v", err)
+	}
+
+	added, removed, lockRemoved := h.reconcile(loadTargets())
+	summary := fmt.Sprintf("Config reloaded: %d target(s) added, %d removed", added, removed)
+	if lockRemoved {
+		summary += ", locked target kept until released"
+	}
+
+	changed := h.applySettings()
+	if applyUI != nil {
+		changed = append(changed, applyUI()...)
+	}
+	if len(changed) > 0 {
+		summary += "; changed " + strings.Join(changed, ", ")
+	}
+	return summary, nil
+}
+
+// Apply the tracker's live-reloadable settings, returning the keys whose values changed
+func (h *hunt) applySettings() []string {
+	var changed []string
+
+	if dwell := time.Duration(viper.GetInt("optional.lock_dwell_seconds")) * time.Second; dwell != h.LockDwell {
+		h.LockDwell = dwell
+		changed = append(changed, "lock_dwell_seconds")
+	}
+
+	if staleAfter := time.Duration(viper.GetInt("optional.stale_after_minutes")) * time.Minute; staleAfter != h.StaleAfter {
+		h.StaleAfter = staleAfter
+		if staleAfter == 0 {
+			for _, target := range h.Targets {
+				target.Stale = false
+			}
+		}
+		changed = append(changed, "stale_after_minutes")
+	}
+
+	// Takes effect from the next channel change
+	if dwell := time.Duration(viper.GetInt("optional.rotate_dwell_seconds")) * time.Second; dwell != h.rotateDwell {
+		h.rotateDwell = dwell
+		changed = append(changed, "rotate_dwell_seconds")
+	}
+
+	// Takes effect from the next target's turn
+	if interval := time.Duration(viper.GetInt("optional.cycle_interval_seconds")) * time.Second; interval != h.cycleInterval {
+		h.cycleInterval = interval
+		changed = append(changed, "cycle_interval_seconds")
+	}
+
+	proximity := viper.GetInt("optional.proximity_threshold_dbm")
+	switch {
+	case proximity == 0 && h.proximity != nil:
+		h.proximity = nil
+		changed = append(changed, "proximity_threshold_dbm")
+	case proximity != 0 && h.proximity == nil:
+		h.proximity = newProximityWatch(proximity)
+		changed = append(changed, "proximity_threshold_dbm")
+	case proximity != 0 && proximity != h.proximity.threshold:
+		h.proximity.threshold = proximity
+		changed = append(changed, "proximity_threshold_dbm")
+	}
+
+	threshold := viper.GetInt("optional.webhook_rssi_threshold")
+	var thresholdChanged bool
+	if h.alerts != nil && h.alerts.above.threshold != threshold {
+		h.alerts.above.threshold = threshold
+		thresholdChanged = true
+	}
+	if h.syslog != nil && h.syslog.above.threshold != threshold {
+		h.syslog.above.threshold = threshold
+		thresholdChanged = true
+	}
+	if thresholdChanged {
+		changed = append(changed, "webhook_rssi_threshold")
+	}
+
+	if cooldown := time.Duration(viper.GetInt("optional.notify_cooldown_seconds")) * time.Second; h.notifier != nil && cooldown != h.notifier.cooldown {
+		h.notifier.cooldown = cooldown
+		changed = append(changed, "notify_cooldown_seconds")
+	}
+	return changed
+}
+
+// Reload the config as the tracker does, also applying the theme (if any [theme] key changed) and
+// rssi_display
+func (m *Model) reloadConfig() (string, error) {
+	theme := viper.GetStringMapString("theme")
+	display := viper.GetString("optional.rssi_display")
+
+	return m.hunt.reloadConfig(func() []string {
+		var changed []string
+		if !maps.Equal(theme, viper.GetStringMapString("theme")) {
+			m.setTheme(viper.GetString("theme.name"))
+			changed = append(changed, "theme")
+		}
+		if newDisplay := viper.GetString("optional.rssi_display"); newDisplay != display {
+			m.rssiDisplay, _ = parseRSSIDisplay(newDisplay)
+			changed = append(changed, "rssi_display")
+		}
+		return changed
+	})
+}
+
+// Replace the targets with a freshly loaded set, keeping the existing item (and so its ignore, signal and lock
+// state) for any target in both. A locked target that was removed stays until it's released. Reports whether
+// that happened.
+func (h *hunt) reconcile(fresh []*TargetItem) (added, removed int, lockRemoved bool) {
+	existing := make(map[string]*TargetItem, len(h.Targets))
+	for _, target := range h.Targets {
+		existing[target.ConfigKey()] = target
+	}
+
+	var merged []*TargetItem
+	for _, target := range fresh {
+		key := target.ConfigKey()
+		if old, ok := existing[key]; ok {
+			old.Label = target.Label
+			old.Tags = target.Tags
+			old.AlertRSSI = target.AlertRSSI
+			merged = append(merged, old)
+			delete(existing, key)
+			continue
+		}
+		merged = append(merged, target)
+		added++
+	}
+
+	// Whatever is left wasn't in the new config
+	h.orphan = nil
+	for _, target := range existing {
+		if target == h.LockedTarget {
+			lockRemoved = true
+			h.orphan = target
+			merged = append(merged, target)
+		}
+	}
+
+	h.Targets = merged
+	if !slices.Contains(h.AllTags(), h.ActiveTag) {
+		h.ActiveTag = ""
+	}
+	return added, len(existing), lockRemoved
+}
+
+// Drop the locked target from the list if it was removed from the config while locked
+func (h *hunt) dropOrphan() {
+	if h.orphan == nil {
+		return
+	}
+	h.Targets = slices.DeleteFunc(h.Targets, func(target *TargetItem) bool { return target == h.orphan })
+	h.orphan = nil
+}
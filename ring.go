@@ -0,0 +1,77 @@
+package main
+
+// Fixed-capacity buffer that overwrites its oldest element once full, so the per-tick appends to the RSSI
+// history and message buffers neither allocate nor reslice. The zero value has no room and drops
+// everything pushed to it; use newRing.
+type ring[T any] struct {
+	buf   []T
+	start int // Index in buf of the oldest element
+	n     int
+}
+
+func newRing[T any](size int) ring[T] {
+	return ring[T]{buf: make([]T, max(size, 0))}
+}
+
+func (r *ring[T]) len() int {
+	return r.n
+}
+
+// Append v, dropping the oldest element if the ring is full
+func (r *ring[T]) push(v T) {
+	if len(r.buf) == 0 {
+		return
+	}
+	if r.n < len(r.buf) {
+		r.buf[(r.start+r.n)%len(r.buf)] = v
+		r.n++
+		return
+	}
+	r.buf[r.start] = v
+	r.start = (r.start + 1) % len(r.buf)
+}
+
+// The i-th element, oldest first
+func (r *ring[T]) at(i int) T {
+	return r.buf[(r.start+i)%len(r.buf)]
+}
+
+// Remove the elements del returns true for, keeping the rest in order
+func (r *ring[T]) deleteFunc(del func(T) bool) {
+	kept := 0
+	for i := range r.n {
+		v := r.at(i)
+		if !del(v) {
+			r.buf[(r.start+kept)%len(r.buf)] = v
+			kept++
+		}
+	}
+	var zero T
+	for i := kept; i < r.n; i++ {
+		r.buf[(r.start+i)%len(r.buf)] = zero
+	}
+	r.n = kept
+}
+
+// Change the capacity, keeping the newest elements that fit
+func (r *ring[T]) resize(size int) {
+	size = max(size, 0)
+	if size == len(r.buf) {
+		return
+	}
+	resized := newRing[T](size)
+	for i := max(r.n-size, 0); i < r.n; i++ {
+		resized.push(r.at(i))
+	}
+	*r = resized
+}
+
+// A copy of the newest n elements (all of them if there are fewer), oldest first
+func (r *ring[T]) last(n int) []T {
+	n = min(max(n, 0), r.n)
+	out := make([]T, n)
+	for i := range out {
+		out[i] = r.at(r.n - n + i)
+	}
+	return out
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestRing(t *testing.T) {
+	r := newRing[int](3)
+	if r.len() != 0 || len(r.last(3)) != 0 {
+		t.Fatalf("new ring holds %v", r.last(3))
+	}
+
+	tests := []struct {
+		push int
+		want []int
+	}{
+		{1, []int{1}},
+		{2, []int{1, 2}},
+		{3, []int{1, 2, 3}},
+		// Full, so each push drops the oldest
+		{4, []int{2, 3, 4}},
+		{5, []int{3, 4, 5}},
+		{6, []int{4, 5, 6}},
+		{7, []int{5, 6, 7}},
+	}
+	for _, tt := range tests {
+		r.push(tt.push)
+		if got := r.last(r.len()); r.len() != len(tt.want) || !slices.Equal(got, tt.want) {
+			t.Errorf("after pushing %d: len %d, %v, want %v", tt.push, r.len(), got, tt.want)
+		}
+		for i, want := range tt.want {
+			if got := r.at(i); got != want {
+				t.Errorf("after pushing %d: at(%d) = %d, want %d", tt.push, i, got, want)
+			}
+		}
+	}
+	if got := r.last(2); !slices.Equal(got, []int{6, 7}) {
+		t.Errorf("last(2) = %v, want the newest two", got)
+	}
+	if got := r.last(10); !slices.Equal(got, []int{5, 6, 7}) {
+		t.Errorf("last(10) = %v, want all of them", got)
+	}
+
+	// Deleting across the wrapped end keeps the rest in order, with room to push again
+	r.deleteFunc(func(v int) bool { return v == 6 })
+	r.push(8)
+	if got := r.last(3); !slices.Equal(got, []int{5, 7, 8}) {
+		t.Errorf("after deleting 6 and pushing 8: %v", got)
+	}
+
+	r.resize(2)
+	if got := r.last(3); !slices.Equal(got, []int{7, 8}) {
+		t.Errorf("shrunk to %v, want the newest two", got)
+	}
+	r.resize(4)
+	r.push(9)
+	r.push(10)
+	if got := r.last(4); !slices.Equal(got, []int{7, 8, 9, 10}) {
+		t.Errorf("grown to %v", got)
+	}
+
+	// The zero value drops everything
+	var zero ring[int]
+	zero.push(1)
+	if zero.len() != 0 {
+		t.Errorf("zero ring holds %d elements", zero.len())
+	}
+}
+
+func BenchmarkRingPush(b *testing.B) {
+	r := newRing[int](rssiHistorySize)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		r.push(i)
+	}
+}
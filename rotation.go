@@ -0,0 +1,90 @@
+package main
+
+import (
+	"slices"
+	"time"
+)
+
+// In multi-target mode with a rotation, targets heard within this long keep their channel in the rotation
+// and their row in the view, even while the radio is sampling other channels
+const rotationMemory = 2 * time.Minute
+
+// Whether multi-target mode is dwelling on each target's channel in turn rather than hopping
+func (h *hunt) rotating() bool {
+	return h.Multi && h.rotateDwell > 0
+}
+
+// How long a target stays on the multi-target view after it was last heard
+func (h *hunt) visibleFor() time.Duration {
+	if h.rotating() {
+		return rotationMemory
+	}
+	return timeout
+}
+
+// The channels of the recently heard active targets, in target list order, each once
+func (h *hunt) rotationChannels() []string {
+	var channels []string
+	for _, target := range h.ActiveTargets() {
+		if target.IsIgnored() || target.Stale || target.Channel == "" || time.Since(target.LastSeen) > rotationMemory {
+			continue
+		}
+		if !slices.Contains(channels, target.Channel) {
+			channels = append(channels, target.Channel)
+		}
+	}
+	return channels
+}
+
+// Move the multi-target rotation along once the current window is up: lock the next target channel, and
+// after the last one hop for a window so targets not heard yet can turn up
+func (h *hunt) rotate(uuid string) error {
+	if !h.rotating() || time.Now().Before(h.rotateUntil) {
+		return nil
+	}
+	h.rotateUntil = time.Now().Add(h.rotateDwell)
+
+	// A channel that dropped out of the rotation (Index -1) starts it over
+	channels := h.rotationChannels()
+	next := ""
+	if i := slices.Index(channels, h.rotateChannel); i+1 < len(channels) {
+		next = channels[i+1]
+	}
+	if next == h.rotateChannel {
+		return nil
+	}
+
+	h.rotateChannel = next
+	if next == "" {
+		h.countChannelCommand("hop")
+		return hopChannel(uuid, h.kismetEndpoint)
+	}
+	h.countChannelCommand("lock")
+	return h.lockTo(uuid, next)
+}
+
+// The targets on the channel being sampled, empty while the rotation hops
+func (h *hunt) sampledTargets() []*TargetItem {
+	if !h.rotating() || h.rotateChannel == "" {
+		return nil
+	}
+	var sampled []*TargetItem
+	for _, target := range h.ActiveTargets() {
+		if target.Channel == h.rotateChannel && !target.IsIgnored() && !target.Stale {
+			sampled = append(sampled, target)
+		}
+	}
+	return sampled
+}
+
+// Stop the multi-target rotation, going back to hopping if it had a channel locked
+func (h *hunt) stopRotation(uuid string) error {
+	h.Multi = false
+	h.rotateUntil = time.Time{}
+	if h.rotateChannel == "" {
+		return nil
+	}
+	h.rotateChannel = ""
+	h.countChannelCommand("hop")
+	return hopChannel(uuid, h.kismetEndpoint)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How the RSSI readout is shown, set with optional.rssi_display and cycled with d
+type rssiDisplay string
+
+const (
+	rssiDBm     rssiDisplay = "dbm"
+	rssiPercent rssiDisplay = "percent"
+	rssiBars    rssiDisplay = "bars"
+)
+
+var rssiDisplays = []rssiDisplay{rssiDBm, rssiPercent, rssiBars}
+
+var signalBars = []rune("▁▂▃▄▅▆▇█")
+
+// Parse optional.rssi_display, falling back to dBm
+func parseRSSIDisplay(s string) (rssiDisplay, error) {
+	for _, d := range rssiDisplays {
+		if strings.EqualFold(s, string(d)) {
+			return d, nil
+		}
+	}
+	return rssiDBm, fmt.Errorf("unknown rssi_display %q, expected dbm, percent or bars", s)
+}
+
+func (d rssiDisplay) next() rssiDisplay {
+	for i, display := range rssiDisplays {
+		if display == d {
+			return rssiDisplays[(i+1)%len(rssiDisplays)]
+		}
+	}
+	return rssiDBm
+}
+
+// Signal strength from 0 to 1 across the MinRSSI..MaxRSSI range
+func signalQuality(rssi int) float64 {
+	return min(max(float64(rssi-MinRSSI)/float64(MaxRSSI-MinRSSI), 0), 1)
+}
+
+// Format an RSSI for the readout in the current display mode
+func (m *Model) formatRSSI(rssi int) string {
+	quality := signalQuality(rssi)
+	switch m.rssiDisplay {
+	case rssiPercent:
+		return fmt.Sprintf("%.0f%%", quality*100)
+	case rssiBars:
+		// Always at least one bar so the readout never looks empty; unlit bars are dimmed baselines
+		lit := max(int(quality*float64(len(signalBars))+0.5), 1)
+		return string(signalBars[:lit]) + m.styles.Help.Render(strings.Repeat(string(signalBars[0]), len(signalBars)-lit))
+	default:
+		return fmt.Sprintf("%d dBm", rssi)
+	}
+}
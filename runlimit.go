@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Process exit codes, listed in --help for scripts
+const (
+	exitOK       = 0 // Quit by the user, the duration elapsed, or a target was found with --exit-on-found
+	exitError    = 1 // Kismet or the config failed
+	exitUsage    = 2 // Bad flags or arguments
+	exitNotFound = 3 // The duration elapsed with --exit-on-found and no target was ever locked
+)
+
+const exitCodesHelp = `
+Exit codes:
+  0  quit, --duration elapsed, or a target was locked with --exit-on-found
+  1  error (Kismet, config or credentials)
+  2  invalid flags or arguments
+  3  --duration elapsed with --exit-on-found and no target was locked
+`
+
+// Print the flags followed by the exit codes, for --help
+func printUsage() {
+	fmt.Fprintf(os.Stderr, "Usage: rizzyscope [flags]\n       rizzyscope history|list-interfaces|completion [flags]\n\nFlags:\n")
+	pflag.PrintDefaults()
+	fmt.Fprint(os.Stderr, exitCodesHelp)
+}
+
+// When a scripted run should end on its own, from --duration and --exit-on-found
+type runLimit struct {
+	deadline    time.Time // Zero to run until quit
+	exitOnFound bool
+}
+
+// Start the clock on a run lasting duration (0 for no limit)
+func newRunLimit(duration time.Duration, exitOnFound bool, now time.Time) runLimit {
+	limit := runLimit{exitOnFound: exitOnFound}
+	if duration > 0 {
+		limit.deadline = now.Add(duration)
+	}
+	return limit
+}
+
+// Whether the run is over after a poll, with the exit code and why. A lock on the same poll the duration
+// runs out still counts as found.
+func (l runLimit) check(result pollResult, locked *TargetItem, now time.Time) (done bool, code int, reason string) {
+	if l.exitOnFound && result.locked && locked != nil {
+		return true, exitOK, fmt.Sprintf("Found target %s on channel %s", locked.DisplayValue(), result.reading.Channel)
+	}
+	if l.deadline.IsZero() || now.Before(l.deadline) {
+		return false, 0, ""
+	}
+	if l.exitOnFound {
+		return true, exitNotFound, "Duration elapsed without finding a target"
+	}
+	return true, exitOK, "Duration elapsed"
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRunLimit(t *testing.T) {
+	start := time.Date(2026, 10, 15, 14, 0, 0, 0, time.UTC)
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	heard := pollResult{locked: true, reading: &DeviceInfo{Channel: "6"}}
+
+	for _, tt := range []struct {
+		name        string
+		duration    time.Duration
+		exitOnFound bool
+		result      pollResult
+		locked      *TargetItem
+		after       time.Duration // Since the start
+		wantDone    bool
+		wantCode    int
+	}{
+		{"no limit", 0, false, pollResult{}, nil, 24 * time.Hour, false, 0},
+		{"no limit, locked", 0, false, heard, phone, 24 * time.Hour, false, 0},
+		{"before the limit", time.Minute, false, pollResult{}, nil, 59 * time.Second, false, 0},
+		{"at the limit", time.Minute, false, pollResult{}, nil, time.Minute, true, exitOK},
+		{"past the limit", time.Minute, false, pollResult{}, nil, 2 * time.Minute, true, exitOK},
+		{"found before the limit", time.Minute, true, heard, phone, 10 * time.Second, true, exitOK},
+		{"found with no limit", 0, true, heard, phone, 24 * time.Hour, true, exitOK},
+		{"found as the limit runs out", time.Minute, true, heard, phone, time.Minute, true, exitOK},
+		{"not found by the limit", time.Minute, true, pollResult{}, nil, time.Minute, true, exitNotFound},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			limit := newRunLimit(tt.duration, tt.exitOnFound, start)
+			done, code, reason := limit.check(tt.result, tt.locked, start.Add(tt.after))
+			if done != tt.wantDone || code != tt.wantCode {
+				t.Errorf("check = %v, %d (%q); want %v, %d", done, code, reason, tt.wantDone, tt.wantCode)
+			}
+			if done && reason == "" {
+				t.Error("no reason given for ending the run")
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transport for every Kismet API request. --record-session wraps it to archive responses and --replay
+// replaces it to serve them back without Kismet.
+var kismetTransport http.RoundTripper = http.DefaultTransport
+
+// One recorded Kismet API response, a line of a --record-session file
+type sessionEntry struct {
+	Time   time.Time `json:"time"`
+	Method string    `json:"method"`
+	Path   string    `json:"path"` // URL path only; the query holds the credentials
+	Status int       `json:"status,omitempty"`
+	Body   string    `json:"body,omitempty"`
+	Error  string    `json:"error,omitempty"` // Set instead of Status and Body if the request failed
+}
+
+// Archives every Kismet response to a JSON lines file as it passes through
+type recordingTransport struct {
+	base http.RoundTripper
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func newRecordingTransport(path string, base http.RoundTripper) (*recordingTransport, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session recording: %v", err)
+	}
+	return &recordingTransport{base: base, file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (r *recordingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	entry := sessionEntry{Time: time.Now(), Method: req.Method, Path: req.URL.Path}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		entry.Error = err.Error()
+		r.write(entry)
+		return nil, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	entry.Status = resp.StatusCode
+	entry.Body = string(body)
+	r.write(entry)
+	return resp, nil
+}
+
+func (r *recordingTransport) write(entry sessionEntry) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(entry); err != nil {
+		slog.Error("Error writing session recording", "err", err)
+	}
+}
+
+func (r *recordingTransport) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// Serves recorded responses in place of Kismet. Each request gets the latest response recorded for the
+// same endpoint at the equivalent point in the recording, so the session plays out at its original pace
+// (scaled by speed). Channel lock and hop commands are logged and otherwise ignored.
+type replayTransport struct {
+	responses map[string][]sessionEntry // By method and path, in time order
+	origin    time.Time                 // Time of the first recorded response
+	length    time.Duration
+	speed     float64
+
+	mu       sync.Mutex
+	start    time.Time // When the first request was replayed
+	finished bool
+}
+
+func newReplayTransport(path string, speed float64) (*replayTransport, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening session recording: %v", err)
+	}
+	defer file.Close()
+
+	r := &replayTransport{responses: make(map[string][]sessionEntry), speed: speed}
+
+	var entries []sessionEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 64*1024*1024) // Device listings can be large
+	for line := 1; scanner.Scan(); line++ {
+		var entry sessionEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error reading session recording line %d: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading session recording: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("session recording %s is empty", path)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+	r.origin = entries[0].Time
+	r.length = entries[len(entries)-1].Time.Sub(r.origin)
+	for _, entry := range entries {
+		key := entry.Method + " " + entry.Path
+		r.responses[key] = append(r.responses[key], entry)
+	}
+	return r, nil
+}
+
+// Whether a request path is a channel lock or hop command
+func isChannelCommand(path string) bool {
+	return strings.HasSuffix(path, "/set_channel.cmd") || strings.HasSuffix(path, "/set_hop.cmd")
+}
+
+func (r *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	if isChannelCommand(req.URL.Path) {
+		slog.Info("Replay: ignoring channel command", "path", req.URL.Path)
+		return syntheticResponse(req, http.StatusOK, "{}"), nil
+	}
+
+	elapsed := r.elapsed()
+
+	responses := r.responses[req.Method+" "+req.URL.Path]
+	if len(responses) == 0 {
+		return syntheticResponse(req, http.StatusNotFound, "no recorded response for "+req.URL.Path), nil
+	}
+
+	// The last response recorded by this point, or the first if the endpoint hadn't been called yet
+	i := sort.Search(len(responses), func(i int) bool { return responses[i].Time.Sub(r.origin) > elapsed })
+	entry := responses[max(i-1, 0)]
+
+	if entry.Error != "" {
+		return nil, errors.New(entry.Error)
+	}
+	return syntheticResponse(req, entry.Status, entry.Body), nil
+}
+
+// Time into the recording, counted from the first replayed request
+func (r *replayTransport) elapsed() time.Duration {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	elapsed := time.Duration(float64(time.Since(r.start)) * r.speed)
+
+	if elapsed > r.length && !r.finished {
+		r.finished = true
+		slog.Info("Replay reached the end of the recording, holding the last responses")
+	}
+	return elapsed
+}
+
+// A canned HTTP response for the replay and demo transports
+func syntheticResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	sightingBatchSize     = 100             // Sightings written per transaction at most
+	sightingFlushInterval = 2 * time.Second // Longest a sighting waits before being written
+	sightingQueueSize     = 1000            // Sightings buffered before new ones are dropped
+)
+
+// A target seen at a point in time
+type sighting struct {
+	time    time.Time
+	target  string
+	mac     string
+	ssid    string
+	channel string
+	rssi    int
+	lat     *float64 // Unknown without GPS
+	lon     *float64
+	iface   string
+}
+
+// Summary of every sighting of one MAC
+type sightingSummary struct {
+	count     int
+	firstSeen time.Time
+	lastSeen  time.Time
+	peakRSSI  int
+}
+
+// Durable storage for sightings
+type sightingStore interface {
+	insert(sightings []sighting) error
+	summary(mac string) (sightingSummary, error)
+	Close() error
+}
+
+// sightingStore backed by SQLite
+type sqliteStore struct {
+	db *sql.DB
+}
+
+// Open (creating if needed) the SQLite database at path; ":memory:" gives an in-memory database
+func openSQLiteStore(path string) (*sqliteStore, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening database: %v", err)
+	}
+	// SQLite allows a single writer, and an in-memory database only exists on its one connection
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS sightings (
+		ts      INTEGER NOT NULL,
+		target  TEXT NOT NULL,
+		mac     TEXT NOT NULL,
+		ssid    TEXT,
+		channel TEXT,
+		rssi    INTEGER NOT NULL,
+		lat     REAL,
+		lon     REAL,
+		iface   TEXT
+	);
+	CREATE INDEX IF NOT EXISTS sightings_mac_ts ON sightings (mac, ts);`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating sightings table: %v", err)
+	}
+
+	return &sqliteStore{db: db}, nil
+}
+
+func (s *sqliteStore) insert(sightings []sighting) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(`INSERT INTO sightings (ts, target, mac, ssid, channel, rssi, lat, lon, iface)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, si := range sightings {
+		_, err := stmt.Exec(si.time.UnixMilli(), si.target, si.mac, si.ssid, si.channel, si.rssi, si.lat, si.lon, si.iface)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteStore) summary(mac string) (sightingSummary, error) {
+	var (
+		summary     sightingSummary
+		first, last sql.NullInt64
+		peak        sql.NullInt64
+	)
+	err := s.db.QueryRow(`SELECT COUNT(*), MIN(ts), MAX(ts), MAX(rssi) FROM sightings WHERE mac = ?`, mac).
+		Scan(&summary.count, &first, &last, &peak)
+	if err != nil {
+		return summary, err
+	}
+
+	if summary.count > 0 {
+		summary.firstSeen = time.UnixMilli(first.Int64)
+		summary.lastSeen = time.UnixMilli(last.Int64)
+		summary.peakRSSI = int(peak.Int64)
+	}
+	return summary, nil
+}
+
+func (s *sqliteStore) Close() error {
+	return s.db.Close()
+}
+
+// Writes sightings to a store in batches on its own goroutine so polling never waits on the disk
+type sightingWriter struct {
+	store   sightingStore
+	queue   chan sighting
+	done    sync.WaitGroup
+	dropped int // Sightings dropped since the queue was last full, so a backlog is logged once rather than per sighting
+}
+
+func newSightingWriter(store sightingStore) *sightingWriter {
+	w := &sightingWriter{store: store, queue: make(chan sighting, sightingQueueSize)}
+	w.done.Add(1)
+	go w.run()
+	return w
+}
+
+// Queue the samples from a poll, dropping them if the writer has fallen too far behind. Called from the
+// polling goroutine only.
+func (w *sightingWriter) write(samples []sample, iface string) {
+	for _, s := range samples {
+		si := sighting{
+			time:    s.time,
+			target:  s.target.DisplayValue(),
+			mac:     s.mac,
+			ssid:    s.ssid,
+			channel: s.channel,
+			rssi:    s.rssi,
+			iface:   iface,
+		}
+		select {
+		case w.queue <- si:
+			if w.dropped > 0 {
+				slog.Warn("Sighting queue caught up", "dropped", w.dropped)
+				w.dropped = 0
+			}
+		default:
+			if w.dropped == 0 {
+				slog.Warn("Sighting queue full, dropping sightings until it catches up")
+			}
+			w.dropped++
+		}
+	}
+}
+
+func (w *sightingWriter) run() {
+	defer w.done.Done()
+
+	ticker := time.NewTicker(sightingFlushInterval)
+	defer ticker.Stop()
+
+	var batch []sighting
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := w.store.insert(batch); err != nil {
+			slog.Error("Error writing sightings", "err", err)
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case si, ok := <-w.queue:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, si)
+			if len(batch) >= sightingBatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
+
+// Write anything still queued and close the store
+func (w *sightingWriter) Close() error {
+	close(w.queue)
+	w.done.Wait()
+	return w.store.Close()
+}
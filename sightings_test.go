@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// sightingStore that keeps each inserted batch in memory. Inserts wait on block, if it's set, so a test
+// can back up the writer's queue.
+type memoryStore struct {
+	mu      sync.Mutex
+	batches [][]sighting
+	block   chan struct{}
+	closed  bool
+}
+
+func (s *memoryStore) insert(sightings []sighting) error {
+	if s.block != nil {
+		<-s.block
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.batches = append(s.batches, append([]sighting(nil), sightings...))
+	return nil
+}
+
+func (s *memoryStore) summary(string) (sightingSummary, error) {
+	return sightingSummary{}, nil
+}
+
+func (s *memoryStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func TestSQLiteStoreSummary(t *testing.T) {
+	store, err := openSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	start := time.Date(2026, 10, 15, 14, 25, 1, 0, time.UTC)
+	lat, lon := 51.5, -0.12
+	err = store.insert([]sighting{
+		{time: start, target: "Phone", mac: "32:34:00:00:00:01", channel: "6", rssi: -70, iface: "wlan0"},
+		{time: start.Add(time.Minute), target: "Phone", mac: "32:34:00:00:00:01", channel: "6", rssi: -48, lat: &lat, lon: &lon},
+		{time: start.Add(2 * time.Minute), target: "Laptop", mac: "32:34:00:00:00:02", channel: "11", rssi: -30},
+		{time: start.Add(3 * time.Minute), target: "Phone", mac: "32:34:00:00:00:01", channel: "11", rssi: -61},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := store.summary("32:34:00:00:00:01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.count != 3 || !summary.firstSeen.Equal(start) || !summary.lastSeen.Equal(start.Add(3*time.Minute)) || summary.peakRSSI != -48 {
+		t.Errorf("summary = %+v, want 3 sightings from %s to %s peaking at -48", summary, start, start.Add(3*time.Minute))
+	}
+	got := formatSightingSummary("32:34:00:00:00:01", summary)
+	if !strings.Contains(got, "peak RSSI:  -48 dBm") || !strings.Contains(got, "sightings:  3") {
+		t.Errorf("formatted summary:\n%s", got)
+	}
+}
+
+func TestSQLiteStoreNeverSeen(t *testing.T) {
+	store, err := openSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	summary, err := store.summary("32:34:00:00:00:09")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.count != 0 || !summary.firstSeen.IsZero() || !summary.lastSeen.IsZero() {
+		t.Errorf("summary = %+v, want no sightings", summary)
+	}
+	if got, want := formatSightingSummary("32:34:00:00:00:09", summary), "32:34:00:00:00:09: never seen"; got != want {
+		t.Errorf("formatted summary = %q, want %q", got, want)
+	}
+}
+
+func phoneSamples(n int) []sample {
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC, Label: "Phone"}
+	samples := make([]sample, n)
+	for i := range samples {
+		samples[i] = sample{time: time.Now(), target: phone, mac: phone.Value, channel: "6", rssi: -60 - i%10}
+	}
+	return samples
+}
+
+func TestSightingWriterCloseFlushes(t *testing.T) {
+	store := &memoryStore{}
+	w := newSightingWriter(store)
+
+	// Fewer than a batch, and closed well before the flush interval
+	w.write(phoneSamples(3), "wlan0")
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(store.batches) != 1 || len(store.batches[0]) != 3 {
+		t.Fatalf("batches = %v, want the 3 sightings written in one on Close", store.batches)
+	}
+	if si := store.batches[0][0]; si.target != "Phone" || si.mac != "32:34:00:00:00:01" || si.iface != "wlan0" {
+		t.Errorf("sighting = %+v", si)
+	}
+	if !store.closed {
+		t.Error("store wasn't closed")
+	}
+}
+
+func TestSightingWriterLogsOverflowOnce(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	store := &memoryStore{block: make(chan struct{})}
+	w := newSightingWriter(store)
+
+	// The writer takes one batch and waits on the store, so the queue fills and the rest are dropped
+	w.write(phoneSamples(sightingBatchSize+sightingQueueSize+50), "wlan0")
+	for range 10 {
+		w.write(phoneSamples(5), "wlan0")
+	}
+	if got := strings.Count(buf.String(), "Sighting queue full"); got != 1 {
+		t.Errorf("logged the full queue %d times, want once:\n%s", got, buf.String())
+	}
+
+	close(store.block)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	var written int
+	for _, batch := range store.batches {
+		written += len(batch)
+	}
+	if written > sightingBatchSize+sightingQueueSize {
+		t.Errorf("%d sightings written, more than a batch and a full queue", written)
+	}
+}
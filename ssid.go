@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const maxSSIDLength = 32 // Bytes, the most an 802.11 SSID element holds
+
+// An SSID from the config, flags or a targets file that couldn't be used
+type InvalidSSIDError struct {
+	Input  string // As it was given
+	Reason string
+}
+
+func (e *InvalidSSIDError) Error() string {
+	return fmt.Sprintf("invalid SSID %q: %s", e.Input, e.Reason)
+}
+
+// Trim an SSID target and check it could be one: an empty SSID would match every access point hiding its
+// name, and no SSID is longer than 32 bytes.
+func formatSSID(ssid string) (string, error) {
+	trimmed := strings.TrimSpace(ssid)
+	if trimmed == "" {
+		return "", &InvalidSSIDError{Input: ssid, Reason: "empty, which would match every hidden network"}
+	}
+	if len(trimmed) > maxSSIDLength {
+		return "", &InvalidSSIDError{Input: ssid, Reason: fmt.Sprintf("%d bytes, longer than the %d an SSID can be", len(trimmed), maxSSIDLength)}
+	}
+	return trimmed, nil
+}
+
+// One error listing every SSID in ssids that formatSSID rejects, or nil if they're all valid
+func checkSSIDs(ssids []string) error {
+	var invalid []string
+	for _, ssid := range ssids {
+		if _, err := formatSSID(ssid); err != nil {
+			invalid = append(invalid, err.Error())
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(invalid, "; "))
+}
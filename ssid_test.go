@@ -0,0 +1,53 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestFormatSSID(t *testing.T) {
+	tests := []struct {
+		ssid, want string
+		valid      bool
+	}{
+		{"CoffeeShop", "CoffeeShop", true},
+		{"  Coffee Shop ", "Coffee Shop", true},
+		{strings.Repeat("x", 32), strings.Repeat("x", 32), true},
+		{"", "", false},
+		{" \t ", "", false},
+		{strings.Repeat("x", 33), "", false},
+		{strings.Repeat("é", 17), "", false}, // 34 bytes
+	}
+	for _, test := range tests {
+		got, err := formatSSID(test.ssid)
+		if got != test.want || (err == nil) != test.valid {
+			t.Errorf("formatSSID(%q) = %q, %v", test.ssid, got, err)
+		}
+		if _, ok := err.(*InvalidSSIDError); err != nil && !ok {
+			t.Errorf("formatSSID(%q) err = %T, want *InvalidSSIDError", test.ssid, err)
+		}
+	}
+}
+
+func TestLoadTargetsSkipsInvalidSSIDs(t *testing.T) {
+	viper.Set("optional.target_ssid", []string{"", "  CoffeeShop ", "   ", strings.Repeat("x", 33)})
+	t.Cleanup(func() { viper.Set("optional.target_ssid", nil) })
+
+	targets := loadTargets()
+	if len(targets) != 1 || targets[0].Value != "CoffeeShop" || targets[0].TType != SSID {
+		t.Fatalf("targets = %+v, want just the trimmed CoffeeShop", targets)
+	}
+}
+
+func TestParseTargetLineSSID(t *testing.T) {
+	for _, line := range []string{"ssid,", "ssid,   ", "ssid," + strings.Repeat("x", 33), strings.Repeat("x", 33)} {
+		if _, err := parseTargetLine(line); err == nil {
+			t.Errorf("parseTargetLine(%q) accepted an invalid SSID", line)
+		}
+	}
+	if target, err := parseTargetLine("ssid, Coffee Shop "); err != nil || target.Value != "Coffee Shop" {
+		t.Errorf("parseTargetLine = %+v, %v", target, err)
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+)
+
+// Per-user UI preferences remembered between sessions in optional.state_file. Unlike the config this
+// isn't meant to be shared; it holds whatever was last chosen in the TUI.
+type uiState struct {
+	View        string   `json:"view,omitempty"`
+	Theme       string   `json:"theme,omitempty"`
+	Sort        string   `json:"sort,omitempty"`
+	RSSIDisplay string   `json:"rssi_display,omitempty"`
+	Percentage  *bool    `json:"show_percentage,omitempty"` // Unset until the state is first saved
+	Ignored     []string `json:"ignored,omitempty"`         // Config keys of the ignored targets
+}
+
+// Default state file, ~/.config/rizzyscope/state.json on Linux, or empty if there's no config directory
+func defaultStatePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "rizzyscope", "state.json")
+}
+
+// Read the state file. A missing file is the same as an empty state.
+func loadState(path string) (*uiState, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &uiState{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading state file: %v", err)
+	}
+
+	var state uiState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("error parsing state file %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+// Write the state file, creating its directory if needed
+func saveState(path string, state *uiState) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating state directory: %v", err)
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write a temporary file and rename it over the old one, so a crash can't leave it half written
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error writing state file: %v", err)
+	}
+	return nil
+}
+
+// Restore the remembered preferences, skipping any that no longer make sense
+func (m *Model) applyState(state *uiState) {
+	for v := viewGrid; v < viewCount; v++ {
+		if v.String() == state.View {
+			m.screenView = v
+		}
+	}
+	if _, ok := themePresets[state.Theme]; ok {
+		m.setTheme(state.Theme)
+	}
+	for _, sort := range targetSorts {
+		if string(sort) == state.Sort {
+			m.targetSort = sort
+		}
+	}
+	if state.RSSIDisplay != "" {
+		if display, err := parseRSSIDisplay(state.RSSIDisplay); err == nil {
+			m.rssiDisplay = display
+		}
+	}
+	if state.Percentage != nil {
+		m.progress.ShowPercentage = *state.Percentage
+	}
+
+	ignored := make(map[string]bool, len(state.Ignored))
+	for _, key := range state.Ignored {
+		ignored[key] = true
+	}
+	for _, target := range m.Targets {
+		if ignored[target.ConfigKey()] {
+			target.Ignored = true
+		}
+	}
+	m.applyLayout()
+}
+
+// The preferences to remember from this session
+func (m *Model) currentState() *uiState {
+	percentage := m.progress.ShowPercentage
+	state := &uiState{
+		View:        m.screenView.String(),
+		Theme:       m.themeName,
+		Sort:        string(m.targetSort),
+		RSSIDisplay: string(m.rssiDisplay),
+		Percentage:  &percentage,
+	}
+	for _, target := range m.Targets {
+		if target.IsIgnored() {
+			state.Ignored = append(state.Ignored, target.ConfigKey())
+		}
+	}
+	return state
+}
+
+// Load optional.state_file into the model, warning and carrying on with the config's settings if it
+// can't be read
+func restoreState(m *Model, path string) {
+	if path == "" {
+		return
+	}
+	state, err := loadState(path)
+	if err != nil {
+		fmt.Printf("Warning: %v, starting with default preferences\n", err)
+		return
+	}
+	m.applyState(state)
+}
+
+// Save the model's preferences to optional.state_file on exit
+func persistState(m *Model, path string) {
+	if path == "" {
+		return
+	}
+	if err := saveState(path, m.currentState()); err != nil {
+		slog.Error("Error saving UI state", "err", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	statusBarLines = 1  // Rows the status bar takes at the bottom of every pane view
+	pollHealthSize = 10 // Poll outcomes kept for the status bar's health count
+)
+
+// One-line summary of the session: poll health, interfaces, Kismet endpoint and version, uptime and the devices the
+// last poll saw. The dot is green while the latest poll succeeded and red once it fails, with the count of
+// recent polls that succeeded next to it, so a remote Kismet dropping out shows at a glance.
+func (m *Model) renderStatusBar(width int) string {
+	health := m.styles.Help.Render("●")
+	if n := m.pollHealth.len(); n > 0 {
+		ok := 0
+		for i := range n {
+			if m.pollHealth.at(i) {
+				ok++
+			}
+		}
+		dot := m.styles.Good.Render("●")
+		if !m.pollHealth.at(n - 1) {
+			dot = m.styles.Bad.Render("●")
+		}
+		health = fmt.Sprintf("%s %s", dot, m.styles.Help.Render(fmt.Sprintf("%d/%d polls ok", ok, n)))
+	}
+
+	ifaces := strings.Join(m.iface, ", ")
+	if ifaces == "" {
+		ifaces = "no interface"
+	}
+	kismet := m.kismetEndpoint
+	if m.kismetStatus != nil {
+		kismet += " (Kismet " + m.kismetStatus.Version + ")"
+	}
+	info := fmt.Sprintf(" • %s • %s • up %s • %d devices",
+		ifaces, kismet, time.Since(m.startedAt).Round(time.Second), m.devicesSeen)
+
+	return lipgloss.NewStyle().MaxWidth(width).Render(health + m.styles.Help.Render(info))
+}
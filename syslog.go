@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// Syslog severities (RFC 5424) used for tracking events
+const (
+	severityWarning = 4
+	severityNotice  = 5
+	severityInfo    = 6
+)
+
+const syslogFacilityDaemon = 3
+
+// Where syslog messages are delivered: the local daemon, or a remote one over the network
+type syslogSink interface {
+	send(severity int, msgID, msg string) error
+	Close() error
+}
+
+// Writes channel lock/unlock and alert events to syslog for SOC tooling, alongside the webhook and
+// JSON-lines outputs
+type syslogLogger struct {
+	sink    syslogSink
+	above   *rssiWatch
+	failing bool // Only the first failure after a success is logged
+}
+
+// Set up from optional.syslog_addr (remote, RFC 5424) or optional.syslog (the local daemon), or nil if
+// neither is set
+func newSyslogLogger(local bool, addr string, threshold int) (*syslogLogger, error) {
+	var sink syslogSink
+	var err error
+	switch {
+	case addr != "":
+		sink, err = newRemoteSyslog(addr)
+	case local:
+		sink, err = newLocalSyslog()
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &syslogLogger{sink: sink, above: newRSSIWatch(threshold)}, nil
+}
+
+// Log the events from a poll
+func (l *syslogLogger) observe(t *hunt, result pollResult) {
+	if result.locked {
+		l.log(severityNotice, "channel_locked", fmt.Sprintf("Locked onto target %s (%s) on channel %s at %d dBm",
+			t.LockedTarget.DisplayValue(), t.LockedTarget.Value, t.Channel, t.RSSI))
+	}
+	if result.movedFrom != "" {
+		l.log(severityNotice, eventTargetMoved, fmt.Sprintf("Target %s (%s) moved from channel %s to %s, following it",
+			t.LockedTarget.DisplayValue(), t.LockedTarget.Value, result.movedFrom, t.Channel))
+	}
+	if result.conflict != nil {
+		l.log(severityWarning, eventMACConflict, describeConflict(t.LockedTarget, result.conflict))
+	}
+	for _, s := range result.samples {
+		if l.above.crossed(s) {
+			l.log(severityWarning, alertRSSIAbove, fmt.Sprintf("Target %s (%s) is at %d dBm on channel %s",
+				s.target.DisplayValue(), s.mac, s.rssi, s.channel))
+		}
+	}
+	for _, a := range result.deauth {
+		l.log(severityWarning, eventDeauthAlert, fmt.Sprintf("Deauthentication attack on target %s (%s): %s %s",
+			t.LockedTarget.DisplayValue(), t.LockedTarget.Value, a.Header, a.Text))
+	}
+	for _, pair := range result.colocated {
+		l.log(severityWarning, alertColocation, fmt.Sprintf("Targets %s (%s) and %s (%s) are both in range",
+			pair.a.DisplayValue(), pair.a.Value, pair.b.DisplayValue(), pair.b.Value))
+	}
+	if s := result.near; s != nil {
+		l.log(severityWarning, alertProximity, fmt.Sprintf("Target %s (%s) is within reach at %d dBm on channel %s",
+			s.target.DisplayValue(), s.mac, s.rssi, s.channel))
+	}
+	if result.lost {
+		l.above.reset(t.LockedTarget)
+		l.log(severityWarning, alertTargetLost, fmt.Sprintf("Lost target %s (%s)", t.LockedTarget.DisplayValue(), t.LockedTarget.Value))
+	}
+}
+
+// Log that the locked target was released and the channel unlocked
+func (l *syslogLogger) released(target *TargetItem) {
+	l.log(severityInfo, "channel_unlocked", fmt.Sprintf("Released target %s (%s), hopping channels", target.DisplayValue(), target.Value))
+}
+
+func (l *syslogLogger) log(severity int, msgID, msg string) {
+	err := l.sink.send(severity, msgID, msg)
+	switch {
+	case err != nil && !l.failing:
+		l.failing = true
+		slog.Warn("Error writing to syslog, further failures won't be logged until it recovers", "err", err)
+	case err == nil && l.failing:
+		l.failing = false
+		slog.Info("Syslog messages are being delivered again")
+	}
+}
+
+func (l *syslogLogger) Close() error {
+	return l.sink.Close()
+}
+
+// Sends RFC 5424 messages to a remote syslog server over UDP or TCP, redialing after a failed write
+type remoteSyslog struct {
+	network  string
+	addr     string
+	hostname string
+	conn     net.Conn
+}
+
+// Parse udp://host:port, tcp://host:port or host:port (UDP)
+func newRemoteSyslog(addr string) (*remoteSyslog, error) {
+	network, hostport, ok := strings.Cut(addr, "://")
+	if !ok {
+		network, hostport = "udp", addr
+	}
+	if network != "udp" && network != "tcp" {
+		return nil, fmt.Errorf("unsupported syslog_addr scheme %q, expected udp or tcp", network)
+	}
+	if _, _, err := net.SplitHostPort(hostport); err != nil {
+		return nil, fmt.Errorf("invalid syslog_addr %q: %v", addr, err)
+	}
+	hostname, _ := os.Hostname()
+	return &remoteSyslog{network: network, addr: hostport, hostname: hostname}, nil
+}
+
+func (r *remoteSyslog) send(severity int, msgID, msg string) error {
+	if r.conn == nil {
+		conn, err := net.DialTimeout(r.network, r.addr, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		r.conn = conn
+	}
+
+	line := formatRFC5424(syslogFacilityDaemon*8+severity, time.Now(), r.hostname, os.Getpid(), msgID, msg)
+	if r.network == "tcp" {
+		// Octet counting framing (RFC 6587)
+		line = fmt.Sprintf("%d %s", len(line), line)
+	}
+
+	r.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.conn.Write([]byte(line)); err != nil {
+		r.conn.Close()
+		r.conn = nil
+		return err
+	}
+	return nil
+}
+
+func (r *remoteSyslog) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	return r.conn.Close()
+}
+
+// An RFC 5424 message without structured data
+func formatRFC5424(priority int, ts time.Time, hostname string, pid int, msgID, msg string) string {
+	if hostname == "" {
+		hostname = "-"
+	}
+	return fmt.Sprintf("<%d>1 %s %s rizzyscope %d %s - %s",
+		priority, ts.UTC().Format("2006-01-02T15:04:05.000000Z07:00"), hostname, pid, msgID, msg)
+}
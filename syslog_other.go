@@ -0,0 +1,17 @@
+//go:build windows || plan9
+
+package main
+
+import "log/slog"
+
+// There is no local syslog daemon here, so events are dropped; syslog_addr still works
+type localSyslog struct{}
+
+func newLocalSyslog() (syslogSink, error) {
+	slog.Warn("optional.syslog is not supported on this platform, use optional.syslog_addr for a remote server")
+	return localSyslog{}, nil
+}
+
+func (localSyslog) send(int, string, string) error { return nil }
+
+func (localSyslog) Close() error { return nil }
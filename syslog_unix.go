@@ -0,0 +1,37 @@
+//go:build !windows && !plan9
+
+package main
+
+import (
+	"fmt"
+	"log/syslog"
+)
+
+// Writes to the local syslog daemon
+type localSyslog struct {
+	w *syslog.Writer
+}
+
+func newLocalSyslog() (syslogSink, error) {
+	w, err := syslog.New(syslog.LOG_DAEMON|syslog.LOG_INFO, "rizzyscope")
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to syslog: %v", err)
+	}
+	return &localSyslog{w: w}, nil
+}
+
+func (l *localSyslog) send(severity int, msgID, msg string) error {
+	msg = msgID + ": " + msg
+	switch severity {
+	case severityWarning:
+		return l.w.Warning(msg)
+	case severityNotice:
+		return l.w.Notice(msg)
+	default:
+		return l.w.Info(msg)
+	}
+}
+
+func (l *localSyslog) Close() error {
+	return l.w.Close()
+}
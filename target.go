@@ -1,46 +1,84 @@
 package main
 
-type TargetType int
+import (
+	"cmp"
+	"slices"
 
-const (
-	MAC TargetType = iota + 1
-	SSID
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
 )
 
-type TargetItem struct {
-	Value string
-	TType TargetType
-	// This will store the 'value' when it is an SSID for display. The 'value' will now become a MAC
-	OriginalValue string
-	Ignored       bool
-	Search        bool
-	ChannelLocked bool
-}
+// Targets, their matching and the search state live in internal/tracker; these keep the names used
+// throughout the program
+type (
+	TargetItem = tracker.Target
+	TargetType = tracker.TargetType
+	DeviceInfo = tracker.DeviceInfo
+)
 
-func (i TargetItem) Title() string {
-	if i.TType == MAC {
-		return "MAC: " + i.Value
-	}
+const (
+	MAC  = tracker.MAC
+	SSID = tracker.SSID
+)
 
-	if i.TType == SSID && i.OriginalValue != "" {
-		return "SSID: " + i.OriginalValue
-	}
+// Order of the target list within each group, cycled with o
+type targetSort string
 
-	return "SSID: " + i.Value
-}
+const (
+	sortBySignal   targetSort = "signal" // Strongest last-seen RSSI first
+	sortByName     targetSort = "name"
+	sortByLastSeen targetSort = "last_seen" // Most recently heard first
+)
 
-func (i TargetItem) Description() string { return "" }
-func (i TargetItem) FilterValue() string { return i.Value }
+var targetSorts = []targetSort{sortBySignal, sortByName, sortByLastSeen}
 
-// Check if the TargetItem is currently being ignored
-func (t *TargetItem) IsIgnored() bool {
-	return t.Ignored
+func (s targetSort) next() targetSort {
+	for i, sort := range targetSorts {
+		if sort == s {
+			return targetSorts[(i+1)%len(targetSorts)]
+		}
+	}
+	return sortBySignal
 }
 
-// Replace addToIgnoreList and removeFromIgnoreList with a single toggle function
-func (t *TargetItem) ToggleIgnore() *TargetItem {
-	t.Ignored = !t.Ignored
-	return t
+// Returns the targets in display order: the locked target first, then active targets, then targets never
+// seen, then stale targets, then ignored targets. Active targets are ordered by strongest last-seen RSSI, name or most recently
+// seen; ties are broken by title so equal signals don't make the list jump around.
+func sortTargets(targets []*TargetItem, locked *TargetItem, by targetSort) []*TargetItem {
+	rank := func(t *TargetItem) int {
+		switch {
+		case t == locked:
+			return 0
+		case t.IsIgnored():
+			return 4
+		case t.Stale:
+			return 3
+		case t.LastSeen.IsZero():
+			return 2
+		default:
+			return 1
+		}
+	}
+
+	sorted := slices.Clone(targets)
+	slices.SortStableFunc(sorted, func(a, b *TargetItem) int {
+		if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+			return c
+		}
+		if !a.LastSeen.IsZero() && !b.LastSeen.IsZero() {
+			switch by {
+			case sortBySignal:
+				if c := cmp.Compare(b.LastRSSI, a.LastRSSI); c != 0 {
+					return c
+				}
+			case sortByLastSeen:
+				if c := b.LastSeen.Compare(a.LastSeen); c != 0 {
+					return c
+				}
+			}
+		}
+		return cmp.Compare(a.Title(), b.Title())
+	})
+	return sorted
 }
 
 // // Enables search on the target Item
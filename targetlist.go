@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Targets piped in on stdin, merged with the config's targets on every load
+var pipedTargets []*TargetItem
+
+// Whether stdin is a pipe or file rather than a terminal
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+// Read newline-delimited targets. Each line is "mac,<MAC>" or "ssid,<SSID>", or a bare value taken as a
+// MAC if it's shaped like one and an SSID otherwise. A bare value shaped like a MAC that doesn't parse as
+// one is an error, not an SSID. Blank lines and # comments are skipped.
+func readTargetList(r io.Reader) ([]*TargetItem, error) {
+	var targets []*TargetItem
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		target, err := parseTargetLine(text)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		targets = append(targets, target)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading targets: %v", err)
+	}
+	return targets, nil
+}
+
+func parseTargetLine(line string) (*TargetItem, error) {
+	kind, value, ok := strings.Cut(line, ",")
+	if !ok {
+		if macShaped(line) {
+			mac, err := formatMAC(line)
+			if err != nil {
+				return nil, err
+			}
+			return &TargetItem{Value: mac, TType: MAC}, nil
+		}
+		ssid, err := formatSSID(line)
+		if err != nil {
+			return nil, err
+		}
+		return &TargetItem{Value: ssid, TType: SSID}, nil
+	}
+
+	value = strings.TrimSpace(value)
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "mac":
+		mac, err := formatMAC(value)
+		if err != nil {
+			return nil, err
+		}
+		return &TargetItem{Value: mac, TType: MAC}, nil
+	case "ssid":
+		ssid, err := formatSSID(value)
+		if err != nil {
+			return nil, err
+		}
+		return &TargetItem{Value: ssid, TType: SSID}, nil
+	default:
+		return nil, fmt.Errorf("unknown target type %q, expected mac or ssid", kind)
+	}
+}
+
+// Drop every target with the same type and value as one before it, returning the rest in order and how
+// many were dropped
+func dedupTargets(targets []*TargetItem) ([]*TargetItem, int) {
+	seen := make(map[string]bool, len(targets))
+	var unique []*TargetItem
+	for _, target := range targets {
+		if seen[target.ConfigKey()] {
+			continue
+		}
+		seen[target.ConfigKey()] = true
+		unique = append(unique, target)
+	}
+	return unique, len(targets) - len(unique)
+}
+
+// Append copies of the piped targets, leaving duplicates for dedupTargets
+func withPipedTargets(targets []*TargetItem) []*TargetItem {
+	for _, piped := range pipedTargets {
+		targets = append(targets, &TargetItem{Value: piped.Value, TType: piped.TType})
+	}
+	return targets
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestLoadTargetsRemovesDuplicates(t *testing.T) {
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, nil)))
+	viper.Set("required.target_mac", []string{"32:34:00:00:00:01", "32-34-00-00-00-01", "3234.0000.0002", "323400000001"})
+	viper.Set("optional.target_ssid", []string{"CoffeeShop", " CoffeeShop", "coffeeshop"})
+	// Piped from stdin: one repeats the config and one repeats itself
+	pipedTargets = []*TargetItem{
+		{Value: "32:34:00:00:00:02", TType: MAC},
+		{Value: "Cafe", TType: SSID},
+		{Value: "Cafe", TType: SSID},
+	}
+	t.Cleanup(func() {
+		slog.SetDefault(previous)
+		viper.Set("required.target_mac", nil)
+		viper.Set("optional.target_ssid", nil)
+		pipedTargets = nil
+	})
+
+	// The first of each is kept, in order. SSIDs are case sensitive.
+	want := []string{"mac:32:34:00:00:00:01", "mac:32:34:00:00:00:02", "ssid:CoffeeShop", "ssid:coffeeshop", "ssid:Cafe"}
+	targets := loadTargets()
+	if len(targets) != len(want) {
+		t.Fatalf("got %d targets, want %d", len(targets), len(want))
+	}
+	for i, target := range targets {
+		if target.ConfigKey() != want[i] {
+			t.Errorf("target %d = %s, want %s", i, target.ConfigKey(), want[i])
+		}
+	}
+	if !strings.Contains(buf.String(), "Removed 5 duplicate target(s)") {
+		t.Errorf("log doesn't count every duplicate, piped ones included:\n%s", buf.String())
+	}
+}
+
+func TestDedupTargets(t *testing.T) {
+	first := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	targets := []*TargetItem{first, {Value: "32:34:00:00:00:01", TType: SSID}, {Value: "32:34:00:00:00:01", TType: MAC}}
+
+	unique, removed := dedupTargets(targets)
+	if removed != 1 || len(unique) != 2 || unique[0] != first {
+		t.Errorf("dedupTargets = %d targets, %d removed; want the MAC and the SSID with the same name kept", len(unique), removed)
+	}
+}
+
+func TestLoadTargetsAlertRSSI(t *testing.T) {
+	viper.Set("required.target_mac", []string{"32:34:00:00:00:01", "32:34:00:00:00:02"})
+	viper.Set("optional.target_alert_rssi", []string{"32:34:00:00:00:01=-60", "32:34:00:00:00:02=-60dBm"})
+	viper.Set("optional.target_tags", []string{"32:34:00:00:00:02=work"})
+	t.Cleanup(func() {
+		viper.Set("required.target_mac", nil)
+		viper.Set("optional.target_alert_rssi", nil)
+		viper.Set("optional.target_tags", nil)
+	})
+
+	targets := loadTargets()
+	if len(targets) != 2 {
+		t.Fatalf("got %d targets, want 2", len(targets))
+	}
+	if targets[0].AlertRSSI != -60 {
+		t.Errorf("alert RSSI = %d, want -60", targets[0].AlertRSSI)
+	}
+	// An invalid threshold is skipped, leaving the global one, and the rest of the target's settings still apply
+	if targets[1].AlertRSSI != 0 || len(targets[1].Tags) != 1 || targets[1].Tags[0] != "work" {
+		t.Errorf("target with an invalid alert RSSI = %+v, want no threshold and the work tag", targets[1])
+	}
+}
+
+func TestParseTargetLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		want  string // ConfigKey of the target, or "" for an error
+		isMAC bool   // Whether the error must be an InvalidMACError
+	}{
+		{"32:34:00:00:00:01", "mac:32:34:00:00:00:01", false},
+		{"3234.0000.0001", "mac:32:34:00:00:00:01", false},
+		{"mac, 323400000001", "mac:32:34:00:00:00:01", false},
+		{"Coffee Shop", "ssid:Coffee Shop", false},
+		{"HomeNetwork1", "ssid:HomeNetwork1", false},
+		{"ssid,AA:BB:CC:DD:EE:GG", "ssid:AA:BB:CC:DD:EE:GG", false},
+
+		// Shaped like a MAC, so an invalid one is an error rather than an SSID
+		{"AA:BB:CC:DD:EE:GG", "", true},
+		{"01:00:5E:00:00:01", "", true},
+		{"ff-ff-ff-ff-ff-ff", "", true},
+		{"zzbb.ccdd.eeff", "", true},
+		{"AABBCCDDEEGG", "", true},
+		{"mac,Coffee Shop", "", true},
+
+		{"bssid,32:34:00:00:00:01", "", false},
+	}
+	for _, tt := range tests {
+		target, err := parseTargetLine(tt.line)
+		if tt.want != "" {
+			if err != nil || target.ConfigKey() != tt.want {
+				t.Errorf("parseTargetLine(%q) = %+v, %v, want %s", tt.line, target, err, tt.want)
+			}
+			continue
+		}
+		var invalid *InvalidMACError
+		if err == nil || errors.As(err, &invalid) != tt.isMAC {
+			t.Errorf("parseTargetLine(%q) = %+v, %v, want an error (invalid MAC: %v)", tt.line, target, err, tt.isMAC)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/spf13/viper"
+)
+
+// Theme maps the named color roles used throughout the UI to lipgloss colors
+type Theme struct {
+	Border lipgloss.Color // Pane borders
+	Accent lipgloss.Color // Headers and highlights
+	Good   lipgloss.Color // Strong signal / healthy state
+	Warn   lipgloss.Color // Degraded state
+	Bad    lipgloss.Color // Weak signal / errors / alerts
+	Muted  lipgloss.Color // Help text and secondary information
+
+	// RSSI bar gradient from weakest to strongest; empty uses Bad and Good
+	GradientFrom lipgloss.Color
+	GradientTo   lipgloss.Color
+}
+
+// Built-in presets selectable with theme.name
+var themePresets = map[string]Theme{
+	"dark": {
+		Border: "63",
+		Accent: "#bd93f9",
+		Good:   "#50fa7b",
+		Warn:   "#f1fa8c",
+		Bad:    "#ff5555",
+		Muted:  "#626262",
+	},
+	"light": {
+		Border: "25",
+		Accent: "#5a2ca0",
+		Good:   "#2e7d32",
+		Warn:   "#b26a00",
+		Bad:    "#c62828",
+		Muted:  "#6e6e6e",
+	},
+	"dracula": {
+		Border: "#6272a4",
+		Accent: "#bd93f9",
+		Good:   "#50fa7b",
+		Warn:   "#f1fa8c",
+		Bad:    "#ff5555",
+		Muted:  "#6272a4",
+	},
+	"solarized": {
+		Border: "#2aa198",
+		Accent: "#268bd2",
+		Good:   "#859900",
+		Warn:   "#b58900",
+		Bad:    "#dc322f",
+		Muted:  "#586e75",
+	},
+}
+
+const defaultThemeName = "dark"
+
+// Preset names in the order T cycles through them
+var themeNames = []string{"dark", "light", "dracula", "solarized"}
+
+// Styles are built once from a Theme at startup and reused by every render helper
+type Styles struct {
+	Pane   lipgloss.Style // Bordered, padded pane
+	Header lipgloss.Style
+	Help   lipgloss.Style
+	Good   lipgloss.Style
+	Warn   lipgloss.Style
+	Bad    lipgloss.Style
+	Theme  Theme
+}
+
+// Build the lipgloss styles for a theme
+func NewStyles(t Theme) Styles {
+	return Styles{
+		Pane: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(t.Border).
+			Padding(1, 2),
+		Header: lipgloss.NewStyle().Bold(true).Foreground(t.Accent),
+		Help:   lipgloss.NewStyle().Foreground(t.Muted),
+		Good:   lipgloss.NewStyle().Foreground(t.Good),
+		Warn:   lipgloss.NewStyle().Foreground(t.Warn),
+		Bad:    lipgloss.NewStyle().Foreground(t.Bad),
+		Theme:  t,
+	}
+}
+
+// Load the theme from the [theme] config section. The preset named by theme.name is used as the base
+// and individual roles can be overridden. Invalid values are reported as warnings and fall back to the preset.
+func LoadTheme() (Theme, []string) {
+	return loadThemeNamed(viper.GetString("theme.name"))
+}
+
+// Load the named preset with the [theme] role overrides applied on top
+func loadThemeNamed(name string) (Theme, []string) {
+	var warnings []string
+
+	if name == "" {
+		name = defaultThemeName
+	}
+
+	theme, ok := themePresets[name]
+	if !ok {
+		warnings = append(warnings, fmt.Sprintf("unknown theme %q, using %q", name, defaultThemeName))
+		theme = themePresets[defaultThemeName]
+	}
+
+	overrides := []struct {
+		key   string
+		color *lipgloss.Color
+	}{
+		{"theme.border", &theme.Border},
+		{"theme.accent", &theme.Accent},
+		{"theme.good", &theme.Good},
+		{"theme.warn", &theme.Warn},
+		{"theme.bad", &theme.Bad},
+		{"theme.muted", &theme.Muted},
+		{"theme.gradient_from", &theme.GradientFrom},
+		{"theme.gradient_to", &theme.GradientTo},
+	}
+
+	for _, o := range overrides {
+		value := strings.TrimSpace(viper.GetString(o.key))
+		if value == "" {
+			continue
+		}
+		if !isValidColor(value) {
+			warnings = append(warnings, fmt.Sprintf("invalid color %q for %s, using %q", value, o.key, string(*o.color)))
+			continue
+		}
+		*o.color = lipgloss.Color(value)
+	}
+
+	if theme.GradientFrom == "" {
+		theme.GradientFrom = theme.Bad
+	}
+	if theme.GradientTo == "" {
+		theme.GradientTo = theme.Good
+	}
+
+	return theme, warnings
+}
+
+// Rebuild the styles from the named preset. Override warnings were already shown at startup.
+func (m *Model) setTheme(name string) {
+	theme, _ := loadThemeNamed(name)
+	m.themeName = name
+	m.styles = NewStyles(theme)
+	m.progress = newProgressBar(theme, m.progress.ShowPercentage)
+	// The next tick sets the new bar's percent and animates it up from empty
+	m.targetList.SetDelegate(newTargetDelegate(m.styles, func() *TargetItem { return m.LockedTarget }))
+	m.applyLayout()
+}
+
+// The signal quality bar in the theme's gradient, with or without the percentage after it
+func newProgressBar(theme Theme, showPercentage bool) progress.Model {
+	bar := progress.New(progress.WithGradient(string(theme.GradientFrom), string(theme.GradientTo)))
+	bar.ShowPercentage = showPercentage
+	return bar
+}
+
+// Show or hide the percentage after the signal quality bar. The bar's width includes it, so the layout
+// is reapplied to keep the pane the same size.
+func (m *Model) togglePercentage() {
+	m.progress.ShowPercentage = !m.progress.ShowPercentage
+	m.applyLayout()
+}
+
+// Switch to the next preset
+func (m *Model) cycleTheme() {
+	next := themeNames[0]
+	if i := slices.Index(themeNames, m.themeName); i >= 0 {
+		next = themeNames[(i+1)%len(themeNames)]
+	}
+	m.setTheme(next)
+	m.addTempMessage(fmt.Sprintf("Theme: %s", next))
+}
+
+// Check that a color is either an ANSI color number (0-255) or a #rgb / #rrggbb hex string
+func isValidColor(c string) bool {
+	if hex, ok := strings.CutPrefix(c, "#"); ok {
+		if len(hex) != 3 && len(hex) != 6 {
+			return false
+		}
+		_, err := strconv.ParseUint(hex, 16, 32)
+		return err == nil
+	}
+
+	n, err := strconv.Atoi(c)
+	return err == nil && n >= 0 && n <= 255
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	trackStrongRSSI = -50 // Track segments at or above this RSSI are drawn as strong in KML
+	trackMediumRSSI = -70 // and at or above this one as medium
+)
+
+// A GPS position during the hunt, annotated with the locked target's RSSI if one was being tracked
+type trackPoint struct {
+	time   time.Time
+	fix    gpsFix
+	target string // Empty while searching
+	rssi   int
+}
+
+// The path driven during the session. A new segment starts whenever the GPS fix comes back after
+// being lost, so gaps aren't drawn as straight lines.
+type huntTrack struct {
+	segments [][]trackPoint
+	gap      bool // The fix was lost since the last point
+}
+
+func newHuntTrack() *huntTrack {
+	return &huntTrack{gap: true}
+}
+
+// Add a point for the current fix, or mark a gap if there is none
+func (h *huntTrack) observe(fix *gpsFix, locked *TargetItem, rssi int) {
+	if fix == nil {
+		h.gap = true
+		return
+	}
+
+	p := trackPoint{time: time.Now(), fix: *fix}
+	if locked != nil {
+		p.target = locked.DisplayValue()
+		p.rssi = rssi
+	}
+
+	if h.gap {
+		h.segments = append(h.segments, []trackPoint{p})
+		h.gap = false
+		return
+	}
+
+	// Standing still with the same reading adds nothing to the track
+	segment := h.segments[len(h.segments)-1]
+	last := segment[len(segment)-1]
+	if last.fix == p.fix && last.target == p.target && last.rssi == p.rssi {
+		return
+	}
+	h.segments[len(h.segments)-1] = append(segment, p)
+}
+
+func (h *huntTrack) empty() bool {
+	return len(h.segments) == 0
+}
+
+// Write the track to path as KML if it ends in .kml, otherwise as GPX
+func (h *huntTrack) export(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating track file: %v", err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".kml") {
+		err = h.writeKML(file)
+	} else {
+		err = h.writeGPX(file)
+	}
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error writing track file: %v", err)
+	}
+	return file.Close()
+}
+
+// Default file name for a track exported without --record-track
+func defaultTrackPath() string {
+	return fmt.Sprintf("rizzyscope-track-%s.gpx", time.Now().Format("20060102T150405"))
+}
+
+type gpxFile struct {
+	XMLName    xml.Name `xml:"gpx"`
+	Version    string   `xml:"version,attr"`
+	Creator    string   `xml:"creator,attr"`
+	Xmlns      string   `xml:"xmlns,attr"`
+	XmlnsRizzy string   `xml:"xmlns:rizzyscope,attr"`
+	Track      gpxTrack `xml:"trk"`
+}
+
+type gpxTrack struct {
+	Name     string       `xml:"name"`
+	Segments []gpxSegment `xml:"trkseg"`
+}
+
+type gpxSegment struct {
+	Points []gpxPoint `xml:"trkpt"`
+}
+
+type gpxPoint struct {
+	Lat        float64        `xml:"lat,attr"`
+	Lon        float64        `xml:"lon,attr"`
+	Ele        float64        `xml:"ele"`
+	Time       string         `xml:"time"`
+	Extensions *gpxExtensions `xml:"extensions,omitempty"`
+}
+
+type gpxExtensions struct {
+	Target string `xml:"rizzyscope:target"`
+	RSSI   int    `xml:"rizzyscope:rssi"`
+}
+
+// Write the track as GPX 1.1, with the target and RSSI in each point's extensions
+func (h *huntTrack) writeGPX(w io.Writer) error {
+	doc := gpxFile{
+		Version:    "1.1",
+		Creator:    "rizzyscope",
+		Xmlns:      "http://www.topografix.com/GPX/1/1",
+		XmlnsRizzy: "https://github.com/GobiasSomeCoffeeCo/rizzyscope",
+		Track:      gpxTrack{Name: "rizzyscope hunt"},
+	}
+
+	for _, segment := range h.segments {
+		var s gpxSegment
+		for _, p := range segment {
+			point := gpxPoint{Lat: p.fix.lat, Lon: p.fix.lon, Ele: p.fix.alt, Time: p.time.UTC().Format(time.RFC3339)}
+			if p.target != "" {
+				point.Extensions = &gpxExtensions{Target: p.target, RSSI: p.rssi}
+			}
+			s.Points = append(s.Points, point)
+		}
+		doc.Track.Segments = append(doc.Track.Segments, s)
+	}
+
+	return writeXML(w, doc)
+}
+
+type kmlFile struct {
+	XMLName  xml.Name    `xml:"kml"`
+	Xmlns    string      `xml:"xmlns,attr"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Styles     []kmlStyle     `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	ID    string `xml:"id,attr"`
+	Color string `xml:"LineStyle>color"` // aabbggrr
+	Width int    `xml:"LineStyle>width"`
+}
+
+type kmlPlacemark struct {
+	Name        string `xml:"name"`
+	StyleURL    string `xml:"styleUrl"`
+	Tessellate  int    `xml:"LineString>tessellate"`
+	Coordinates string `xml:"LineString>coordinates"`
+}
+
+var kmlStyles = []kmlStyle{
+	{ID: "strong", Color: "ff00ff00", Width: 5},
+	{ID: "medium", Color: "ff00ffff", Width: 4},
+	{ID: "weak", Color: "ff0000ff", Width: 3},
+	{ID: "searching", Color: "ff888888", Width: 2},
+}
+
+// KML style for a point's reading
+func trackStyle(p trackPoint) string {
+	switch {
+	case p.target == "":
+		return "searching"
+	case p.rssi >= trackStrongRSSI:
+		return "strong"
+	case p.rssi >= trackMediumRSSI:
+		return "medium"
+	default:
+		return "weak"
+	}
+}
+
+// Write the track as KML, splitting each segment into lines colored by the locked target's RSSI
+func (h *huntTrack) writeKML(w io.Writer) error {
+	doc := kmlFile{
+		Xmlns:    "http://www.opengis.net/kml/2.2",
+		Document: kmlDocument{Name: "rizzyscope hunt", Styles: kmlStyles},
+	}
+
+	for _, segment := range h.segments {
+		start := 0
+		for i := 1; i <= len(segment); i++ {
+			if i < len(segment) && trackStyle(segment[i]) == trackStyle(segment[start]) && segment[i].target == segment[start].target {
+				continue
+			}
+			// Each line ends on the first point of the next so the track has no breaks
+			if line := segment[start:min(i+1, len(segment))]; len(line) > 1 {
+				doc.Document.Placemarks = append(doc.Document.Placemarks, kmlLine(segment[start:i], line))
+			}
+			start = i
+		}
+	}
+
+	return writeXML(w, doc)
+}
+
+// A placemark for the line through a run of points with the same target and style, named after the
+// run's strongest reading
+func kmlLine(run, line []trackPoint) kmlPlacemark {
+	first := run[0]
+	name := "Searching"
+	if first.target != "" {
+		peak := first.rssi
+		for _, p := range run {
+			peak = max(peak, p.rssi)
+		}
+		name = fmt.Sprintf("%s, peak %d dBm", first.target, peak)
+	}
+
+	coords := make([]string, len(line))
+	for i, p := range line {
+		coords[i] = fmt.Sprintf("%g,%g,%g", p.fix.lon, p.fix.lat, p.fix.alt)
+	}
+
+	return kmlPlacemark{
+		Name:        name,
+		StyleURL:    "#" + trackStyle(first),
+		Tessellate:  1,
+		Coordinates: strings.Join(coords, " "),
+	}
+}
+
+func writeXML(w io.Writer, doc any) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
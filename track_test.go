@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// A short drive in two segments: searching, then closing in on a target, then picking up again after the
+// GPS fix was lost
+func fixedTrack() *huntTrack {
+	start := time.Date(2026, 10, 15, 14, 25, 0, 0, time.UTC)
+	point := func(seconds int, lat, lon float64, target string, rssi int) trackPoint {
+		return trackPoint{time: start.Add(time.Duration(seconds) * time.Second), fix: gpsFix{lat: lat, lon: lon, alt: 12.5},
+			target: target, rssi: rssi}
+	}
+	return &huntTrack{segments: [][]trackPoint{
+		{
+			point(0, 38.2527, -85.7585, "", 0),
+			point(2, 38.2529, -85.7581, "Phone", -78),
+			point(4, 38.2531, -85.7577, "Phone", -64),
+		},
+		{
+			point(30, 38.2540, -85.7560, "Phone", -48),
+			point(32, 38.2541, -85.7558, "Phone", -45),
+		},
+	}}
+}
+
+func TestTrackExportGolden(t *testing.T) {
+	for _, name := range []string{"track.gpx", "track.kml"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := fixedTrack().export(path); err != nil {
+				t.Fatal(err)
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, name+".golden", got)
+		})
+	}
+}
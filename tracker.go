@@ -0,0 +1,415 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
+)
+
+const (
+	sourceWaitAttempts = 10          // Lookups of a missing interface before it's reported, while Kismet may still be adding it
+	sourceRetryDelay   = time.Second // Least time between lookups of a missing interface
+)
+
+// Drives the tracker state from Kismet, sending the channel commands it calls for and feeding every
+// poll to the optional outputs. Shared by the TUI and headless mode.
+type hunt struct {
+	*tracker.State
+
+	kismetEndpoint string // host:port from parseKismetEndpoint, with IPv6 hosts in brackets
+	iface          []string
+	rssiData       ring[int]        // RSSI of the locked target at each poll it was heard, for the chart
+	recorder       *recorder        // Optional session recording of every sample
+	sightings      *sightingWriter  // Optional database of every sample
+	alerts         *webhookNotifier // Optional webhook alerts
+	mqtt           *mqttPublisher   // Optional MQTT publishing of samples and state
+	metrics        *metrics         // Optional Prometheus metrics
+	api            *stateAPI        // Optional read-only JSON API
+	track          *huntTrack       // Optional GPS track of the hunt
+	wigle          *wigleLog        // Optional log of every device for a WiGLE CSV
+	notifier       *foundNotifier   // Optional bell and desktop notification when a target is locked
+	syslog         *syslogLogger    // Optional lock/unlock and alert events to syslog
+	startedAt      time.Time        // When the session started
+	recentErrors   []recentError    // The latest poll errors, at most recentErrorCount
+	dumpDir        string           // Where SIGUSR1 writes state dumps, the working directory if empty
+	reading        *DeviceInfo      // Latest reading of readingTarget, for the locked target's details
+	readingTarget  *TargetItem
+	orphan         *TargetItem      // Locked target removed from the config, dropped from the list once released
+	noChannel      *TargetItem      // Target heard without a channel, so the warning is only logged once
+	rotateDwell    time.Duration    // Time spent on each target's channel in multi-target mode, 0 to just hop
+	rotateChannel  string           // Channel the multi-target rotation is sampling, empty while hopping
+	rotateUntil    time.Time        // When the rotation moves on to the next channel
+	cycleInterval  time.Duration    // Time each target stays locked in cycle mode
+	cycling        bool             // Cycle mode: locking each target in turn for cycleInterval
+	cycleTarget    *TargetItem      // Target whose turn it is in cycle mode
+	cycleHeard     bool             // cycleTarget has been locked during its turn
+	cycleUntil     time.Time        // When the cycle moves on to the next target
+	proximity      *proximityWatch  // Optional alert when the locked target comes within reach
+	activity       activityMeter    // Packets per second from the locked target
+	handshake      handshakeWatch   // Whether a WPA handshake has been captured for the locked target
+	deauth         *deauthWatch     // Optional watch for deauthentication attacks on the locked target
+	colocation     *colocationWatch // Optional alert when both targets of a configured pair are in range
+
+	kismetStatus    *kismetStatus           // Kismet's version and datasources, read at startup; nil if they couldn't be
+	sourceUUID      string                  // Kismet datasource UUID of the first interface, once found
+	sourceErr       *interfaceNotFoundError // Set while Kismet doesn't have the first interface
+	sourceCheckedAt time.Time               // When sourceErr was last checked
+	sourceMisses    int                     // Lookups so far that didn't find the interface
+	lockByFrequency bool                    // The datasource lists channels in MHz, so they're locked by frequency
+}
+
+func newHunt(targets []*TargetItem, iface []string, kismetEndpoint string) *hunt {
+	return &hunt{
+		State:          tracker.New(targets, time.Now()),
+		kismetEndpoint: kismetEndpoint,
+		iface:          iface,
+		rssiData:       newRing[int](rssiHistorySize),
+		startedAt:      time.Now(),
+	}
+}
+
+// What happened during a single poll
+type pollResult struct {
+	devices   []map[string]interface{} // Every device Kismet saw recently, nil if the listing failed
+	found     bool                     // A target was picked to search for during this poll
+	reading   *DeviceInfo              // Latest info for the locked target, nil if it wasn't heard
+	locked    bool                     // The channel was locked to the target during this poll
+	movedFrom string                   // Channel the locked target moved off during this poll, if it did
+	cycle     *cycleStep               // The cycle moved on to the next target during this poll
+	conflict  []*DeviceInfo            // Records claiming the locked target's MAC on different channels, when that starts
+	handshake bool                     // A WPA handshake for the locked target was captured during this poll
+	lost      bool                     // The locked target went quiet during this poll
+	dropped   *TargetItem              // Locked target given up on after lockDwell without a reading
+	near      *sample                  // Reading (with the smoothed RSSI) that brought the locked target within reach
+	deauth    []kismetAlert            // Kismet deauth alerts naming the locked target, raised since the last check
+	colocated []*targetPair            // Configured pairs of targets that came into range together during this poll
+	lockErr   error                    // Set if locking the channel failed
+	errs      []error                  // Kismet API errors hit while polling
+	samples   []sample                 // Every target signal seen during this poll
+}
+
+// Look up the Kismet datasource UUID for the first configured interface. It's remembered once found. An
+// interface Kismet doesn't have is returned as an *interfaceNotFoundError and looked up again no more
+// than once per sourceRetryDelay, since Kismet may still be opening it.
+func (h *hunt) uuid() (string, error) {
+	if h.sourceUUID != "" {
+		return h.sourceUUID, nil
+	}
+	if len(h.iface) == 0 {
+		return "", errors.New("no interface configured in required.interface (--interface)")
+	}
+	if h.sourceErr != nil && time.Since(h.sourceCheckedAt) < sourceRetryDelay {
+		return "", h.sourceErr
+	}
+
+	// TODO will need to handle multiple interfaces and bands they can support.
+	// The interface chosen has no logic behind whether it can support the channel passed by another network card
+	source, err := findDatasource(h.iface[0], h.kismetEndpoint)
+	if notFound, ok := err.(*interfaceNotFoundError); ok {
+		h.sourceErr, h.sourceCheckedAt = notFound, time.Now()
+		h.sourceMisses++
+		return "", notFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get UUID: %v\nPlease check the config.toml and make sure your interface names are correct", err)
+	}
+	h.sourceUUID, h.sourceErr = source["kismet.datasource.uuid"].(string), nil
+	if h.lockByFrequency = frequencyChannels(source); h.lockByFrequency {
+		slog.Info("Datasource lists frequencies, locking channels by frequency", "interface", h.iface[0])
+	}
+	return h.sourceUUID, nil
+}
+
+// Whether the interface is missing but still within the first sourceWaitAttempts lookups, as it is for a
+// moment after Kismet starts while it adds the source, so it isn't worth reporting as an error yet
+func (h *hunt) waitingForSource() bool {
+	return h.sourceErr != nil && h.sourceMisses < sourceWaitAttempts
+}
+
+// Look up the datasource UUID, waiting for an interface Kismet is still adding. Gives up with the
+// *interfaceNotFoundError once it has been missing for sourceWaitAttempts lookups, or when ctx is done.
+func (h *hunt) awaitUUID(ctx context.Context) (string, error) {
+	for {
+		uuid, err := h.uuid()
+		if _, missing := err.(*interfaceNotFoundError); !missing || !h.waitingForSource() {
+			return uuid, err
+		}
+		if h.sourceMisses == 1 {
+			slog.Info(fmt.Sprintf("Waiting for interface %s to come up...", h.iface[0]))
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", err
+		case <-time.After(sourceRetryDelay):
+		}
+	}
+}
+
+// A warning that several devices claim the locked target's MAC, e.g. "WARNING: 10:22:33:44:55:66 seen on
+// ch 6 (-50 dBm) and ch 149 (-71 dBm) simultaneously — possible spoof"
+func describeConflict(target *TargetItem, readings []*DeviceInfo) string {
+	seen := make([]string, len(readings))
+	for i, reading := range readings {
+		seen[i] = fmt.Sprintf("ch %s (%d dBm)", reading.Channel, reading.RSSI)
+	}
+	list := strings.Join(seen, ", ")
+	if len(seen) == 2 {
+		list = seen[0] + " and " + seen[1]
+	}
+	return fmt.Sprintf("WARNING: %s seen on %s simultaneously — possible spoof", target.DisplayValue(), list)
+}
+
+// Lock the datasource to channel, by its frequency if that's how the datasource lists its channels
+func (h *hunt) lockTo(uuid, channel string) error {
+	if h.lockByFrequency {
+		if mhz, ok := tracker.ChannelFrequency(channel); ok {
+			channel = strconv.Itoa(mhz)
+		}
+	}
+	return lockChannel(uuid, channel, h.kismetEndpoint)
+}
+
+// Read Kismet's version and datasources once at startup and log them, so a report of fields coming back
+// wrong can be matched to the Kismet release. Failing here also shows the API isn't answering before the
+// first poll does.
+func (h *hunt) logKismetStatus() {
+	status, err := FetchKismetStatus(h.kismetEndpoint)
+	if err != nil {
+		slog.Warn("Couldn't read Kismet's status", "err", err)
+		return
+	}
+	h.kismetStatus = status
+	slog.Info("Connected to Kismet", "version", status.Version, "build", status.Build, "datasources", status.Sources)
+}
+
+// Run one discovery/lock/poll cycle: find a target if none is locked, read its RSSI, lock the channel
+// the first time it's heard and decay the RSSI if it has gone quiet
+func (h *hunt) poll(uuid string) pollResult {
+	var result pollResult
+	start := time.Now()
+
+	// One device listing serves discovery, the locked target's reading and the exports
+	devices, err := FetchAllDevices(h.kismetEndpoint)
+	if err == nil {
+		var conflicts []tracker.Event
+		devices, conflicts = h.DropConflicts(devices)
+		for _, conflict := range conflicts {
+			result.conflict = conflict.Readings
+		}
+		result.devices = devices
+		result.samples = updateTargetSignals(h.Targets, devices)
+	} else {
+		result.errs = append(result.errs, err)
+	}
+
+	// Discovery, giving up on a target gone for the whole dwell and the locked target's reading
+	for _, event := range h.Observe(devices, time.Now()) {
+		switch event.Kind {
+		case tracker.Dropped:
+			result.dropped = event.Target
+			if err := h.released(event.Target, uuid); err != nil {
+				result.errs = append(result.errs, fmt.Errorf("error hopping channel: %v", err))
+			}
+		case tracker.Found:
+			result.found = true
+		case tracker.Moved:
+			result.movedFrom = event.From
+			h.follow(uuid, &result)
+		case tracker.Heard:
+			result.reading = event.Reading
+			h.reading, h.readingTarget = event.Reading, h.LockedTarget
+			h.heard(uuid, event.Reading, &result)
+		}
+	}
+
+	if h.Multi {
+		if err := h.rotate(uuid); err != nil {
+			result.errs = append(result.errs, fmt.Errorf("error rotating channel: %v", err))
+		}
+	}
+	step, err := h.advanceCycle(uuid, time.Now())
+	if err != nil {
+		result.errs = append(result.errs, err)
+	}
+	result.cycle = step
+
+	if h.deauth != nil && h.LockedTarget != nil && h.ChannelLocked {
+		result.deauth = h.deauth.check(h.kismetEndpoint, h.LockedTarget, start)
+	}
+	if h.colocation != nil {
+		result.colocated = h.colocation.check(start)
+	}
+
+	if h.recorder != nil {
+		h.recorder.record(result.samples)
+	}
+	if h.sightings != nil {
+		h.sightings.write(result.samples, h.iface[0])
+	}
+	if h.alerts != nil {
+		var lost *TargetItem
+		if result.lost {
+			lost = h.LockedTarget
+		}
+		h.alerts.observe(result.samples, lost)
+		if result.near != nil {
+			h.alerts.alert(alertProximity, *result.near)
+		}
+		for _, pair := range result.colocated {
+			h.alerts.colocated(pair, start)
+		}
+	}
+	if h.syslog != nil {
+		h.syslog.observe(h, result)
+	}
+	if h.notifier != nil && result.near != nil {
+		h.notifier.near(h.LockedTarget, result.near.rssi)
+	}
+	if h.mqtt != nil {
+		h.mqtt.publish(h, result.samples)
+	}
+	if h.metrics != nil {
+		h.metrics.observe(h, result, time.Since(start))
+	}
+	if h.track != nil || h.wigle != nil {
+		fix, err := FetchGPSLocation(h.kismetEndpoint)
+		if err != nil {
+			// Not every Kismet has a GPS, so this isn't worth more than a debug message
+			slog.Debug("Error fetching GPS location", "err", err)
+		}
+		if h.track != nil {
+			h.track.observe(fix, h.LockedTarget, h.RSSI)
+		}
+		if h.wigle != nil {
+			h.wigle.observe(result.devices, fix)
+		}
+	}
+
+	for _, event := range h.Tick(time.Now()) {
+		if event.Kind == tracker.Lost {
+			result.lost = true
+		}
+	}
+
+	h.noteErrors(result, start)
+	if h.api != nil {
+		h.api.publish(h, start)
+	}
+	return result
+}
+
+// Lock the channel the first time the target being searched for is heard, and add its reading to the
+// chart and the poll's samples
+func (h *hunt) heard(uuid string, deviceInfo *DeviceInfo, result *pollResult) {
+	if !h.ChannelLocked && h.Channel == "" {
+		// Kismet has heard the target but not said on which channel, so wait for a reading that does
+		if h.noChannel != h.LockedTarget {
+			slog.Warn("Kismet reported no channel for the target, not locking until it does", "target", h.LockedTarget.DisplayValue())
+			h.noChannel = h.LockedTarget
+		}
+	} else if !h.ChannelLocked {
+		h.countChannelCommand("lock")
+		if err := h.lockTo(uuid, h.Channel); err != nil {
+			result.lockErr = err
+		} else {
+			h.Locked(time.Now())
+			result.locked = true
+			if h.notifier != nil {
+				h.notifier.found(h.LockedTarget, h.Channel, h.RSSI)
+			}
+		}
+	}
+	h.rssiData.push(h.RSSI)
+	h.activity.observe(h.LockedTarget, deviceInfo.Packets, h.LastReceived)
+	result.handshake = h.handshake.observe(h.LockedTarget, deviceInfo.Handshake)
+
+	// The reading supersedes anything the device listing said about the locked target
+	reading := sample{
+		time:    h.LastReceived,
+		target:  h.LockedTarget,
+		mac:     h.LockedTarget.Value,
+		ssid:    deviceInfo.SSID,
+		channel: h.Channel,
+		rssi:    h.RSSI,
+		locked:  h.ChannelLocked,
+		clients: deviceInfo.ClientMACs(),
+	}
+	result.samples = slices.DeleteFunc(result.samples, func(s sample) bool { return s.target == h.LockedTarget })
+	result.samples = append(result.samples, reading)
+
+	if h.proximity != nil && h.proximity.observe(h.LockedTarget, h.RSSI) {
+		reading.rssi = h.proximity.smoothedRSSI()
+		result.near = &reading
+	}
+}
+
+// Lock onto the channel the locked target moved to. If that fails the lock is dropped, so it's tried again
+// the next time the target is heard.
+func (h *hunt) follow(uuid string, result *pollResult) {
+	h.countChannelCommand("lock")
+	if err := h.lockTo(uuid, h.Channel); err != nil {
+		result.lockErr = err
+		h.ChannelLocked = false
+		return
+	}
+	h.Locked(time.Now())
+}
+
+// Start searching for the given target, unlocking the channel until it is heard. The events say whether
+// it was taken off the ignore list.
+func (h *hunt) search(target *TargetItem, uuid string) ([]tracker.Event, error) {
+	h.rotateChannel, h.rotateUntil = "", time.Time{}
+	if target != h.orphan {
+		h.dropOrphan()
+	}
+	events := h.SelectTarget(target)
+	h.activity.reset()
+	h.handshake.reset()
+	if h.metrics != nil {
+		h.metrics.released()
+	}
+	if h.proximity != nil {
+		h.proximity.reset()
+	}
+	h.countChannelCommand("hop")
+	return events, hopChannel(uuid, h.kismetEndpoint)
+}
+
+// Drop the current target and go back to hopping channels
+func (h *hunt) release(uuid string) error {
+	target := h.LockedTarget
+	h.Release()
+	return h.released(target, uuid)
+}
+
+// Follow up on the state releasing target (nil if none was locked) by going back to hopping channels
+func (h *hunt) released(target *TargetItem, uuid string) error {
+	if target != nil && h.syslog != nil {
+		h.syslog.released(target)
+	}
+	h.activity.reset()
+	h.handshake.reset()
+	if h.metrics != nil {
+		h.metrics.released()
+	}
+	if h.proximity != nil {
+		h.proximity.reset()
+	}
+	h.dropOrphan()
+	h.countChannelCommand("hop")
+	return hopChannel(uuid, h.kismetEndpoint)
+}
+
+func (h *hunt) countChannelCommand(command string) {
+	if h.metrics != nil {
+		h.metrics.channelCommand(command)
+	}
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A hunt for targets through the fake Kismet, with the datasource UUID looked up
+func testHunt(t *testing.T, server *testkismet.Server, targets ...*TargetItem) (*hunt, string) {
+	t.Helper()
+	h := newHunt(targets, []string{"wlan0"}, server.Endpoint())
+	uuid, err := h.uuid()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return h, uuid
+}
+
+func TestPollFindsAndLocksMACTarget(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(
+		testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "Neighbors", Channel: "1", RSSI: -80, Type: "Wi-Fi AP"},
+		testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -57, Type: "Wi-Fi Client"},
+	)
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h, uuid := testHunt(t, server, phone)
+
+	result := h.poll(uuid)
+	if len(result.errs) != 0 || result.lockErr != nil {
+		t.Fatalf("poll errors: %v, %v", result.errs, result.lockErr)
+	}
+	if !result.found || !result.locked || h.LockedTarget != phone || !h.ChannelLocked || h.RSSI != -57 {
+		t.Fatalf("after poll: found = %v, locked = %v, target = %v, RSSI = %d", result.found, result.locked, h.LockedTarget, h.RSSI)
+	}
+
+	requests := server.RequestsTo("/set_channel.cmd")
+	if len(requests) != 1 {
+		t.Fatalf("got %d set_channel requests, want 1", len(requests))
+	}
+	var body map[string]string
+	if err := json.Unmarshal(requests[0].Body, &body); err != nil || body["channel"] != "6" {
+		t.Errorf("set_channel body = %s, want channel 6", requests[0].Body)
+	}
+	if server.Channel(uuid) != "6" {
+		t.Errorf("Kismet locked to %q, want 6", server.Channel(uuid))
+	}
+
+	// Staying locked doesn't send the lock again
+	h.poll(uuid)
+	if n := len(server.RequestsTo("/set_channel.cmd")); n != 1 {
+		t.Errorf("got %d set_channel requests after a second poll, want 1", n)
+	}
+}
+
+func TestPollResolvesSSIDTarget(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", SSID: "CoffeeShop", Channel: "149", RSSI: -66, Type: "Wi-Fi AP"})
+	cafe := &TargetItem{Value: "CoffeeShop", TType: SSID}
+	h, uuid := testHunt(t, server, cafe)
+
+	result := h.poll(uuid)
+	if !result.locked || cafe.Value != "10:22:33:44:55:66" || cafe.OriginalValue != "CoffeeShop" {
+		t.Fatalf("locked = %v, target = %+v; want the SSID resolved to its access point", result.locked, cafe)
+	}
+	if server.Channel(uuid) != "149" {
+		t.Errorf("Kismet locked to %q, want 149", server.Channel(uuid))
+	}
+}
+
+func TestPollWaitsForChannelBeforeLocking(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", RSSI: -50})
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h, uuid := testHunt(t, server, phone)
+
+	result := h.poll(uuid)
+	if !result.found || result.locked || result.lockErr != nil || h.ChannelLocked {
+		t.Fatalf("found = %v, locked = %v, lockErr = %v; want found but not locked", result.found, result.locked, result.lockErr)
+	}
+	if n := len(server.RequestsTo("/set_channel.cmd")); n != 0 {
+		t.Fatalf("sent %d set_channel requests without a channel", n)
+	}
+
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "5180", RSSI: -50})
+	if result := h.poll(uuid); !result.locked || server.Channel(uuid) != "36" {
+		t.Errorf("locked = %v to %q, want the frequency locked as channel 36", result.locked, server.Channel(uuid))
+	}
+}
+
+func TestPollLocksByFrequency(t *testing.T) {
+	server := testkismet.New(testUser, testPassword)
+	server.AddSource("sdr0", testUUID, "2412", "2437", "2462", "5180", "5220")
+	t.Cleanup(server.Close)
+	useCredentials(t, testUser, testPassword)
+
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "44", RSSI: -50})
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h := newHunt([]*TargetItem{phone}, []string{"sdr0"}, server.Endpoint())
+	uuid, err := h.uuid()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result := h.poll(uuid); !result.locked || server.Channel(uuid) != "5220" {
+		t.Errorf("locked = %v to %q, want channel 44 locked as 5220 MHz", result.locked, server.Channel(uuid))
+	}
+	if h.Channel != "44" {
+		t.Errorf("channel = %q, want it kept as 44 for display", h.Channel)
+	}
+}
+
+func TestIgnoreKeyHopsToNextTarget(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(
+		testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -50},
+		testkismet.Device{MAC: "32:34:00:00:00:02", Channel: "11", RSSI: -70},
+	)
+	first := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	second := &TargetItem{Value: "32:34:00:00:00:02", TType: MAC}
+	h, uuid := testHunt(t, server, first, second)
+	m := &Model{
+		hunt:           h,
+		targetList:     list.New(nil, list.NewDefaultDelegate(), 40, 10),
+		realTimeOutput: newRing[logEntry](10),
+		tempMessages:   newRing[tempMessage](3),
+	}
+
+	h.poll(uuid)
+	if h.LockedTarget != first || server.Channel(uuid) != "6" {
+		t.Fatalf("locked to %v on %q, want the first target on 6", h.LockedTarget, server.Channel(uuid))
+	}
+
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")})
+	if !first.IsIgnored() || h.LockedTarget != nil {
+		t.Fatalf("ignored = %v, locked = %v; want the target ignored and released", first.IsIgnored(), h.LockedTarget)
+	}
+	if len(server.RequestsTo("/set_hop.cmd")) != 1 || server.Channel(uuid) != "" {
+		t.Fatalf("Kismet still locked to %q, want set_hop sent", server.Channel(uuid))
+	}
+
+	// Searching resumes with the target that isn't ignored
+	h.poll(uuid)
+	if h.LockedTarget != second || server.Channel(uuid) != "11" {
+		t.Errorf("locked to %v on %q, want the second target on 11", h.LockedTarget, server.Channel(uuid))
+	}
+}
+
+func TestPollFollowsTargetToNewChannel(t *testing.T) {
+	server := fakeKismet(t)
+	server.AddSource("wlan1", "5FE308BD-0000-0000-0000-000000000002") // Still hopping, so it hears every channel
+	server.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "52", RSSI: -50, Type: "Wi-Fi AP"})
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	h, uuid := testHunt(t, server, ap)
+	h.poll(uuid)
+
+	// The AP leaves its DFS channel; the lock follows once it's been heard there twice
+	server.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "100", RSSI: -50, Type: "Wi-Fi AP"})
+	if result := h.poll(uuid); result.movedFrom != "" || server.Channel(uuid) != "52" {
+		t.Fatalf("moved from %q to %q after one reading", result.movedFrom, server.Channel(uuid))
+	}
+	result := h.poll(uuid)
+	if result.movedFrom != "52" || result.lockErr != nil || h.Channel != "100" || !h.ChannelLocked {
+		t.Fatalf("movedFrom = %q, lockErr = %v, channel = %q; want a move from 52 to 100", result.movedFrom, result.lockErr, h.Channel)
+	}
+	if server.Channel(uuid) != "100" || len(server.RequestsTo("/set_channel.cmd")) != 2 {
+		t.Errorf("Kismet locked to %q, want the lock re-issued for 100", server.Channel(uuid))
+	}
+
+	events := pollEvents(h, result, time.Now())
+	if !slices.ContainsFunc(events, func(e event) bool {
+		return e.Type == eventTargetMoved && e.FromChannel == "52" && e.Channel == "100"
+	}) {
+		t.Errorf("events = %+v, want target_moved", events)
+	}
+}
+
+func TestPollReadsDeviceDetails(t *testing.T) {
+	server := fakeKismet(t)
+	apDevice := testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "44", RSSI: -50, Type: "Wi-Fi AP",
+		Packets: 14200, DataPackets: 9000, WPSState: 1 << 1, BeaconRate: 10, HTMode: "VHT80"}
+	server.SetDevices(apDevice)
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	h, uuid := testHunt(t, server, ap)
+
+	result := h.poll(uuid)
+	if !result.locked || result.reading == nil {
+		t.Fatal("didn't lock to the AP")
+	}
+	want := "WiFi 5 · 80MHz · WPS on · 10 beacons/s · 14.2k pkts"
+	if details := result.reading.Details(); details != want || result.reading.DataPackets != 9000 {
+		t.Errorf("details = %q (%d data packets), want %q", details, result.reading.DataPackets, want)
+	}
+	if e := pollEvents(h, result, time.Now()); !slices.ContainsFunc(e, func(e event) bool { return e.Type == eventChannelLocked && e.Details == want }) {
+		t.Errorf("events = %+v, want the details on channel_locked", e)
+	}
+
+	// The packet count growing between readings shows the AP is busy
+	apDevice.Packets += 500
+	server.SetDevices(apDevice)
+	h.poll(uuid)
+	if !h.activity.known || h.activity.rate <= 0 {
+		t.Errorf("activity = %+v, want a packet rate", h.activity)
+	}
+
+	// Picking another target starts the rate over
+	if _, err := h.search(ap, uuid); err != nil {
+		t.Fatal(err)
+	}
+	if h.activity.known {
+		t.Error("activity kept after selecting a target")
+	}
+}
+
+func TestPollNotesCapturedHandshake(t *testing.T) {
+	server := fakeKismet(t)
+	apDevice := testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "44", RSSI: -50, Type: "Wi-Fi AP", Crypt: "WPA2"}
+	server.SetDevices(apDevice)
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	h, uuid := testHunt(t, server, ap)
+
+	if result := h.poll(uuid); !result.locked || result.handshake || h.handshake.capturedFor(ap) {
+		t.Fatalf("locked %v, handshake %v; want a lock without a handshake", result.locked, result.handshake)
+	}
+
+	// A client reconnects, and Kismet sees messages 1 and 2
+	apDevice.Handshake = 1<<1 | 1<<2
+	server.SetDevices(apDevice)
+	if result := h.poll(uuid); !result.handshake || !h.handshake.capturedFor(ap) {
+		t.Errorf("handshake %v, captured %v; want it noted the poll it turned up", result.handshake, h.handshake.capturedFor(ap))
+	}
+	if result := h.poll(uuid); result.handshake || !h.handshake.capturedFor(ap) {
+		t.Errorf("handshake %v, captured %v; want it noted only once and kept", result.handshake, h.handshake.capturedFor(ap))
+	}
+
+	// One already captured when the target is locked is shown, but isn't news
+	if err := h.release(uuid); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.search(ap, uuid); err != nil {
+		t.Fatal(err)
+	}
+	if result := h.poll(uuid); !result.locked || result.handshake || !h.handshake.capturedFor(ap) {
+		t.Errorf("locked %v, handshake %v, captured %v; want it shown from the lock", result.locked, result.handshake, h.handshake.capturedFor(ap))
+	}
+}
+
+func TestPollWarnsOfSpoofedMAC(t *testing.T) {
+	server := fakeKismet(t)
+	server.AddSource("wlan1", "5FE308BD-0000-0000-0000-000000000002") // Still hopping, so it hears every channel
+	real := testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "6", RSSI: -50, Type: "Wi-Fi AP"}
+	server.SetDevices(real)
+	ap := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	h, uuid := testHunt(t, server, ap)
+	h.poll(uuid)
+
+	// A decoy starts beaconing with the target's BSSID on another channel
+	server.SetDevices(testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "149", RSSI: -30, Type: "Wi-Fi AP"}, real)
+	result := h.poll(uuid)
+	if len(result.conflict) != 2 || result.reading == nil || result.reading.Channel != "6" || h.RSSI != -50 {
+		t.Fatalf("conflict = %+v, reading = %+v, RSSI = %d; want both records flagged and only the real one tracked", result.conflict, result.reading, h.RSSI)
+	}
+	if ap.LastRSSI != -50 || ap.Peak.RSSI != -50 || len(result.devices) != 1 {
+		t.Errorf("last RSSI %d, peak %d, %d devices; want the decoy left out", ap.LastRSSI, ap.Peak.RSSI, len(result.devices))
+	}
+
+	want := "WARNING: 10:22:33:44:55:66 seen on ch 149 (-30 dBm) and ch 6 (-50 dBm) simultaneously — possible spoof"
+	events := pollEvents(h, result, time.Now())
+	if !slices.ContainsFunc(events, func(e event) bool {
+		return e.Type == eventMACConflict && e.Message == want && slices.Equal(e.Channels, []string{"149", "6"})
+	}) {
+		t.Errorf("events = %+v, want mac_conflict", events)
+	}
+
+	// It's only reported once, and the lock stays put
+	if result := h.poll(uuid); result.conflict != nil || result.movedFrom != "" || server.Channel(uuid) != "6" {
+		t.Errorf("conflict = %v, moved from %q, locked to %q on the next poll", result.conflict, result.movedFrom, server.Channel(uuid))
+	}
+}
+
+func TestPollLosesTargetOnOtherChannel(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -50})
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h, uuid := testHunt(t, server, phone)
+	h.poll(uuid)
+
+	// Once the target moves off the locked channel the radio no longer hears it
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "11", RSSI: -50})
+	if result := h.poll(uuid); result.reading != nil {
+		t.Errorf("heard the target on %s while locked to 6", result.reading.Channel)
+	}
+	if h.LockedTarget != phone || !h.ChannelLocked {
+		t.Error("lock dropped before the dwell or timeout")
+	}
+}
+
+func TestAwaitUUIDWaitsForInterface(t *testing.T) {
+	server := testkismet.New(testUser, testPassword)
+	t.Cleanup(server.Close)
+	useCredentials(t, testUser, testPassword)
+	h := newHunt(nil, []string{"wlan0"}, server.Endpoint())
+
+	// Kismet adds the source a moment after it starts
+	time.AfterFunc(200*time.Millisecond, func() { server.AddSource("wlan0", testUUID) })
+	uuid, err := h.awaitUUID(context.Background())
+	if err != nil || uuid != testUUID {
+		t.Fatalf("awaitUUID = %q, %v; want the UUID once the source is added", uuid, err)
+	}
+	if h.sourceMisses == 0 || h.waitingForSource() {
+		t.Errorf("sourceMisses = %d, waiting = %v; want a miss recorded and no longer waiting", h.sourceMisses, h.waitingForSource())
+	}
+}
+
+func TestAwaitUUIDGivesUp(t *testing.T) {
+	server := fakeKismet(t)
+	h := newHunt(nil, []string{"wlan9"}, server.Endpoint())
+
+	// On the last lookup it's still missing, so it's reported
+	h.sourceMisses = sourceWaitAttempts - 1
+	if _, err := h.awaitUUID(context.Background()); err == nil {
+		t.Fatal("found a UUID for an interface Kismet doesn't have")
+	} else if _, ok := err.(*interfaceNotFoundError); !ok {
+		t.Errorf("err = %v, want an *interfaceNotFoundError", err)
+	}
+
+	// Cancelling stops the wait
+	h = newHunt(nil, []string{"wlan9"}, server.Endpoint())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := h.awaitUUID(ctx); err == nil || h.sourceMisses != 1 {
+		t.Errorf("err = %v after %d lookups, want a single lookup", err, h.sourceMisses)
+	}
+}
+
+func TestMissingInterfaceShownAsWaiting(t *testing.T) {
+	server := fakeKismet(t)
+	m := &Model{
+		hunt:           newHunt(nil, []string{"wlan9"}, server.Endpoint()),
+		targetList:     list.New(nil, list.NewDefaultDelegate(), 40, 10),
+		realTimeOutput: newRing[logEntry](10),
+		tempMessages:   newRing[tempMessage](3),
+	}
+
+	m.Update(tickMsg(time.Now()))
+	if !m.sourceMissing || !m.sourceWaiting || m.fatalErr != nil {
+		t.Fatalf("missing = %v, waiting = %v, fatalErr = %v; want waiting for the interface", m.sourceMissing, m.sourceWaiting, m.fatalErr)
+	}
+	if last := m.realTimeOutput.last(1); len(last) != 1 || last[0].level == levelError {
+		t.Errorf("output = %+v, want the waiting message rather than an error", last)
+	}
+
+	// Once the lookups run out the interface is reported as missing
+	m.sourceMisses, m.sourceCheckedAt = sourceWaitAttempts-1, time.Time{}
+	m.Update(tickMsg(time.Now()))
+	if !m.sourceMissing || m.sourceWaiting {
+		t.Fatalf("missing = %v, waiting = %v; want it reported", m.sourceMissing, m.sourceWaiting)
+	}
+	if last := m.realTimeOutput.last(1); len(last) != 1 || last[0].level != levelError {
+		t.Errorf("output = %+v, want the error", last)
+	}
+}
+
+func TestUUIDForMissingInterface(t *testing.T) {
+	server := fakeKismet(t)
+	h := newHunt(nil, []string{"wlan9"}, server.Endpoint())
+
+	if _, err := h.uuid(); err == nil {
+		t.Fatal("found a UUID for an interface Kismet doesn't have")
+	} else if _, ok := err.(*interfaceNotFoundError); !ok {
+		t.Errorf("err = %v, want an *interfaceNotFoundError so the TUI can keep retrying", err)
+	}
+}
+
+func TestCycleLocksEachTargetInTurn(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(
+		testkismet.Device{MAC: "10:22:33:44:55:66", Channel: "6", RSSI: -48, Type: "Wi-Fi AP"},
+		testkismet.Device{MAC: "10:22:33:44:55:77", Channel: "44", RSSI: -60, Type: "Wi-Fi AP"},
+	)
+	office := &TargetItem{Value: "10:22:33:44:55:66", TType: MAC}
+	lobby := &TargetItem{Value: "10:22:33:44:55:77", TType: MAC}
+	away := &TargetItem{Value: "10:22:33:44:55:88", TType: MAC} // Out of range
+	ignored := &TargetItem{Value: "10:22:33:44:55:99", TType: MAC, Ignored: true}
+	h, uuid := testHunt(t, server, office, lobby, away, ignored)
+	h.cycleInterval = 30 * time.Second
+	if err := h.startCycle(uuid); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing was locked, so the first target gets the first turn and is locked
+	if result := h.poll(uuid); result.cycle == nil || result.cycle.from != nil || result.cycle.to != office {
+		t.Fatalf("cycle = %+v, want it to start with the first target", result.cycle)
+	}
+	if h.poll(uuid); h.LockedTarget != office || !h.ChannelLocked {
+		t.Fatalf("locked to %v (%v), want the first target", h.LockedTarget, h.ChannelLocked)
+	}
+
+	// It stays for the interval, then moves on
+	now := time.Now()
+	if step, _ := h.advanceCycle(uuid, now.Add(20*time.Second)); step != nil {
+		t.Errorf("moved on to %v before the interval was up", step.to)
+	}
+	step, err := h.advanceCycle(uuid, now.Add(31*time.Second))
+	if err != nil || step == nil || step.from != office || !step.heard || step.peak.RSSI != -48 || step.to != lobby {
+		t.Fatalf("step = %+v, err = %v; want the first target done at -48 dBm and the second next", step, err)
+	}
+	if got := describeCycleStep(step, (&Model{}).formatRSSI); got != "Cycle: 10:22:33:44:55:66 done (peak -48 dBm), searching for 10:22:33:44:55:77" {
+		t.Errorf("described as %q", got)
+	}
+	if h.poll(uuid); h.LockedTarget != lobby || !h.ChannelLocked || h.Channel != "44" {
+		t.Fatalf("locked to %v on ch %s, want the second target on 44", h.LockedTarget, h.Channel)
+	}
+
+	// A target that isn't heard is skipped well before the interval, and the ignored one never comes up
+	later := now.Add(62 * time.Second)
+	h.advanceCycle(uuid, later)
+	if h.poll(uuid); h.LockedTarget != away || h.ChannelLocked {
+		t.Fatalf("locked to %v (%v), want the search for the out of range target", h.LockedTarget, h.ChannelLocked)
+	}
+	step, _ = h.advanceCycle(uuid, later.Add(cycleSkipAfter+time.Second))
+	if step == nil || step.from != away || step.heard || step.to != office {
+		t.Fatalf("step = %+v, want the out of range target skipped and the cycle back at the start", step)
+	}
+
+	h.stopCycle()
+	if step, _ := h.advanceCycle(uuid, time.Now().Add(time.Hour)); step != nil || h.LockedTarget != office {
+		t.Errorf("stopped cycle moved on: step = %+v, locked to %v", step, h.LockedTarget)
+	}
+}
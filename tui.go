@@ -2,307 +2,805 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/version"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/progress"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 const (
-	padding   = 2
-	maxWidth  = 80
-	timeout   = 5 * time.Second        // Timeout duration for holding RSSI value
-	interval  = 500 * time.Millisecond // Query interval
-	decayRate = 10                     // Rate at which RSSI decays if no new data
+	padding  = 2
+	maxWidth = 80
+	timeout  = tracker.Timeout        // Timeout duration for holding RSSI value
+	interval = 500 * time.Millisecond // Query interval
+
+	rssiHistorySize = 50 // RSSI readings kept for the chart until the window is sized
+	kismetDataSize  = 10 // Devices kept for the Kismet real-time pane
+
+	quitConfirmWindow = 2 * time.Second // Time allowed for the second quit press when confirm_quit is set
+
+	deauthBannerTime = time.Minute // How long a deauth attack stays flagged on the status line
 )
 
 type tickMsg time.Time
 
+// Pane that currently receives navigation keys, cycled with Tab
+type focusPane int
+
+const (
+	focusTargets focusPane = iota
+	focusRealTime
+	focusPaneCount
+)
+
+// A short-lived message that is cleared from the UI after tempMessageDuration
+type tempMessage struct {
+	text    string
+	created time.Time
+}
+
 type Model struct {
+	*hunt
+
 	progress       progress.Model
-	rssi           int
-	rssiData       []int
-	lockedTarget   *TargetItem
-	channel        string
-	ignoreList     []string
-	iface          []string
-	lastReceived   time.Time
 	kismet         *exec.Cmd
-	targets        []*TargetItem
-	channelLocked  bool
-	realTimeOutput []string
+	realTimeOutput ring[logEntry] // The last optional.realtime_history messages, for scrollback
 	windowWidth    int
+	windowHeight   int
+	layout         layout            // Pane budgets computed from the window size
+	screenView     screenView        // Grid, or a single pane filling the screen
+	pendingSize    tea.WindowSizeMsg // Latest window size, applied once resizing settles
+	resizeSeq      int               // Incremented on each resize so stale debounce timers are ignored
 	targetList     list.Model
-	kismetEndpoint string
-	kismetData     []string // Holds Kismet data to display
-	maxDataSize    int
+	kismetData     ring[kismetEntry] // Devices for the Kismet real-time pane
+	eventLog       *os.File          // Optional file that receives every real-time message
+	trackPath      string            // Where x exports the GPS track, a timestamped file if empty
+	wiglePath      string            // Where x also exports the WiGLE CSV, if set
+	styles         Styles            // Styles built from the configured theme
+
+	realTimeLines       int // Number of real-time output lines kept on screen
+	realTimeScroll      int // Lines scrolled back from the newest real-time message
+	focus               focusPane
+	confirmQuit         bool       // Require a second quit press before exiting
+	quitRequestedAt     time.Time  // When the first quit press happened
+	bounds              paneBounds // Pane rectangles for the current layout, used for mouse hit-testing
+	lastClickAt         time.Time
+	lastClickIndex      int
+	showHelp            bool              // Full-screen key binding overlay is open
+	logView             logViewer         // Full-screen scrollable message log
+	logSink             *logSink          // Log records captured while the TUI is running
+	minLogLevel         logLevel          // Entries below this level are hidden from the log panes
+	fatalErr            error             // Error that ended the session, printed after the TUI exits
+	limit               runLimit          // When the session ends on its own (--duration, --exit-on-found)
+	exitCode            int               // Process exit code once the session has ended on its own
+	exitReason          string            // Why the session ended on its own, printed after the TUI exits
+	deauthTarget        *TargetItem       // Target of the last deauth attack seen
+	deauthAlert         string            // Its Kismet alert type
+	deauthAt            time.Time         // When it was seen
+	tempMessages        ring[tempMessage] // The last tempMessageCount temp messages
+	tempMessageCount    int               // Number of temp messages kept on screen
+	tempMessageDuration time.Duration     // How long a temp message stays before being cleared
+	rssiDisplay         rssiDisplay       // dBm, percent or signal bars
+	clients             []string          // Associated clients of clientsOf from its latest reading
+	clientsOf           *TargetItem
+	clientMakes         map[string]string // Manufacturer of each of clients, by MAC
+	clientCounts        []int             // Number of clients of clientsOf at each of the last clientHistoryLen polls
+	themeName           string            // Preset the styles were built from, cycled with T
+	targetSort          targetSort        // Order of the target list, cycled with o
+	multiCount          int               // Most targets shown at once in multi-target mode
+	searchPolls         int               // Successful polls so far, turning the searching spinner
+	sourceMissing       bool              // Kismet doesn't have the first interface, so polling waits for it
+	sourceWaiting       bool              // The interface is missing but may still be coming up, so it's not an error yet
+	devicesSeen         int               // Devices in the last successful poll's listing
+
+	pollHealth ring[bool] // Whether each of the last pollHealthSize polls got through without errors
 }
 
+// Clear Kismet's startup output off the screen and start polling. The TUI draws inline rather than on the
+// alternate screen, and Bubble Tea's clear works without a clear binary and on Windows consoles.
 func (m *Model) Init() tea.Cmd {
-	return tickCmd()
+	return tea.Batch(tea.ClearScreen, tickCmd())
 }
 
-// Add a message to the real-time output, ensuring we only keep the last 7 messages
+// Add an informational message to the real-time output
 func (m *Model) addRealTimeOutput(message string) {
-	m.realTimeOutput = append(m.realTimeOutput, message)
-	if len(m.realTimeOutput) > 7 {
-		m.realTimeOutput = m.realTimeOutput[len(m.realTimeOutput)-7:]
+	m.addLogEntry(levelInfo, message)
+}
+
+// Add a timestamped message to the log, keeping the last optional.realtime_history entries for scrollback.
+// Every message is also appended to the event log (if configured) so the full history survives.
+func (m *Model) addLogEntry(level logLevel, message string) {
+	m.pushLogEntry(logEntry{time: time.Now(), level: level, text: message})
+}
+
+// Append an already timestamped entry to the log
+func (m *Model) pushLogEntry(entry logEntry) {
+	if m.eventLog != nil {
+		fmt.Fprintf(m.eventLog, "%s %-5s %s\n", entry.time.Format(time.RFC3339), entry.level, entry.text)
+	}
+
+	m.realTimeOutput.push(entry)
+
+	// Keep the view pinned to the same messages while scrolled back
+	if m.realTimeScroll > 0 && entry.level >= m.minLogLevel {
+		m.scrollRealTime(1)
+	}
+
+	m.refreshLogViewer()
+}
+
+// Scroll the real-time pane by delta lines (positive scrolls back in history)
+func (m *Model) scrollRealTime(delta int) {
+	maxScroll := m.shownLogCount() - m.realTimeLines
+	if maxScroll < 0 {
+		maxScroll = 0
+	}
+
+	m.realTimeScroll += delta
+	if m.realTimeScroll > maxScroll {
+		m.realTimeScroll = maxScroll
+	}
+	if m.realTimeScroll < 0 {
+		m.realTimeScroll = 0
+	}
+}
+
+// Returns the window of real-time messages currently visible, honoring the scroll offset. The log is
+// walked back from the newest entry rather than copied out.
+func (m *Model) visibleRealTimeOutput(lines int) []string {
+	var visible []string
+	skip := m.realTimeScroll
+	for i := m.realTimeOutput.len() - 1; i >= 0 && len(visible) < lines; i-- {
+		e := m.realTimeOutput.at(i)
+		if e.level < m.minLogLevel {
+			continue
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		visible = append(visible, m.formatLogEntry(e))
 	}
+	slices.Reverse(visible)
+	return visible
+}
+
+// Add a temporary message that is cleared after tempMessageDuration, keeping only the last tempMessageCount
+func (m *Model) addTempMessage(message string) {
+	m.tempMessages.push(tempMessage{text: message, created: time.Now()})
+}
+
+// Drop temp messages that have been on screen longer than tempMessageDuration
+func (m *Model) clearExpiredTempMessages() {
+	m.tempMessages.deleteFunc(func(msg tempMessage) bool { return time.Since(msg.created) >= m.tempMessageDuration })
 }
 
 func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
-	// TODO will need to handle multiple interfaces and bands they can support.
-	// The interface chosen has no logic behind whether it can support the channel passed by another network card
-	uuid, err := GetUUIDForInterface(m.iface[0], m.kismetEndpoint)
-	if err != nil {
-		log.Printf("Failed to get UUID: %v\n\rPlease check the config.toml and make sure your interface names are correct.", err)
-		os.Exit(1)
+	model, cmd := m.update(msg)
+	m.syncTargetList()
+	return model, cmd
+}
+
+// Bring the list's items in line with the targets, filter and sort order after a message. The items are
+// only replaced when the order actually changed, since SetItems resets the list's filtering and paging.
+func (m *Model) syncTargetList() {
+	sorted := sortTargets(m.ActiveTargets(), m.LockedTarget, m.targetSort)
+	items := m.targetList.Items()
+	if slices.EqualFunc(sorted, items, func(t *TargetItem, item list.Item) bool { return item == list.Item(t) }) {
+		return
+	}
+
+	// Keep the cursor on the same target as the order changes
+	selected, _ := m.targetList.SelectedItem().(*TargetItem)
+	selectedIndex := m.targetList.Index()
+
+	targetItems := make([]list.Item, 0, len(sorted))
+	for i, target := range sorted {
+		targetItems = append(targetItems, target)
+		if target == selected {
+			selectedIndex = i
+		}
+	}
+
+	m.targetList.SetItems(targetItems)
+	m.targetList.Select(selectedIndex)
+}
+
+func (m *Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	uuid, err := m.uuid()
+	notFound, missingSource := err.(*interfaceNotFoundError)
+	if err != nil && !missingSource {
+		// Reported once the TUI has released the terminal
+		m.fatalErr = err
+		m.stopKismet()
+		return m, tea.Quit
 	}
 
+	m.drainLogSink()
+
+	// A missing interface may still turn up (Kismet opening it late), so it's retried, and only reported as
+	// an error once the first few lookups have missed it
+	waiting := missingSource && m.waitingForSource()
+	switch {
+	case waiting && !m.sourceWaiting:
+		m.addRealTimeOutput(fmt.Sprintf("Waiting for interface %s to come up...", m.iface[0]))
+	case missingSource && !waiting && (m.sourceWaiting || !m.sourceMissing):
+		m.addLogEntry(levelError, fmt.Sprintf("Can't search: %v; retrying", notFound))
+	case !missingSource && m.sourceMissing:
+		m.addRealTimeOutput(fmt.Sprintf("Kismet has interface %s now, searching", m.iface[0]))
+	}
+	m.sourceMissing, m.sourceWaiting = missingSource, waiting
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		if m.logView.open && msg.String() != "ctrl+c" {
+			return m, m.updateLogViewer(msg)
+		}
+
+		if m.showHelp {
+			switch msg.String() {
+			case "?", "esc":
+				m.showHelp = false
+				return m, nil
+			case "ctrl+c", "q":
+				// Quitting still works while the help overlay is open
+			default:
+				return m, nil
+			}
+		}
+
 		switch msg.String() {
+		case "?":
+			m.showHelp = true
+			return m, nil
+		case "L":
+			m.openLogViewer()
+			return m, nil
+		case "w":
+			m.toggleWarningsOnly()
+			return m, nil
 		case "ctrl+c", "q":
-			if m.kismet != nil {
-				err := m.kismet.Process.Kill()
-				if err != nil {
-					log.Printf("Unable to kill Kismet process. Please check if Kismet is still running.")
-				}
+			if m.confirmQuit && time.Since(m.quitRequestedAt) > quitConfirmWindow {
+				m.quitRequestedAt = time.Now()
+				m.addTempMessage(fmt.Sprintf("Press %s again to quit", msg.String()))
+				return m, nil
 			}
+			m.stopKismet()
 			return m, tea.Quit
+		case "tab":
+			m.focus = (m.focus + 1) % focusPaneCount
+			return m, nil
 		case "up", "k", "down", "j":
+			if m.focus == focusRealTime {
+				if msg.String() == "up" || msg.String() == "k" {
+					m.scrollRealTime(1)
+				} else {
+					m.scrollRealTime(-1)
+				}
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.targetList, cmd = m.targetList.Update(msg)
+			return m, cmd
+		case "pgup", "pgdown", "home", "end":
+			if m.focus == focusRealTime {
+				switch msg.String() {
+				case "pgup":
+					m.scrollRealTime(m.realTimeLines)
+				case "pgdown":
+					m.scrollRealTime(-m.realTimeLines)
+				case "home":
+					m.scrollRealTime(m.shownLogCount())
+				case "end":
+					m.realTimeScroll = 0
+				}
+				return m, nil
+			}
+			// The list pages by a screen and jumps to either end with these keys itself
 			var cmd tea.Cmd
 			m.targetList, cmd = m.targetList.Update(msg)
 			return m, cmd
 		case "enter":
 			if selectedItem, ok := m.targetList.SelectedItem().(*TargetItem); ok {
-				displayValue := selectedItem.Value
-				if selectedItem.TType == SSID {
-					displayValue = selectedItem.OriginalValue
-				}
-
-				if selectedItem.IsIgnored() {
-					selectedItem.ToggleIgnore()
-					m.addRealTimeOutput(fmt.Sprintf("Target %s removed from ignore list.", displayValue))
-					m.addRealTimeOutput(fmt.Sprintf("Removed from ignore list? %v", selectedItem.Ignored))
-				}
-
-				m.lockedTarget = selectedItem
-				m.lockedTarget.ChannelLocked = false
-				m.channelLocked = false
-
-				err := hopChannel(uuid, m.kismetEndpoint)
-				if err != nil {
-					log.Printf("Error hopping channel: %v", err)
-					m.addRealTimeOutput(fmt.Sprintf("Error hopping channel: %v", err))
-				}
-
-				m.addRealTimeOutput(fmt.Sprintf("Searching for target %s...", displayValue))
+				m.pauseCycle()
+				m.selectTarget(selectedItem, uuid)
 			}
 			return m, nil
 		case "i":
-			if m.lockedTarget != nil {
-				m.lockedTarget.ToggleIgnore()
-				displayValue := m.lockedTarget.Value
-				if m.lockedTarget.TType == SSID {
-					displayValue = m.lockedTarget.OriginalValue
-				}
+			if m.LockedTarget != nil {
 				action := "added to"
-				if !m.lockedTarget.IsIgnored() {
+				if !m.ToggleIgnore(m.LockedTarget) {
 					action = "removed from"
 				}
 
-				m.addRealTimeOutput(fmt.Sprintf("Target %s %s ignore list", displayValue, action))
-				for _, target := range m.targets {
-					if (m.lockedTarget.TType == MAC && target.Value == m.lockedTarget.Value) ||
-						(m.lockedTarget.TType == SSID && target.OriginalValue == m.lockedTarget.OriginalValue) {
-						target.Ignored = m.lockedTarget.Ignored
-						break
-					}
-				}
-				m.lockedTarget = nil
-				m.channel = ""
+				m.addRealTimeOutput(fmt.Sprintf("Target %s %s ignore list", m.LockedTarget.DisplayValue(), action))
 				m.addRealTimeOutput("Continuing search for new target...")
-				m.channelLocked = false
 			}
-			err := hopChannel(uuid, m.kismetEndpoint)
-			if err != nil {
-				log.Printf("Error hopping channel: %v", err)
+			if err := m.release(uuid); err != nil {
+				slog.Error("Error hopping channel", "err", err)
+			}
+			return m, nil
+		case "I":
+			if selectedItem, ok := m.targetList.SelectedItem().(*TargetItem); ok {
+				m.ignoreAllExcept(selectedItem, uuid)
 			}
 			return m, nil
+		case "U":
+			m.unignoreAll()
+			return m, nil
+		case "u":
+			m.undoIgnore(uuid)
+			return m, nil
+		case "t":
+			m.cycleTagFilter(uuid)
+			return m, nil
+		case "x":
+			m.exportTrack()
+			return m, nil
+		case "y":
+			m.copyLockedTarget()
+			return m, nil
+		case "F":
+			m.cycleScreenView()
+			return m, nil
+		case "d":
+			m.rssiDisplay = m.rssiDisplay.next()
+			return m, nil
+		case "%":
+			m.togglePercentage()
+			return m, nil
+		case "T":
+			m.cycleTheme()
+			return m, nil
+		case "m":
+			m.toggleMulti(uuid)
+			return m, nil
+		case "c":
+			m.toggleCycle(uuid)
+			return m, nil
+		case "o":
+			m.targetSort = m.targetSort.next()
+			m.addTempMessage(fmt.Sprintf("Sorting targets by %s", strings.ReplaceAll(string(m.targetSort), "_", " ")))
+			return m, nil
 		default:
+			// 1-9 jump to that target, 0 to the 10th
+			if key := msg.String(); len(key) == 1 && key[0] >= '0' && key[0] <= '9' {
+				index := int(key[0]-'0') - 1
+				if key == "0" {
+					index = 9
+				}
+				if index < len(m.targetList.Items()) {
+					m.targetList.Select(index)
+				}
+			}
 			return m, nil
 		}
 
-	case tea.WindowSizeMsg:
-		m.windowWidth = msg.Width
-		m.progress.Width = msg.Width/2 - padding*2 - 4
-		if m.progress.Width > maxWidth {
-			m.progress.Width = maxWidth
+	case tea.MouseMsg:
+		return m, m.handleMouse(msg, uuid)
+
+	case configReloadMsg:
+		summary, err := m.reloadConfig()
+		if summary != "" {
+			m.addRealTimeOutput(summary)
+			m.addTempMessage(summary)
+		}
+		if err != nil {
+			m.addLogEntry(levelError, err.Error())
+		}
+		return m, nil
+
+	case dumpStateMsg:
+		if path, err := m.dumpState(time.Now()); err != nil {
+			m.addLogEntry(levelError, err.Error())
+		} else {
+			m.addRealTimeOutput("State dumped to " + path)
 		}
-		m.targetList.SetWidth(m.windowWidth / 2)
+		return m, nil
+
+	case tea.WindowSizeMsg:
+		return m, m.deferResize(msg)
+
+	case resizeMsg:
+		m.applyResize(msg)
 		return m, nil
 
 	case tickMsg:
-		devices, err := FetchAllDevices(m.kismetEndpoint)
-		m.addKismetData(devices)
-		if err == nil {
-			m.addKismetData(devices)
-		}
-
-		if m.lockedTarget == nil {
-			value, channel, targetItem, _ := FindValidTarget(m.targets, m.kismetEndpoint)
-			if value != "" {
-				m.lockedTarget = targetItem
-				m.channel = channel
-				m.channelLocked = false
-			}
+		m.clearExpiredTempMessages()
+		if m.sourceMissing {
+			// Nothing can be polled or locked without the datasource
+			return m, tickCmd()
 		}
 
-		if m.lockedTarget != nil {
-			// Fetch dynamic info periodically
-			deviceInfo, err := FetchDeviceInfo(m.lockedTarget.Value, m.kismetEndpoint)
-			if err != nil && err != errDeviceNotFound {
-				log.Printf("Error fetching device info: %v", err)
-			}
-			if deviceInfo != nil {
-				m.rssi = deviceInfo.RSSI
-				m.channel = deviceInfo.Channel
-				m.lastReceived = time.Now()
-
-				// Lock the channel if not already locked
-				if !m.channelLocked {
-					if err := lockChannel(uuid, m.channel, m.kismetEndpoint); err != nil {
-						m.addRealTimeOutput(fmt.Sprintf("Failed to lock channel: %v", err))
-					} else {
-						m.channelLocked = true
-						m.addRealTimeOutput(fmt.Sprintf("Channel: %s", m.channel))
-						// m.addRealTimeOutput(fmt.Sprintf("Locked MAC %s", m.lockedMac))
-						m.addRealTimeOutput(fmt.Sprintf("Make: %s", deviceInfo.Manufacturer))
-						m.addRealTimeOutput(fmt.Sprintf("SSID: %s", deviceInfo.SSID))
-						m.addRealTimeOutput(fmt.Sprintf("Encryption: %s", deviceInfo.Crypt))
-						m.addRealTimeOutput(fmt.Sprintf("Type: %s", deviceInfo.Type))
-
-						// if len(deviceInfo.AssociatedClients) > 0 {
-						// 	for clientMac := range deviceInfo.AssociatedClients {
-						// 		m.addRealTimeOutput(fmt.Sprintf("Associated Client: %s", clientMac))
-						// 	}
-						// }
-					}
-				}
-				m.rssiData = append(m.rssiData, m.rssi)
-				if len(m.rssiData) > 50 { // Keep only the last 50 data points
-					m.rssiData = m.rssiData[1:]
-				}
-			}
+		result := m.poll(uuid)
+		m.pollHealth.push(len(result.errs) == 0)
+		m.addKismetData(result.devices)
+		if result.devices != nil {
+			m.searchPolls++
+			m.devicesSeen = len(result.devices)
 		}
 
-		// Decay RSSI if no signal received in a while
-		if time.Since(m.lastReceived) > timeout && m.rssi > MinRSSI {
-			m.rssi -= decayRate
-			if m.rssi < MinRSSI {
-				m.rssi = MinRSSI
+		if result.dropped != nil {
+			m.addRealTimeOutput(fmt.Sprintf("Lost target %s, resuming scan", result.dropped.DisplayValue()))
+		}
+		if result.lockErr != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Failed to lock channel: %v", result.lockErr))
+		}
+		if result.reading != nil {
+			m.updateClients(m.LockedTarget, result.reading, result.devices)
+		}
+		for _, a := range result.deauth {
+			m.deauthTarget, m.deauthAlert, m.deauthAt = m.LockedTarget, a.Header, time.Now()
+			m.addLogEntry(levelWarn, fmt.Sprintf("DEAUTH ATTACK on %s (%s): %s", m.LockedTarget.DisplayValue(), a.Header, a.Text))
+		}
+		for _, pair := range result.colocated {
+			together := fmt.Sprintf("Targets %s and %s both in range", pair.a.DisplayValue(), pair.b.DisplayValue())
+			m.addLogEntry(levelWarn, together)
+			m.addTempMessage(together)
+		}
+		if result.near != nil {
+			near := fmt.Sprintf("Target %s within reach: %s", m.LockedTarget.DisplayValue(), m.formatRSSI(result.near.rssi))
+			m.addRealTimeOutput(near)
+			m.addTempMessage(near)
+		}
+		if result.conflict != nil {
+			conflict := describeConflict(m.LockedTarget, result.conflict)
+			m.addLogEntry(levelWarn, conflict)
+			m.addTempMessage(conflict)
+		}
+		if result.cycle != nil {
+			m.addRealTimeOutput(describeCycleStep(result.cycle, m.formatRSSI))
+		}
+		if result.handshake {
+			captured := fmt.Sprintf("WPA handshake captured for %s", m.LockedTarget.DisplayValue())
+			m.addRealTimeOutput(captured)
+			m.addTempMessage(captured)
+		}
+		if result.movedFrom != "" {
+			moved := fmt.Sprintf("Target %s moved: ch %s → %s", m.LockedTarget.DisplayValue(), result.movedFrom, m.Channel)
+			if tracker.IsDFS(result.movedFrom) {
+				moved += " (DFS?)"
+			}
+			m.addLogEntry(levelWarn, moved)
+			m.addTempMessage(moved)
+		}
+		if result.locked {
+			m.addRealTimeOutput(fmt.Sprintf("Channel: %s", tracker.FormatChannel(m.Channel)))
+			m.addRealTimeOutput(fmt.Sprintf("Make: %s", result.reading.Manufacturer))
+			m.addRealTimeOutput(fmt.Sprintf("SSID: %s", result.reading.SSID))
+			m.addRealTimeOutput(fmt.Sprintf("Encryption: %s", result.reading.Crypt))
+			m.addRealTimeOutput(fmt.Sprintf("Type: %s", result.reading.Type))
+			if details := result.reading.Details(); details != "" {
+				m.addRealTimeOutput(details)
 			}
 		}
 
-		// Update progress bar
-		percent := float64(m.rssi-MinRSSI) / float64(MaxRSSI-MinRSSI)
-		if percent < 0 {
-			percent = 0
-		} else if percent > 1 {
-			percent = 1
+		if done, code, reason := m.limit.check(result, m.LockedTarget, time.Now()); done {
+			m.exitCode, m.exitReason = code, reason
+			m.stopKismet()
+			return m, tea.Quit
 		}
-		m.progress.SetPercent(percent)
 
-		return m, tea.Batch(tickCmd(), m.progress.IncrPercent(0))
+		// Update progress bar. SetPercent only starts the animation; its frames arrive as FrameMsg.
+		quality := signalQuality(m.RSSI)
+		if m.Multi {
+			quality = signalQuality(m.multiRSSI())
+		}
+		return m, tea.Batch(tickCmd(), m.progress.SetPercent(quality))
 
-	// case progress.FrameMsg:
-	// 	progressModel, cmd := m.progress.Update(msg)
-	// 	m.progress = progressModel.(progress.Model)
-	// 	return m, cmd
+	case progress.FrameMsg:
+		progressModel, cmd := m.progress.Update(msg)
+		m.progress = progressModel.(progress.Model)
+		return m, cmd
 
 	default:
 		return m, nil
 	}
 }
 
-// Add new Kismet data to the model's buffer
-func (m *Model) addKismetData(data []map[string]interface{}) {
-	for _, device := range data {
-		// Format device information (MAC, RSSI, etc.)
-		mac, _ := device["kismet.device.base.macaddr"].(string)
+// Kill Kismet if we launched it
+func (m *Model) stopKismet() {
+	stopKismet(m.kismet)
+}
+
+// Start searching for the given target, removing it from the ignore list if needed
+func (m *Model) selectTarget(selectedItem *TargetItem, uuid string) {
+	displayValue := selectedItem.DisplayValue()
+
+	events, err := m.search(selectedItem, uuid)
+	for _, event := range events {
+		if event.Kind == tracker.Unignored {
+			m.addRealTimeOutput(fmt.Sprintf("Target %s removed from ignore list.", displayValue))
+			m.addRealTimeOutput(fmt.Sprintf("Removed from ignore list? %v", selectedItem.Ignored))
+		}
+	}
+	if err != nil {
+		m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+	}
+
+	m.addRealTimeOutput(fmt.Sprintf("Searching for target %s...", displayValue))
+}
+
+// Ignore every target except the selected one, dropping the lock if it was on one of them
+func (m *Model) ignoreAllExcept(selectedItem *TargetItem, uuid string) {
+	count := m.IgnoreAllExcept(selectedItem)
+	if m.LockedTarget != nil && m.LockedTarget.IsIgnored() {
+		if err := m.release(uuid); err != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+		}
+	}
+
+	m.addRealTimeOutput(fmt.Sprintf("Ignored %d target(s), keeping %s", count, selectedItem.Title()))
+	m.addTempMessage(fmt.Sprintf("Ignored %d target(s)", count))
+}
+
+// Show the next group of targets, wrapping back round to all of them. A locked target outside the group is released.
+func (m *Model) cycleTagFilter(uuid string) {
+	tags := m.AllTags()
+	if len(tags) == 0 {
+		m.addTempMessage("No target tags configured")
+		return
+	}
+
+	next := slices.Index(tags, m.ActiveTag) + 1 // The empty (all) filter isn't in tags, so it goes to the first tag
+	if next >= len(tags) {
+		m.ActiveTag = ""
+		m.addTempMessage("Showing all targets")
+	} else {
+		m.ActiveTag = tags[next]
+		m.addTempMessage(fmt.Sprintf("Showing targets tagged %q", m.ActiveTag))
+	}
+	m.targetList.Select(0)
+
+	if m.LockedTarget != nil && !m.LockedTarget.HasTag(m.ActiveTag) {
+		m.addRealTimeOutput(fmt.Sprintf("Target %s is not in the group, continuing search...", m.LockedTarget.DisplayValue()))
+		if err := m.release(uuid); err != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+		}
+	}
+}
+
+// Export the GPS track of the hunt so far, and the WiGLE CSV if --export-wigle is set
+func (m *Model) exportTrack() {
+	if m.wigle != nil {
+		if err := m.wigle.export(m.wiglePath); err != nil {
+			m.addLogEntry(levelError, err.Error())
+		} else {
+			m.addRealTimeOutput(fmt.Sprintf("WiGLE CSV with %d devices exported to %s", len(m.wigle.devices), m.wiglePath))
+		}
+	}
+
+	if m.track == nil || m.track.empty() {
+		m.addTempMessage("No GPS track to export yet")
+		return
+	}
+
+	path := m.trackPath
+	if path == "" {
+		path = defaultTrackPath()
+	}
+	if err := m.track.export(path); err != nil {
+		m.addLogEntry(levelError, err.Error())
+		return
+	}
+	m.addRealTimeOutput(fmt.Sprintf("Track exported to %s", path))
+}
 
-		// rssi, _ := device["kismet.device.base.signal/kismet.common.signal.last_signal"].(float64)
-		channel, _ := device["kismet.device.base.channel"].(string)
-		// ssid, _ := device["dot11.device/dot11.device.last_beaconed_ssid_record/dot11.advertisedssid.ssid"].(string)
+// Remove every target from the ignore list
+func (m *Model) unignoreAll() {
+	count := m.UnignoreAll()
+	m.addRealTimeOutput(fmt.Sprintf("Removed %d target(s) from the ignore list", count))
+	m.addTempMessage(fmt.Sprintf("Unignored %d target(s)", count))
+}
 
-		// Create a formatted string to display
-		entry := fmt.Sprintf("MAC: %s, Channel: %s", mac, channel)
+// Revert the last change to the ignore list, dropping the lock if that put the locked target back on it
+func (m *Model) undoIgnore(uuid string) {
+	restored := m.UndoIgnore()
+	if restored == nil {
+		m.addTempMessage("Nothing to undo")
+		return
+	}
 
-		// Append to the data buffer
-		m.kismetData = append(m.kismetData, entry)
+	if m.LockedTarget != nil && m.LockedTarget.IsIgnored() {
+		if err := m.release(uuid); err != nil {
+			m.addLogEntry(levelError, fmt.Sprintf("Error hopping channel: %v", err))
+		}
+	}
 
-		// Keep only the last `maxDataSize` entries
-		if len(m.kismetData) > m.maxDataSize {
-			m.kismetData = m.kismetData[len(m.kismetData)-m.maxDataSize:]
+	// Toggling a target also changes its configured copy, so count targets by config key
+	keys := map[string]bool{}
+	for _, target := range restored {
+		keys[target.ConfigKey()] = true
+	}
+	message := fmt.Sprintf("Undid ignore change to %d target(s)", len(keys))
+	if target := restored[0]; len(keys) == 1 {
+		if target.IsIgnored() {
+			message = fmt.Sprintf("Target %s back on the ignore list", target.DisplayValue())
+		} else {
+			message = fmt.Sprintf("Target %s off the ignore list", target.DisplayValue())
 		}
 	}
+	m.addRealTimeOutput(message)
+	m.addTempMessage(message)
+}
+
+// A device heard by Kismet, as shown in the Kismet real-time pane
+type kismetEntry struct {
+	mac     string
+	channel string
+}
+
+// Add new Kismet data to the model's buffer. A device already in it moves to the end, so the buffer
+// holds distinct devices, most recently heard last.
+func (m *Model) addKismetData(data []map[string]interface{}) {
+	for _, device := range data {
+		mac, _ := device["base.macaddr"].(string)
+		entry := kismetEntry{mac: mac, channel: tracker.DeviceChannel(device)}
+
+		// Drop the device's earlier entry and append to the data buffer
+		m.kismetData.deleteFunc(func(e kismetEntry) bool { return e.mac == mac })
+		m.kismetData.push(entry)
+	}
+}
+
+// The newest n devices for the Kismet real-time pane, one line each
+func (m *Model) kismetLines(n int) []string {
+	entries := m.kismetData.last(n)
+	lines := make([]string, len(entries))
+	for i, e := range entries {
+		lines[i] = fmt.Sprintf("MAC: %s, Channel: %s", e.mac, tracker.FormatChannel(e.channel))
+	}
+	return lines
 }
 
 func (m *Model) View() string {
+	if m.tooSmall() {
+		return m.viewTooSmall()
+	}
+
+	if m.logView.open {
+		return m.renderLogViewer()
+	}
 
-	topPaneWidth := m.windowWidth / 2
+	if m.showHelp {
+		return m.renderHelpOverlay()
+	}
 
-	topLeft := m.renderTargetListWithHelp(topPaneWidth)
+	if m.screenView != viewGrid {
+		return m.viewFocused()
+	}
+
+	if m.layout.stacked {
+		return m.viewStacked()
+	}
+
+	topLeft := m.renderTargetListWithHelp(m.layout.leftWidth)
 
 	topRight := lipgloss.JoinVertical(
 		lipgloss.Top,
-		m.renderRSSIProgressBar(topPaneWidth),
-		m.renderRSSIOverTimeChart(topPaneWidth),
+		m.renderRSSIProgressBar(m.layout.rightWidth),
+		m.renderSignalPane(m.layout.rightWidth, m.layout.chartLevels),
 	)
 
-	var targetDisplay string
-	if m.lockedTarget != nil {
-		if m.lockedTarget.OriginalValue != "" && m.lockedTarget.TType == SSID {
-			targetDisplay = m.lockedTarget.OriginalValue // Display SSID
-		} else {
-			targetDisplay = m.lockedTarget.Value // Display MAC address
+	bottomLeft := m.renderInfoPane(m.layout.leftWidth)
+	bottomRight := m.renderKismetPane("Kismet Real-Time Data", m.kismetLines(m.layout.kismetRows), m.layout.rightWidth)
+
+	topRow := lipgloss.JoinHorizontal(lipgloss.Top, topLeft, topRight)
+	bottomRow := lipgloss.JoinHorizontal(lipgloss.Top, bottomLeft, bottomRight)
+
+	return lipgloss.JoinVertical(lipgloss.Top, topRow, bottomRow, m.renderStatusBar(m.layout.width))
+}
+
+// Render the searching/locked info pane holding the real-time output and temp messages
+func (m *Model) renderInfoPane(width int) string {
+	var tempOutput []string
+	for i := range m.tempMessages.len() {
+		tempOutput = append(tempOutput, m.tempMessages.at(i).text)
+	}
+	realTimeTitle := "Searching for target(s)... " + m.renderSearchActivity()
+	lockStatus := ""
+	if m.Multi {
+		realTimeTitle = "Watching multiple targets"
+		lockStatus = m.renderRotationStatus()
+	}
+	if m.LockedTarget != nil && m.ChannelLocked {
+		realTimeTitle = fmt.Sprintf("Locked to target: %s", m.LockedTarget.DisplayValue())
+		lockStatus = fmt.Sprintf("ch %s • locked for %s • %s", tracker.FormatChannel(m.Channel), time.Since(m.LockedAt).Round(time.Second), m.renderLastPacket())
+		if activity := m.activity.String(); activity != "" {
+			lockStatus += " • activity: " + activity
+		}
+		if m.cycling && m.cycleHeard {
+			lockStatus += fmt.Sprintf(" • cycle: next in %s", time.Until(m.cycleUntil).Round(time.Second))
+		}
+		if m.handshake.capturedFor(m.LockedTarget) {
+			lockStatus += " • " + m.styles.Good.Render("handshake: captured ✔")
+		}
+		if peak := m.LockedTarget.Peak; !peak.At.IsZero() {
+			lockStatus += fmt.Sprintf(" • peak: %s (%s ago)", m.formatRSSI(peak.RSSI), time.Since(peak.At).Round(time.Second))
 		}
+		if channels := m.LockedTarget.Channels; len(channels) > 0 {
+			lockStatus += " • seen on: " + strings.Join(channels, ", ")
+		}
+	}
+	switch {
+	case m.sourceWaiting:
+		lockStatus = fmt.Sprintf("Waiting for interface %s to come up...", m.iface[0])
+	case m.sourceMissing && m.sourceErr != nil:
+		lockStatus = m.styles.Bad.Render("⚠ " + m.sourceErr.Error())
+	}
+	if m.LockedTarget != nil && m.deauthTarget == m.LockedTarget && time.Since(m.deauthAt) < deauthBannerTime {
+		lockStatus = m.styles.Bad.Render(fmt.Sprintf("⚠ deauth attack (%s) %s ago", m.deauthAlert, time.Since(m.deauthAt).Round(time.Second))) +
+			" • " + lockStatus
+	}
+	if m.realTimeScroll > 0 {
+		realTimeTitle += fmt.Sprintf(" [↑%d]", m.realTimeScroll)
+	}
+	if m.minLogLevel == levelWarn {
+		realTimeTitle += " [warnings]"
 	}
 
-	var bottomLeft string
-	if m.lockedTarget == nil || !m.channelLocked {
-		bottomLeft = renderRealTimePane("Searching for target(s)...", m.realTimeOutput, topPaneWidth)
-	} else {
-		bottomLeft = renderRealTimePane(fmt.Sprintf("Locked to target: %s", targetDisplay), m.realTimeOutput, topPaneWidth)
+	return m.renderRealTimePane(realTimeTitle, lockStatus, m.visibleRealTimeOutput(m.layout.realTimeRows), tempOutput, width, m.layout.realTimeH)
+}
+
+// Copy the locked target's MAC (or its SSID until the access point has been found) to the clipboard. The
+// OSC 52 fallback goes to stderr for the same reason as the bell, to stay out of the TUI's output.
+func (m *Model) copyLockedTarget() {
+	if m.LockedTarget == nil {
+		m.addTempMessage("No target locked to copy")
+		return
+	}
+	value := m.LockedTarget.Value
+	if err := copyToClipboard(value, os.Stderr); err != nil {
+		m.addLogEntry(levelError, err.Error())
+		return
 	}
+	m.addTempMessage("Copied " + value)
+}
 
-	bottomRight := renderKismetPane("Kismet Real-Time Data", m.kismetData, topPaneWidth)
-	topRow := lipgloss.JoinHorizontal(lipgloss.Top, topLeft, topRight)
-	bottomRow := lipgloss.JoinHorizontal(lipgloss.Top, bottomLeft, bottomRight)
+// Render the spinner and how many devices the last poll saw. The spinner only turns on a successful poll,
+// so a stalled Kismet shows as a frozen spinner.
+func (m *Model) renderSearchActivity() string {
+	frames := spinner.MiniDot.Frames
+	return fmt.Sprintf("%s %d devices", m.styles.Good.Render(frames[m.searchPolls%len(frames)]), m.devicesSeen)
+}
 
-	return lipgloss.JoinVertical(lipgloss.Top, topRow, bottomRow)
+// Render the time since the last packet, yellow once it is getting stale and red once the RSSI is decaying
+func (m *Model) renderLastPacket() string {
+	since := time.Since(m.LastReceived)
+	text := fmt.Sprintf("last packet: %s ago", since.Round(time.Second))
+	switch {
+	case since > timeout:
+		return m.styles.Bad.Render(text)
+	case since > timeout/2:
+		return m.styles.Warn.Render(text)
+	default:
+		return text
+	}
 }
 
-func (m *Model) renderRSSIOverTimeChart(width int) string {
+// Render the RSSI chart in a pane of the given outer width with the given number of Y-axis levels
+func (m *Model) renderRSSIOverTimeChart(width int, height int) string {
 	var builder strings.Builder
 
-	minWidth := 31
-	if width <= minWidth {
+	minWidth := 29
+	if width <= minWidth || height <= 0 {
 		return ""
 	}
 
 	maxRSSI, minRSSI := -30, -120
-	height := 7
 
 	// Adjust maxPoints to account for the left wall and make sure the dots don't disappear prematurely
-	maxPoints := width - 20
+	maxPoints := max(width-18, 0)
 
 	// Top border of the chart
 	builder.WriteString("     ┌")
@@ -323,9 +821,9 @@ func (m *Model) renderRSSIOverTimeChart(width int) string {
 		}
 
 		// Fill in RSSI data from right to left
-		for i := 0; i < len(m.rssiData) && i < maxPoints; i++ {
-			dataIdx := len(m.rssiData) - (i + 1) // Start from the end of the data
-			rssi := m.rssiData[dataIdx]
+		for i := 0; i < m.rssiData.len() && i < maxPoints; i++ {
+			dataIdx := m.rssiData.len() - (i + 1) // Start from the end of the data
+			rssi := m.rssiData.at(dataIdx)
 
 			normalizedRSSI := (rssi - minRSSI) * height / (maxRSSI - minRSSI)
 
@@ -349,75 +847,87 @@ func (m *Model) renderRSSIOverTimeChart(width int) string {
 	builder.WriteString(strings.Repeat("─", maxPoints-9))
 	builder.WriteString("┘\n")
 
-	return lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 2).
-		Width(width - 4).
+	return m.basePane().
+		Width(width - 2).
 		Render(builder.String())
 }
 
 func (m *Model) renderTargetListWithHelp(width int) string {
 	listTitle := "Targets"
-
-	var targetItems []list.Item
-	for _, target := range m.targets {
-		targetItems = append(targetItems, target)
+	if m.ActiveTag != "" {
+		listTitle += fmt.Sprintf(" [%s]", m.ActiveTag)
 	}
 
-	m.targetList.SetItems(targetItems)
-
 	macListView := m.targetList.View()
-	m.targetList.SetShowHelp(false)
-	customHelp := renderCustomHelpText()
+	customHelp := m.renderCustomHelpText(width - 2 - m.paneHPadding()*2)
 
 	// Create styled header and combine it with the MAC list and custom help
-	header := lipgloss.NewStyle().Bold(true).Render(listTitle)
-	return lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 2).
-		Width(width).
+	header := m.styles.Header.Render(listTitle)
+	return m.paneStyle(focusTargets).
+		Width(width - 2).
 		Render(header + "\n" + macListView + "\n\n" + customHelp)
 }
 
-// Render custom help text
-func renderCustomHelpText() string {
-	help := `
-↑/k up • ↓/j down 
-[Enter] Search for targets
-[i] Ignore current target 
-[q/Ctrl+C] Quit`
-	return lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#626262")).
-		Render(help)
+// Pane style for a focusable pane, highlighting the border when it has focus
+func (m *Model) paneStyle(pane focusPane) lipgloss.Style {
+	if m.focus == pane {
+		return m.basePane().BorderForeground(m.styles.Theme.Accent)
+	}
+	return m.basePane()
+}
+
+// Render the key hints, with the version at the right edge if there's room for it in width
+func (m *Model) renderCustomHelpText(width int) string {
+	hints := "[Enter] search • [i] ignore • [?] help • [q] quit"
+	ver := version.Short()
+	if gap := width - lipgloss.Width(hints) - lipgloss.Width(ver); gap >= 2 {
+		hints += strings.Repeat(" ", gap) + ver
+	}
+	return m.styles.Help.Render(hints)
+}
+
+// Render the MQTT connection status shown next to the RSSI, or nothing if MQTT isn't configured
+func (m *Model) renderMQTTStatus() string {
+	if m.mqtt == nil {
+		return ""
+	}
+	status := m.mqtt.status()
+	if status == "mqtt: connected" {
+		return "  " + m.styles.Help.Render(status)
+	}
+	return "  " + m.styles.Warn.Render(status)
 }
 
 func (m *Model) renderRSSIProgressBar(width int) string {
-	rssiLabel := fmt.Sprintf("RSSI: %d dBm", m.rssi)
+	rssiLabel := "RSSI: " + m.formatRSSI(m.RSSI) + m.renderMQTTStatus()
+	if m.Multi {
+		rssiLabel = "Multi-target, strongest: -" + m.renderMQTTStatus()
+		if visible := m.visibleTargets(1); len(visible) > 0 {
+			rssiLabel = fmt.Sprintf("Multi-target, strongest: %s %s", visible[0].DisplayValue(), m.formatRSSI(visible[0].LastRSSI)) + m.renderMQTTStatus()
+		}
+	}
 	progressBar := m.progress.View()
 
 	rssiDisplay := fmt.Sprintf("%s\n%s", rssiLabel, progressBar)
 
-	return lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 2).
-		Width(width - 4).
+	return m.basePane().
+		Width(width - 2).
 		Render(rssiDisplay)
 }
 
-// Render the real-time output pane with the last entries
-func renderRealTimePane(title string, outputs []string, width int) string {
-	style := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 2).
-		Height(13).
-		Width(width)
+// Render the real-time output pane with the last entries, followed by any temp messages
+// The status line under the title is always reserved, and cut to one line, so locking a target doesn't shift the pane.
+func (m *Model) renderRealTimePane(title string, status string, outputs []string, temps []string, width int, height int) string {
+	style := m.paneStyle(focusRealTime).
+		Height(height).
+		Width(width - 2)
 
-	header := lipgloss.NewStyle().Bold(true).Render(title)
+	status = lipgloss.NewStyle().MaxWidth(width - 2 - m.paneHPadding()*2).Render(status)
+	header := m.styles.Header.Render(title) + "\n" + m.styles.Help.Render(status)
 	body := lipgloss.NewStyle().Render(strings.Join(outputs, "\n"))
+	if len(temps) > 0 {
+		body += "\n\n" + lipgloss.NewStyle().Italic(true).Render(strings.Join(temps, "\n"))
+	}
 
 	return style.Render(header + "\n" + body)
 }
@@ -428,14 +938,11 @@ func tickCmd() tea.Cmd {
 	})
 }
 
-func renderKismetPane(title string, data []string, width int) string {
-	style := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("63")).
-		Padding(1, 2).
-		Width(width - 4)
+func (m *Model) renderKismetPane(title string, data []string, width int) string {
+	style := m.basePane().
+		Width(width - 2)
 
-	header := lipgloss.NewStyle().Bold(true).Render(title)
+	header := m.styles.Header.Render(title)
 	body := lipgloss.NewStyle().Render(strings.Join(data, "\n"))
 
 	return style.Render(header + "\n" + body)
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/testkismet"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestPageKeysMoveSelection(t *testing.T) {
+	server := fakeKismet(t)
+	var targets []*TargetItem
+	var items []list.Item
+	for i := range 30 {
+		target := &TargetItem{Value: fmt.Sprintf("32:34:00:00:00:%02X", i), TType: MAC}
+		targets, items = append(targets, target), append(items, target)
+	}
+	m := &Model{
+		hunt:           newHunt(targets, []string{"wlan0"}, server.Endpoint()),
+		targetList:     list.New(items, list.NewDefaultDelegate(), 40, 20),
+		realTimeOutput: newRing[logEntry](100),
+		tempMessages:   newRing[tempMessage](3),
+		realTimeLines:  5,
+	}
+	perPage := m.targetList.Paginator.PerPage
+	press := func(key tea.KeyType) {
+		m.Update(tea.KeyMsg{Type: key})
+	}
+
+	press(tea.KeyPgDown)
+	if m.targetList.Index() != perPage {
+		t.Errorf("PgDn: selected %d, want %d, the first on the next page", m.targetList.Index(), perPage)
+	}
+	press(tea.KeyEnd)
+	if m.targetList.Index() != len(items)-1 {
+		t.Errorf("End: selected %d, want the last target", m.targetList.Index())
+	}
+	press(tea.KeyPgUp)
+	if m.targetList.Index() >= len(items)-1 {
+		t.Errorf("PgUp: selected %d, want a page back", m.targetList.Index())
+	}
+	press(tea.KeyHome)
+	if m.targetList.Index() != 0 {
+		t.Errorf("Home: selected %d, want the first target", m.targetList.Index())
+	}
+
+	// With the log focused they scroll it instead, leaving the selection alone
+	for i := range 20 {
+		m.addRealTimeOutput(fmt.Sprintf("message %d", i))
+	}
+	m.focus = focusRealTime
+	press(tea.KeyPgUp)
+	if m.realTimeScroll != 5 || m.targetList.Index() != 0 {
+		t.Errorf("PgUp with the log focused: scrolled %d, selected %d", m.realTimeScroll, m.targetList.Index())
+	}
+	press(tea.KeyHome)
+	oldest := m.realTimeScroll
+	press(tea.KeyEnd)
+	if oldest != m.shownLogCount()-5 || m.realTimeScroll != 0 || m.targetList.Index() != 0 {
+		t.Errorf("Home scrolled to %d, End to %d, selected %d; want the oldest, then the newest", oldest, m.realTimeScroll, m.targetList.Index())
+	}
+}
+
+// Run each command in a batch for at most wait, collecting the messages that arrive in time. Commands
+// that take longer, like the next poll's tick, are left running.
+func collectMsgs(cmd tea.Cmd, wait time.Duration) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	msgs := make(chan tea.Msg, 16)
+	var run func(cmd tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		msg := cmd()
+		if batch, ok := msg.(tea.BatchMsg); ok {
+			for _, c := range batch {
+				if c != nil {
+					go run(c)
+				}
+			}
+			return
+		}
+		msgs <- msg
+	}
+	go run(cmd)
+
+	var got []tea.Msg
+	timeout := time.After(wait)
+	for {
+		select {
+		case msg := <-msgs:
+			got = append(got, msg)
+		case <-timeout:
+			return got
+		}
+	}
+}
+
+func TestPollAnimatesSignalBar(t *testing.T) {
+	server := fakeKismet(t)
+	server.SetDevices(testkismet.Device{MAC: "32:34:00:00:00:01", Channel: "6", RSSI: -57, Type: "Wi-Fi Client"})
+	phone := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	h, _ := testHunt(t, server, phone)
+	m := &Model{
+		hunt:           h,
+		targetList:     list.New([]list.Item{phone}, list.NewDefaultDelegate(), 40, 10),
+		realTimeOutput: newRing[logEntry](10),
+		tempMessages:   newRing[tempMessage](3),
+		progress:       newProgressBar(themePresets["dark"], false),
+	}
+
+	_, cmd := m.Update(tickMsg(time.Now()))
+	if h.RSSI != -57 {
+		t.Fatalf("RSSI = %d after the poll, want -57", h.RSSI)
+	}
+	// The bar moves to the new reading through animation frames, so the poll must start them
+	var frame tea.Msg
+	for _, msg := range collectMsgs(cmd, interval/2) {
+		if _, ok := msg.(progress.FrameMsg); ok {
+			frame = msg
+		}
+	}
+	if frame == nil {
+		t.Fatal("no progress.FrameMsg from the poll that changed the RSSI")
+	}
+	// The bar is still on its way there, so the frame asks for the next one
+	if _, cmd := m.Update(frame); cmd == nil {
+		t.Error("no next frame after the first one")
+	}
+}
+
+func TestAddKismetDataDedupes(t *testing.T) {
+	m := &Model{kismetData: newRing[kismetEntry](kismetDataSize)}
+	device := func(mac, channel string) map[string]interface{} {
+		return map[string]interface{}{"base.macaddr": mac, "base.channel": channel}
+	}
+
+	m.addKismetData([]map[string]interface{}{device("32:34:00:00:00:01", "6"), device("32:34:00:00:00:02", "11")})
+	// The same devices again, one of them twice in one listing and one on a new channel
+	m.addKismetData([]map[string]interface{}{
+		device("32:34:00:00:00:01", "6"),
+		device("32:34:00:00:00:02", "1"),
+		device("32:34:00:00:00:02", "1"),
+	})
+
+	entries := m.kismetData.last(kismetDataSize)
+	for _, mac := range []string{"32:34:00:00:00:01", "32:34:00:00:00:02"} {
+		var count int
+		for _, entry := range entries {
+			if entry.mac == mac {
+				count++
+			}
+		}
+		if count != 1 {
+			t.Errorf("%s appears %d times in %+v, want once", mac, count, entries)
+		}
+	}
+	// Each keeps its latest reading, most recently seen last
+	if len(entries) != 2 || entries[1] != (kismetEntry{mac: "32:34:00:00:00:02", channel: "1"}) {
+		t.Errorf("entries = %+v", entries)
+	}
+	// The pane formats them
+	if lines := m.kismetLines(1); len(lines) != 1 || lines[0] != "MAC: 32:34:00:00:00:02, Channel: 1 (2412 MHz)" {
+		t.Errorf("kismetLines(1) = %q", lines)
+	}
+}
+
+func TestViewLeavesListAlone(t *testing.T) {
+	server := fakeKismet(t)
+	var targets []*TargetItem
+	for i := range 30 {
+		targets = append(targets, &TargetItem{Value: fmt.Sprintf("32:34:00:00:00:%02X", i), TType: MAC})
+	}
+	theme := themePresets["dark"]
+	m := &Model{
+		hunt:           newHunt(targets, []string{"wlan0"}, server.Endpoint()),
+		progress:       newProgressBar(theme, false),
+		styles:         NewStyles(theme),
+		windowWidth:    100,
+		windowHeight:   30,
+		targetList:     list.New(nil, list.NewDefaultDelegate(), 40, 10),
+		kismetData:     newRing[kismetEntry](kismetDataSize),
+		realTimeOutput: newRing[logEntry](10),
+		tempMessages:   newRing[tempMessage](3),
+		realTimeLines:  5,
+		logView:        newLogViewer(),
+	}
+	m.targetList.SetShowHelp(false)
+	m.applyLayout()
+	m.syncTargetList()
+
+	m.Update(tea.KeyMsg{Type: tea.KeyPgDown})
+	m.Update(tea.KeyMsg{Type: tea.KeyDown})
+	items := m.targetList.Items()
+	index, page := m.targetList.Index(), m.targetList.Paginator.Page
+	if index == 0 || page == 0 {
+		t.Fatalf("selected %d on page %d, want past the first page", index, page)
+	}
+
+	bounds := m.bounds
+	first := m.View()
+	for range 3 {
+		if view := m.View(); view != first {
+			t.Fatal("repeated View calls rendered differently")
+		}
+	}
+	if m.targetList.Index() != index || m.targetList.Paginator.Page != page {
+		t.Errorf("after View: selected %d on page %d, want %d on page %d", m.targetList.Index(), m.targetList.Paginator.Page, index, page)
+	}
+	if got := m.targetList.Items(); len(got) != len(items) || &got[0] != &items[0] {
+		t.Error("View replaced the list's items")
+	}
+	if m.targetList.ShowHelp() {
+		t.Error("View turned the list's help back on")
+	}
+	if m.bounds != bounds {
+		t.Errorf("after View: bounds %+v, want %+v", m.bounds, bounds)
+	}
+}
+
+// The pane bounds come from the layout rather than the rendered view, so check they land on the right rows
+func TestBoundsMatchView(t *testing.T) {
+	server := fakeKismet(t)
+	target := &TargetItem{Value: "32:34:00:00:00:01", TType: MAC}
+	for _, size := range [][2]int{{160, 50}, {100, 40}, {120, 30}, {80, 40}, {60, 26}} {
+		for _, view := range []screenView{viewGrid, viewTargets} {
+			m := newSourceTestModel(newHunt([]*TargetItem{target}, []string{"wlan0"}, server.Endpoint()))
+			m.windowWidth, m.windowHeight, m.screenView = size[0], size[1], view
+			m.tempMessageCount, m.tempMessages = 3, newRing[tempMessage](3)
+			for i := range 10 {
+				m.addRealTimeOutput(fmt.Sprintf("message %d", i))
+			}
+			for range 3 {
+				m.addTempMessage("temp")
+			}
+			m.applyLayout()
+			m.syncTargetList()
+
+			lines := strings.Split(m.View(), "\n")
+			name := fmt.Sprintf("%dx%d view %d", size[0], size[1], view)
+			targets := m.bounds.targets
+			if !strings.HasPrefix(lines[targets.y], "╭") || !strings.HasPrefix(lines[targets.y+targets.h-1], "╰") {
+				t.Errorf("%s: target pane %+v doesn't match the view:\n%s", name, targets, strings.Join(lines, "\n"))
+			}
+			if !strings.Contains(lines[m.bounds.listItemsTop], target.Title()) {
+				t.Errorf("%s: row %d isn't the first target:\n%s", name, m.bounds.listItemsTop, strings.Join(lines, "\n"))
+			}
+			if view != viewGrid {
+				continue
+			}
+			realTime := m.bounds.realTime
+			if !strings.HasPrefix(lines[realTime.y], "╭") || !strings.HasPrefix(lines[realTime.y+realTime.h-1], "╰") {
+				t.Errorf("%s: real-time pane %+v doesn't match the view:\n%s", name, realTime, strings.Join(lines, "\n"))
+			}
+		}
+	}
+}
+
+func newSourceTestModel(h *hunt) *Model {
+	theme := themePresets["dark"]
+	m := &Model{
+		hunt:           h,
+		progress:       newProgressBar(theme, false),
+		styles:         NewStyles(theme),
+		windowWidth:    160,
+		windowHeight:   30,
+		targetList:     list.New(nil, list.NewDefaultDelegate(), 40, 10),
+		kismetData:     newRing[kismetEntry](kismetDataSize),
+		realTimeOutput: newRing[logEntry](20),
+		tempMessages:   newRing[tempMessage](3),
+		realTimeLines:  5,
+		logView:        newLogViewer(),
+	}
+	m.targetList.SetShowHelp(false)
+	m.applyLayout()
+	return m
+}
+
+func TestUpdateWithNoInterface(t *testing.T) {
+	server := fakeKismet(t)
+	m := newSourceTestModel(newHunt(nil, nil, server.Endpoint()))
+
+	// Ends the session with the error rather than indexing the empty list
+	_, cmd := m.Update(tickMsg(time.Now()))
+	if m.fatalErr == nil || !strings.Contains(m.fatalErr.Error(), "no interface configured") {
+		t.Errorf("fatalErr = %v, want no interface configured", m.fatalErr)
+	}
+	if cmd == nil {
+		t.Fatal("no command, want tea.Quit")
+	}
+	if _, ok := cmd().(tea.QuitMsg); !ok {
+		t.Error("the TUI kept running without an interface")
+	}
+}
+
+func TestUpdateWithMissingInterface(t *testing.T) {
+	server := fakeKismet(t)
+	server.AddSource("wlan1", "5fe308bd-0000-0000-0000-000000000002")
+	m := newSourceTestModel(newHunt(nil, []string{"wlan9"}, server.Endpoint()))
+	lookups := func() int { return len(server.RequestsTo("/datasource/all_sources.json")) }
+
+	// Polling waits on the interface, looking it up again each retry, and only reports it after the first
+	// few lookups miss
+	for i := range sourceWaitAttempts {
+		m.sourceCheckedAt = time.Time{}
+		_, cmd := m.Update(tickMsg(time.Now()))
+		if m.fatalErr != nil || cmd == nil {
+			t.Fatalf("lookup %d: fatalErr %v, cmd %v, want the TUI to keep going", i+1, m.fatalErr, cmd)
+		}
+		if got := lookups(); got != i+1 {
+			t.Fatalf("lookup %d: %d datasource requests", i+1, got)
+		}
+	}
+	if !m.sourceMissing || m.sourceWaiting {
+		t.Fatalf("missing %v, waiting %v after %d lookups", m.sourceMissing, m.sourceWaiting, sourceWaitAttempts)
+	}
+	if len(server.RequestsTo("/devices.json")) != 0 {
+		t.Error("polled for devices without the datasource")
+	}
+
+	// The status line names the interfaces Kismet does have
+	want := "interface wlan9 is not a Kismet datasource (Kismet has wlan0, wlan1)"
+	if view := m.View(); !strings.Contains(view, want) {
+		t.Errorf("view doesn't show %q:\n%s", want, view)
+	}
+
+	// Until the retry delay passes it isn't looked up again
+	m.Update(tickMsg(time.Now()))
+	if got := lookups(); got != sourceWaitAttempts {
+		t.Errorf("%d datasource requests within the retry delay, want %d", got, sourceWaitAttempts)
+	}
+
+	// Once Kismet has it, the hunt carries on
+	server.AddSource("wlan9", "5fe308bd-0000-0000-0000-000000000009")
+	m.sourceCheckedAt = time.Time{}
+	m.Update(tickMsg(time.Now()))
+	if m.sourceMissing || m.sourceUUID != "5fe308bd-0000-0000-0000-000000000009" {
+		t.Errorf("missing %v, uuid %q after Kismet added the interface", m.sourceMissing, m.sourceUUID)
+	}
+}
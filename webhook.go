@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	webhookAttempts  = 3               // Tries per alert before giving up
+	webhookRetryWait = 2 * time.Second // Wait before the first retry, doubled for each one after
+	webhookQueueSize = 100             // Alerts buffered before new ones are dropped
+)
+
+// Alert types that can be enabled in optional.webhook_events
+const (
+	alertTargetFound = "target_found"
+	alertTargetLost  = "target_lost"
+	alertRSSIAbove   = "rssi_above"
+	alertProximity   = "proximity"
+	alertColocation  = "colocation"
+)
+
+// JSON body POSTed to the webhook. Text makes it readable as a Slack-compatible message.
+type webhookAlert struct {
+	Text      string    `json:"text"`
+	Event     string    `json:"event"`
+	Target    string    `json:"target"`
+	MAC       string    `json:"mac"`
+	RSSI      int       `json:"rssi"`
+	Channel   string    `json:"channel"`
+	Other     string    `json:"other,omitempty"`     // The second target of a colocation alert
+	OtherMAC  string    `json:"other_mac,omitempty"` // Its MAC
+	Timestamp time.Time `json:"timestamp"`
+	Hostname  string    `json:"hostname"`
+}
+
+// Tracks which targets are over their RSSI threshold, so rssi_above fires once per crossing
+type rssiWatch struct {
+	threshold int // Default threshold for targets without their own
+	above     map[*TargetItem]bool
+}
+
+func newRSSIWatch(threshold int) *rssiWatch {
+	return &rssiWatch{threshold: threshold, above: make(map[*TargetItem]bool)}
+}
+
+// Whether s takes its target from below its threshold to at or above it
+func (w *rssiWatch) crossed(s sample) bool {
+	threshold := w.threshold
+	if s.target.AlertRSSI != 0 {
+		threshold = s.target.AlertRSSI
+	}
+	wasAbove := w.above[s.target]
+	w.above[s.target] = s.rssi >= threshold
+	return !wasAbove && w.above[s.target]
+}
+
+// Forget that target was above its threshold, e.g. once it's lost
+func (w *rssiWatch) reset(target *TargetItem) {
+	w.above[target] = false
+}
+
+// Watches the tracker for alert-worthy changes and POSTs them to a webhook from its own goroutine
+type webhookNotifier struct {
+	url         string
+	events      []string
+	minInterval time.Duration // Shortest time between two alerts of the same type for the same target
+	hostname    string
+	client      *http.Client
+
+	seen  map[*TargetItem]bool // Targets that have already fired target_found
+	above *rssiWatch
+	sent  map[string]time.Time // Last alert time by event and target, for rate limiting
+	queue chan webhookAlert
+}
+
+func newWebhookNotifier(url string, events []string, threshold int, minInterval time.Duration) *webhookNotifier {
+	hostname, _ := os.Hostname()
+	n := &webhookNotifier{
+		url:         url,
+		events:      events,
+		minInterval: minInterval,
+		hostname:    hostname,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		seen:        make(map[*TargetItem]bool),
+		above:       newRSSIWatch(threshold),
+		sent:        make(map[string]time.Time),
+		queue:       make(chan webhookAlert, webhookQueueSize),
+	}
+	go n.run()
+	return n
+}
+
+// Queue alerts for targets seen for the first time, crossing their RSSI threshold, or going quiet
+func (n *webhookNotifier) observe(samples []sample, lost *TargetItem) {
+	for _, s := range samples {
+		if !n.seen[s.target] {
+			n.seen[s.target] = true
+			n.alert(alertTargetFound, s)
+		}
+
+		if n.above.crossed(s) {
+			n.alert(alertRSSIAbove, s)
+		}
+	}
+
+	if lost != nil {
+		n.above.reset(lost)
+		n.alert(alertTargetLost, sample{time: time.Now(), target: lost, mac: lost.Value, rssi: lost.LastRSSI})
+	}
+}
+
+// Queue an alert if its type is enabled and the target hasn't had the same alert recently
+func (n *webhookNotifier) alert(event string, s sample) {
+	if !slices.Contains(n.events, event) {
+		return
+	}
+
+	key := event + " " + s.target.ConfigKey()
+	if last, ok := n.sent[key]; ok && s.time.Sub(last) < n.minInterval {
+		return
+	}
+	n.sent[key] = s.time
+
+	a := webhookAlert{
+		Event:     event,
+		Target:    s.target.DisplayValue(),
+		MAC:       s.mac,
+		RSSI:      s.rssi,
+		Channel:   s.channel,
+		Timestamp: s.time.UTC(),
+		Hostname:  n.hostname,
+	}
+	switch event {
+	case alertTargetFound:
+		a.Text = fmt.Sprintf("rizzyscope on %s: target %s seen at %d dBm", n.hostname, a.Target, a.RSSI)
+	case alertTargetLost:
+		a.Text = fmt.Sprintf("rizzyscope on %s: lost target %s", n.hostname, a.Target)
+	case alertRSSIAbove:
+		a.Text = fmt.Sprintf("rizzyscope on %s: target %s is at %d dBm", n.hostname, a.Target, a.RSSI)
+	case alertProximity:
+		a.Text = fmt.Sprintf("rizzyscope on %s: target %s is within reach at %d dBm", n.hostname, a.Target, a.RSSI)
+	}
+	n.enqueue(a)
+}
+
+// Queue a colocation alert for a pair that just came into range together, unless the same pair had one
+// recently
+func (n *webhookNotifier) colocated(pair *targetPair, at time.Time) {
+	if !slices.Contains(n.events, alertColocation) {
+		return
+	}
+
+	key := alertColocation + " " + pair.a.ConfigKey() + " " + pair.b.ConfigKey()
+	if last, ok := n.sent[key]; ok && at.Sub(last) < n.minInterval {
+		return
+	}
+	n.sent[key] = at
+
+	a := webhookAlert{
+		Event:     alertColocation,
+		Target:    pair.a.DisplayValue(),
+		MAC:       pair.a.Value,
+		RSSI:      pair.a.LastRSSI,
+		Channel:   pair.a.Channel,
+		Other:     pair.b.DisplayValue(),
+		OtherMAC:  pair.b.Value,
+		Timestamp: at.UTC(),
+		Hostname:  n.hostname,
+	}
+	a.Text = fmt.Sprintf("rizzyscope on %s: targets %s and %s are both in range", n.hostname, a.Target, a.Other)
+	n.enqueue(a)
+}
+
+func (n *webhookNotifier) enqueue(a webhookAlert) {
+	select {
+	case n.queue <- a:
+	default:
+		slog.Warn("Webhook queue full, dropping alert", "event", a.Event, "target", a.Target)
+	}
+}
+
+// Send queued alerts, retrying each a few times. Only the first failure after a success is logged so an
+// unreachable webhook doesn't flood the log.
+func (n *webhookNotifier) run() {
+	failing := false
+	for a := range n.queue {
+		err := n.send(a)
+		switch {
+		case err != nil && !failing:
+			failing = true
+			slog.Warn("Webhook alert failed, further failures won't be logged until it recovers", "event", a.Event, "err", err)
+		case err == nil && failing:
+			failing = false
+			slog.Info("Webhook alerts are being delivered again")
+		}
+	}
+}
+
+func (n *webhookNotifier) send(a webhookAlert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+
+	wait := webhookRetryWait
+	for attempt := 1; ; attempt++ {
+		err = n.post(body)
+		if err == nil || attempt == webhookAttempts {
+			return err
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// Strip the request URL from an HTTP client error
+func redactedError(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
+
+func (n *webhookNotifier) post(body []byte) error {
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The error includes the URL, which for most webhooks is itself a secret
+		return fmt.Errorf("error posting to webhook: %v", redactedError(err))
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/GobiasSomeCoffeeCo/rizzyscope/internal/tracker"
+)
+
+// WiGLE CSV pre-header and column header, see https://api.wigle.net/csvFormat.html
+const wigleHeader = "WigleWifi-1.4,appRelease=rizzyscope,model=rizzyscope,release=1.0,device=rizzyscope,display=,board=,brand=rizzyscope"
+
+var wigleColumns = []string{"MAC", "SSID", "AuthMode", "FirstSeen", "Channel", "RSSI", "CurrentLatitude", "CurrentLongitude", "AltitudeMeters", "AccuracyMeters", "Type"}
+
+// Best sighting of one device during the session
+type wigleDevice struct {
+	mac       string
+	ssid      string
+	crypt     string
+	kind      string // Kismet device type, e.g. "Wi-Fi AP"
+	firstSeen time.Time
+	channel   string
+	rssi      int
+	fix       *gpsFix // Position at the strongest reading, nil without GPS
+}
+
+// Every device seen during the session, one per MAC, keeping the earliest first-seen time and the
+// strongest RSSI along with where it was heard
+type wigleLog struct {
+	devices map[string]*wigleDevice
+}
+
+func newWigleLog() *wigleLog {
+	return &wigleLog{devices: make(map[string]*wigleDevice)}
+}
+
+// Merge a device listing from FetchAllDevices, taken at fix
+func (w *wigleLog) observe(devices []map[string]interface{}, fix *gpsFix) {
+	for _, device := range devices {
+		mac, _ := device["base.macaddr"].(string)
+		kind, _ := device["Type"].(string)
+		if mac == "" || wigleType(kind) == "" {
+			continue
+		}
+
+		rssi, ok := device["RSSI"].(float64)
+		if !ok || rssi == 0 {
+			continue
+		}
+
+		firstSeen := time.Now()
+		if first, ok := device["base.first_time"].(float64); ok && first > 0 {
+			firstSeen = time.Unix(int64(first), 0)
+		}
+
+		d, seen := w.devices[mac]
+		if !seen {
+			d = &wigleDevice{mac: mac, firstSeen: firstSeen, rssi: int(rssi)}
+			w.devices[mac] = d
+		}
+		if firstSeen.Before(d.firstSeen) {
+			d.firstSeen = firstSeen
+		}
+
+		// Details can appear later in the session (e.g. the SSID once a beacon is seen), so keep the latest
+		d.kind = kind
+		if crypt, _ := device["Crypt"].(string); crypt != "" {
+			d.crypt = crypt
+		}
+		if ssid, _ := device["SSID"].(string); ssid != "" {
+			d.ssid = ssid
+		}
+
+		if int(rssi) >= d.rssi || !seen {
+			d.rssi = int(rssi)
+			if channel := tracker.DeviceChannel(device); channel != "" {
+				d.channel = channel
+			}
+			if fix != nil {
+				d.fix = fix
+			}
+		} else if d.fix == nil && fix != nil {
+			d.fix = fix
+		}
+	}
+}
+
+// Write every device as a WiGLE CSV, sorted by first-seen time
+func (w *wigleLog) write(out io.Writer) error {
+	if _, err := io.WriteString(out, wigleHeader+"\n"); err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(out)
+	if err := cw.Write(wigleColumns); err != nil {
+		return err
+	}
+
+	devices := make([]*wigleDevice, 0, len(w.devices))
+	for _, d := range w.devices {
+		devices = append(devices, d)
+	}
+	slices.SortFunc(devices, func(a, b *wigleDevice) int {
+		if c := a.firstSeen.Compare(b.firstSeen); c != 0 {
+			return c
+		}
+		return strings.Compare(a.mac, b.mac)
+	})
+
+	for _, d := range devices {
+		var lat, lon, alt, accuracy string
+		if d.fix != nil {
+			lat = strconv.FormatFloat(d.fix.lat, 'f', -1, 64)
+			lon = strconv.FormatFloat(d.fix.lon, 'f', -1, 64)
+			alt = strconv.FormatFloat(d.fix.alt, 'f', -1, 64)
+			accuracy = "0" // Kismet doesn't report one
+		}
+
+		var channel string
+		if n := int(channelNumber(d.channel)); n > 0 {
+			channel = strconv.Itoa(n)
+		}
+
+		err := cw.Write([]string{
+			strings.ToLower(d.mac),
+			d.ssid,
+			wigleAuthMode(d.crypt, d.kind),
+			d.firstSeen.UTC().Format("2006-01-02 15:04:05"),
+			channel,
+			strconv.Itoa(d.rssi),
+			lat,
+			lon,
+			alt,
+			accuracy,
+			wigleType(d.kind),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Write the CSV to path
+func (w *wigleLog) export(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating WiGLE CSV: %v", err)
+	}
+	if err := w.write(file); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing WiGLE CSV: %v", err)
+	}
+	return file.Close()
+}
+
+// WiGLE's Type column for a Kismet device type, empty for types WiGLE doesn't take
+func wigleType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "Wi-Fi"):
+		return "WIFI"
+	case kind == "BTLE":
+		return "BLE"
+	case strings.HasPrefix(kind, "BR/EDR"), strings.HasPrefix(kind, "Bluetooth"):
+		return "BT"
+	default:
+		return ""
+	}
+}
+
+// WiGLE's bracketed AuthMode for a Kismet crypt summary such as "WPA2 WPA2-PSK AES-CCMP"
+func wigleAuthMode(crypt, kind string) string {
+	if wigleType(kind) != "WIFI" {
+		return ""
+	}
+
+	var mode string
+	if fields := strings.Fields(crypt); len(fields) > 0 && crypt != "None" && crypt != "Open" {
+		// The most specific auth, e.g. WPA2-PSK over WPA2, followed by the cipher as WiGLE writes it
+		auth := fields[0]
+		for _, f := range fields {
+			if strings.Contains(f, "-") && !strings.Contains(f, "CCMP") && !strings.Contains(f, "TKIP") {
+				auth = f
+				break
+			}
+		}
+		switch {
+		case strings.Contains(auth, "CCMP"), strings.Contains(auth, "TKIP"):
+		case strings.Contains(crypt, "CCMP"):
+			auth += "-CCMP"
+		case strings.Contains(crypt, "TKIP"):
+			auth += "-TKIP"
+		}
+		mode = "[" + auth + "]"
+	}
+	if kind == "Wi-Fi AP" {
+		mode += "[ESS]"
+	}
+	return mode
+}
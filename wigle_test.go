@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The CSV's pre-header line and its records
+func parseWigleCSV(t *testing.T, data []byte) (string, [][]string) {
+	t.Helper()
+	r := bufio.NewReader(bytes.NewReader(data))
+	preHeader, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSuffix(preHeader, "\n"), records
+}
+
+func TestWigleCSV(t *testing.T) {
+	first := float64(time.Date(2026, 10, 15, 14, 20, 0, 0, time.UTC).Unix())
+	ap := func(rssi float64) map[string]interface{} {
+		return map[string]interface{}{"base.macaddr": "10:22:33:44:55:66", "Type": "Wi-Fi AP", "SSID": "CoffeeShop",
+			"Crypt": "WPA2 WPA2-PSK AES-CCMP", "RSSI": rssi, "base.channel": "36", "base.first_time": first}
+	}
+	tag := map[string]interface{}{"base.macaddr": "D4:F5:13:00:00:01", "Type": "BTLE", "RSSI": -70.0, "base.first_time": first + 120}
+	zigbee := map[string]interface{}{"base.macaddr": "00:12:4B:00:00:01", "Type": "Zigbee", "RSSI": -60.0, "base.first_time": first}
+
+	w := newWigleLog()
+	w.observe([]map[string]interface{}{ap(-75), tag, zigbee}, &gpsFix{lat: 38.2527, lon: -85.7585, alt: 12.5})
+	// The AP is heard louder further along, which is where it's placed
+	w.observe([]map[string]interface{}{ap(-48)}, &gpsFix{lat: 38.2531, lon: -85.7577, alt: 12.5})
+	w.observe([]map[string]interface{}{ap(-80)}, nil)
+
+	var buf bytes.Buffer
+	if err := w.write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	fixture, err := os.ReadFile("testdata/wigle.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	preHeader, got := parseWigleCSV(t, buf.Bytes())
+	wantPreHeader, want := parseWigleCSV(t, fixture)
+	if preHeader != wantPreHeader {
+		t.Errorf("pre-header = %q, want %q", preHeader, wantPreHeader)
+	}
+	if !slices.Equal(got[0], wigleColumns) {
+		t.Errorf("columns = %v, want %v", got[0], wigleColumns)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d:\n%s", len(got), len(want), buf.String())
+	}
+	for i := range want {
+		if !slices.Equal(got[i], want[i]) {
+			t.Errorf("record %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}